@@ -0,0 +1,11 @@
+package api
+
+import (
+	_ "embed"
+)
+
+// Spec is the OpenAPI document served at /openapi.json. apitest checks the
+// handlers' responses against it.
+//
+//go:embed openapi.json
+var Spec []byte
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"os"
 	"fmt"
 	"log"
-	"strconv"
+	"flag"
+	"context"
+	"syscall"
 	"net/http"
+	"strconv"
+	"os/signal"
 	
-	"github.com/go-chi/chi/v5"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/repository"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/controller"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/core"
-	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/migrate"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/server"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
 )
 
@@ -20,61 +26,144 @@ func main() {
 	// Setup
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
 
+	configPath := flag.String("config", "", "path to a YAML config file or a directory holding config.yaml")
+	flag.Parse()
+
+	// Subcommands only need the database, not provider keys
+	if flag.Arg(0) == "migrate" {
+		dbConfig, err := config.LoadDatabase(*configPath)
+		if err != nil {
+			log.Fatal("Failed to load config: ", err)
+		}
+		pool, err := config.InitPostgresPool(*dbConfig)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to initialize database: %v", err))
+		}
+		err = runMigrate(context.Background(), pool, flag.Args()[1:])
+		pool.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	//Load configurations
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		log.Fatal("Failed to load config: ", err)
 	}
 
 	// Initialize database pool
-	connString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s&connect_timeout=180",
-		cfg.DbUser,
-		cfg.DbPassword,
-		cfg.DbHost,
-		cfg.DbPort,
-		cfg.DbName,
-		cfg.SSLMode,
-	)
-
-	//Debug purposes
-	log.Printf("configurations: %s", connString)
-
-	pool, err := config.InitPostgresPool(connString)
+	pool, err := config.InitPostgresPool(cfg.Database)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to initialize database: %v", err))
 	}
-	defer pool.Close()
 
-	// Declare providers
-	const stripe  model.PaymentProvider = "stripe"
-	//const paypal model.PaymentProvider = "paypal"
+	if err := prepareSchema(context.Background(), pool, cfg.Migrations); err != nil {
+		log.Fatal("Refusing to serve: ", err)
+	}
 
 	// Initialize providers
-	providerRegistry := core.NewProviderRegistry()
-	providerRegistry.Register(stripe, adapters.NewStripeAdapter(cfg.StripeSecretKey, cfg.StripeWebhookSecret))
-	//registry.Register(paypal, adapters.NewPayPalAdapter(cfg.PayPalClientID, cfg.PayPalSecret))
+	providers := adapters.NewProviders(cfg.Providers)
+	fakeAdapter, _ := providers[adapters.FakeProvider].(*adapters.FakeAdapter)
+	providerRegistry := adapters.NewProviderRegistry(providers, cfg.Resilience)
+
+	router, err := core.NewRouter(providerRegistry, cfg.Routing)
+	if err != nil {
+		log.Fatal("Invalid routing rules: ", err)
+	}
 
 	// Initialize repositories
-	repositoryRegistry := core.NewRepositoryRegistry()
-	repositoryRegistry.Register(core.TransactionRepo, repository.NewTransactionRepository(pool))
-	repositoryRegistry.Register(core.PaymentRepo, repository.NewPaymentMethodRepository(pool))
+	transactions := repository.NewTransactionRepository(pool)
+	paymentMethods := repository.NewPaymentMethodRepository(pool)
+	refunds := repository.NewRefundRepository(pool)
+	attempts := repository.NewChargeAttemptRepository(pool)
+	orders := repository.NewOrderRepository(pool)
+	fxQuotes := repository.NewFxQuoteRepository(pool)
+	unitOfWork := repository.NewUnitOfWork(pool)
 
 	// Setup services
-	paymentService := service.NewPaymentService(providerRegistry, repositoryRegistry)
-	paymentController := controller.NewPaymentController(paymentService)
+	paymentService := service.NewPaymentService(providerRegistry, router, transactions, paymentMethods, refunds, attempts, orders, fxQuotes, unitOfWork, cfg.Timeouts.Provider)
+	paymentController := controller.NewPaymentController(paymentService, cfg.Timeouts)
+	orderService := service.NewOrderService(orders, transactions, refunds, unitOfWork)
+	orderController := controller.NewOrderController(orderService, cfg.Timeouts)
+
+	// Health checks
+	checkers := []ports.IHealthChecker{
+		repository.NewDatabaseChecker(pool),
+		repository.NewMigrationChecker(pool, migrate.LatestVersion()),
+	}
+	if cfg.Health.CheckProviders {
+		for _, processor := range providerRegistry.All() {
+			if checker, ok := service.NewProviderChecker(processor); ok {
+				checkers = append(checkers, service.NewCachedChecker(checker, cfg.Health.ProviderTTL))
+			}
+		}
+	}
+	healthService := service.NewHealthService(checkers...)
+	healthController := controller.NewHealthController(healthService, cfg.Health.ProbeTimeout)
+
+	var fxController *controller.FxController
+	var rateRefresher *service.RateRefresher
+	if cfg.FX.Enabled {
+		var source ports.IRateSource = adapters.NewFileRateSource(cfg.FX.SourceLocation)
+		if cfg.FX.Source == "http" {
+			source = adapters.NewHTTPRateSource(cfg.FX.SourceLocation, &http.Client{Timeout: cfg.Timeouts.Provider})
+		}
+		fxService := service.NewFxService(repository.NewFxRateRepository(pool), fxQuotes, source, cfg.FX.SettlementCurrency, cfg.FX.QuoteTTL, cfg.FX.MaxRateAge)
+		fxController = controller.NewFxController(fxService, cfg.Timeouts)
+		rateRefresher = service.NewRateRefresher(fxService, cfg.FX.RefreshInterval)
+	}
+
+	var fakeController *controller.FakeController
+	if fakeAdapter != nil {
+		fakeController = controller.NewFakeController(fakeAdapter, paymentService, cfg.Timeouts.Webhook)
+	}
+
+	idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(pool), cfg.HTTP.IdempotencyKeyTTL)
 
 	// Router
-	r := chi.NewRouter()
-	r.Post("/payments/{provider}/intent", paymentController.CreatePaymentIntent)
-	r.Post("/payments/{provider}/charge", paymentController.ChargeClient)
-	r.Post("/webhooks/{provider}", paymentController.ParseWebhook)
+	r := controller.NewRouter(controller.Controllers{
+		Payments:    paymentController,
+		Orders:      orderController,
+		Health:      healthController,
+		Fx:          fxController,
+		Fake:        fakeController,
+		Idempotency: idempotencyService,
+	})
 
-	r.Get("/payments/health", paymentController.GetHealthCheck)
-	r.Get("/payments/{provider}/intent", paymentController.GetPaymentIntent)
-	r.Get("/payments/methods/{id}", paymentController.GetUserPMethods)
+	// HTTP server
+	srv := server.New(server.Options{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		MaxBodyBytes:      cfg.HTTP.MaxBodyBytes,
+		DrainDelay:        cfg.HTTP.DrainDelay,
+		ShutdownTimeout:   cfg.HTTP.ShutdownTimeout,
+	}, r)
+	if cfg.Workers.Enabled {
+		recovery := service.NewRecoveryWorker(paymentService, cfg.Workers.RecoveryInterval, cfg.Workers.RecoveryGrace, cfg.Workers.RecoveryPolicy)
+		srv.Go("charge-recovery", recovery.Run)
+		srv.Go("idempotency-keys", service.NewIdempotencyPruner(idempotencyService, cfg.Workers.IdempotencyPruneInterval).Run)
+	}
+	if rateRefresher != nil {
+		srv.Go("fx-rates", rateRefresher.Run)
+	}
+	if fakeController != nil {
+		srv.Go("fake-webhooks", fakeController.Run)
+	}
+	srv.OnDrain(healthService.SetDraining)
+	srv.OnClose(pool.Close)
+
+	// ECS sends SIGTERM, then SIGKILL after the task stopTimeout
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	// Start server
 	log.Printf("configurations: %+v", cfg)
-	log.Printf("Server running on :%d", cfg.Port)
-	http.ListenAndServe(":"+strconv.Itoa(cfg.Port), r)
-}
\ No newline at end of file
+	if err := srv.Run(ctx); err != nil {
+		log.Fatal("Server stopped with error: ", err)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"errors"
+	"context"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/migrate"
+)
+
+const migrateUsage = "usage: payment-service [--config path] migrate up|down|status|version"
+
+// runMigrate implements the `migrate` subcommand
+func runMigrate(ctx context.Context, pool *pgxpool.Pool, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := migrate.New(pool)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		results, err := migrator.Up(ctx)
+		for _, res := range results {
+			fmt.Println(res)
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		res, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println(res)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+		for _, st := range statuses {
+			appliedAt := "-"
+			if !st.AppliedAt.IsZero() {
+				appliedAt = st.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Source.Version, st.State, appliedAt, filepath.Base(st.Source.Path))
+		}
+		w.Flush()
+	case "version":
+		current, target, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("current: %d\ntarget:  %d\n", current, target)
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// prepareSchema applies or checks migrations before the server starts serving
+func prepareSchema(ctx context.Context, pool *pgxpool.Pool, cfg config.MigrationsConfig) error {
+	if !cfg.AutoMigrate && !cfg.RequireCurrent {
+		return nil
+	}
+
+	migrator, err := migrate.New(pool)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if cfg.AutoMigrate {
+		results, err := migrator.Up(ctx)
+		for _, res := range results {
+			fmt.Println(res)
+		}
+		if err != nil {
+			return fmt.Errorf("auto-migration failed: %w", err)
+		}
+	}
+
+	if cfg.RequireCurrent {
+		current, target, err := migrator.Version(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+		if current < target {
+			return fmt.Errorf("schema version %d is behind %d, run `migrate up`", current, target)
+		}
+	}
+
+	return nil
+}
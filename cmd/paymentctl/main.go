@@ -0,0 +1,429 @@
+package main
+
+import (
+	"io"
+	"os"
+	"fmt"
+	"log"
+	"flag"
+	"sort"
+	"time"
+	"context"
+	"strings"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/repository"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+)
+
+const usage = `paymentctl - payment service operations
+
+usage: paymentctl [--config path] [--output table|json] [--dry-run] <command> [flags]
+
+commands:
+  tx get      --id ID | --ref PAY-1001 | --intent pi_...
+  tx resync   --intent pi_... [--provider stripe]
+  methods list    --customer ID
+  methods disable --customer ID --method pm_...
+  refund      --tx ID [--amount N] [--reason text] [--provider stripe]
+  webhook replay --file event.json|- [--provider stripe]
+  report      [--since 2006-01-02] [--until 2006-01-02]
+  recover     [--older-than 5m] [--policy refund|record] [--limit N]
+`
+
+type app struct {
+	transactions   *repository.TransactionRepository
+	paymentMethods *repository.PaymentMethodRepository
+	refunds        *repository.RefundRepository
+	attempts       *repository.ChargeAttemptRepository
+	providers      map[model.PaymentProvider]ports.IPaymentProcessor
+	recovery       config.WorkersConfig
+	service        *service.PaymentService
+	out            *printer
+	dryRun         bool
+}
+
+func main() {
+	log.SetFlags(0)
+
+	configPath := flag.String("config", "", "path to a YAML config file or a directory holding config.yaml")
+	output := flag.String("output", "table", "output format: table or json")
+	dryRun := flag.Bool("dry-run", false, "show what would change without writing anything")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
+
+	pool, err := config.InitPostgresPool(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to initialize database: ", err)
+	}
+	defer pool.Close()
+
+	// Same providers as the API, so every provider it charges through can be operated on
+	providers := adapters.NewProviders(cfg.Providers)
+	providerRegistry := adapters.NewProviderRegistry(providers, cfg.Resilience)
+
+	transactions := repository.NewTransactionRepository(pool)
+	paymentMethods := repository.NewPaymentMethodRepository(pool)
+	refunds := repository.NewRefundRepository(pool)
+	attempts := repository.NewChargeAttemptRepository(pool)
+
+	a := &app{
+		transactions:   transactions,
+		paymentMethods: paymentMethods,
+		refunds:        refunds,
+		attempts:       attempts,
+		providers:      providers,
+		recovery:       cfg.Workers,
+		service:        service.NewPaymentService(providerRegistry, nil, transactions, paymentMethods, refunds, attempts, repository.NewOrderRepository(pool), repository.NewFxQuoteRepository(pool), repository.NewUnitOfWork(pool), cfg.Timeouts.Provider),
+		out:            newPrinter(*output),
+		dryRun:         *dryRun,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if err := a.run(ctx, flag.Args()); err != nil {
+		cancel()
+		pool.Close()
+		log.Fatal(err)
+	}
+}
+
+func (a *app) run(ctx context.Context, args []string) error {
+	cmd, rest := args[0], args[1:]
+	if (cmd == "tx" || cmd == "methods" || cmd == "webhook") && len(rest) > 0 {
+		cmd, rest = cmd+" "+rest[0], rest[1:]
+	}
+
+	switch cmd {
+	case "tx get":
+		return a.txGet(ctx, rest)
+	case "tx resync":
+		return a.txResync(ctx, rest)
+	case "methods list":
+		return a.methodsList(ctx, rest)
+	case "methods disable":
+		return a.methodsDisable(ctx, rest)
+	case "refund":
+		return a.refund(ctx, rest)
+	case "webhook replay":
+		return a.webhookReplay(ctx, rest)
+	case "report":
+		return a.report(ctx, rest)
+	case "recover":
+		return a.recover(ctx, rest)
+	}
+	return fmt.Errorf("unknown command %q\n\n%s", strings.Join(args, " "), usage)
+}
+
+func (a *app) txGet(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tx get", flag.ExitOnError)
+	id := fs.String("id", "", "transaction id")
+	ref := fs.String("ref", "", "internal reference (PAY-...)")
+	intent := fs.String("intent", "", "provider payment intent id")
+	fs.Parse(args)
+
+	var column, value string
+	switch {
+	case *id != "":
+		column, value = "id", *id
+	case *ref != "":
+		column, value = "internal_reference", *ref
+	case *intent != "":
+		column, value = "payment_intent_id", *intent
+	default:
+		return fmt.Errorf("one of --id, --ref or --intent is required")
+	}
+
+	txs, err := a.transactions.FindByColumn(ctx, column, value)
+	if err != nil {
+		return err
+	}
+	if len(txs) == 0 {
+		return fmt.Errorf("no transaction found for %s=%s", column, value)
+	}
+
+	for i := range txs {
+		refunds, err := a.refunds.FindByTransaction(ctx, txs[i].ID)
+		if err != nil {
+			return err
+		}
+		a.out.transaction(txs[i], refunds)
+	}
+	return nil
+}
+
+func (a *app) txResync(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tx resync", flag.ExitOnError)
+	intent := fs.String("intent", "", "provider payment intent id")
+	provider := fs.String("provider", "stripe", "payment provider")
+	fs.Parse(args)
+
+	if *intent == "" {
+		return fmt.Errorf("--intent is required")
+	}
+
+	res, err := a.service.ResyncStatus(ctx, model.PaymentProvider(*provider), *intent, a.dryRun)
+	if err != nil {
+		return err
+	}
+
+	a.out.resync(res, a.dryRun)
+	return nil
+}
+
+func (a *app) methodsList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("methods list", flag.ExitOnError)
+	customer := fs.String("customer", "", "customer id")
+	fs.Parse(args)
+
+	if *customer == "" {
+		return fmt.Errorf("--customer is required")
+	}
+
+	methods, err := a.service.GetUserPMethods(ctx, *customer)
+	if err != nil {
+		return err
+	}
+
+	a.out.methods(methods)
+	return nil
+}
+
+func (a *app) methodsDisable(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("methods disable", flag.ExitOnError)
+	customer := fs.String("customer", "", "customer id")
+	method := fs.String("method", "", "payment method id")
+	fs.Parse(args)
+
+	if *customer == "" || *method == "" {
+		return fmt.Errorf("--customer and --method are required")
+	}
+
+	pm, err := a.paymentMethods.FindByID(ctx, *method)
+	if err != nil {
+		return err
+	}
+	if pm.CustomerID != *customer {
+		return fmt.Errorf("payment method %s does not belong to customer %s", *method, *customer)
+	}
+
+	if a.dryRun {
+		a.out.message(fmt.Sprintf("dry-run: would disable %s for %s (currently %s)", *method, *customer, pm.Status))
+		return nil
+	}
+
+	if err := a.service.DisablePaymentMethod(ctx, *customer, *method); err != nil {
+		return err
+	}
+
+	a.out.message(fmt.Sprintf("disabled %s for %s", *method, *customer))
+	return nil
+}
+
+func (a *app) refund(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("refund", flag.ExitOnError)
+	txID := fs.String("tx", "", "transaction id")
+	amount := fs.Int64("amount", 0, "amount in minor units (default: remaining balance)")
+	reason := fs.String("reason", "", "refund reason")
+	provider := fs.String("provider", "", "expected payment provider (default: the transaction's)")
+	fs.Parse(args)
+
+	if *txID == "" {
+		return fmt.Errorf("--tx is required")
+	}
+
+	// The refund goes back through whichever provider took the charge
+	tx, err := a.transactions.FindByID(ctx, *txID)
+	if err != nil {
+		return err
+	}
+	if *provider != "" && *provider != tx.Provider {
+		return fmt.Errorf("transaction %s was charged through %s, not %s", tx.ID, tx.Provider, *provider)
+	}
+
+	if a.dryRun {
+		refunds, err := a.refunds.FindByTransaction(ctx, tx.ID)
+		if err != nil {
+			return err
+		}
+		a.out.transaction(*tx, refunds)
+
+		planned := "the remaining balance"
+		if *amount > 0 {
+			planned = model.FormatAmount(*amount, tx.Currency)
+		}
+		a.out.message(fmt.Sprintf("dry-run: would refund %s of %s on %s via %s", planned, tx.ID, tx.PaymentIntentID, tx.Provider))
+		return nil
+	}
+
+	res, err := a.service.RefundPayment(ctx, model.PaymentProvider(tx.Provider), model.RefundRequest{
+		TransactionID: tx.ID,
+		Amount:        *amount,
+		Reason:        *reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.out.refund(res)
+	return nil
+}
+
+// Replays an event saved from the provider, e.g. from its dashboard, through the
+// regular webhook flow. The original signature has expired by then, so the
+// payload is signed again with the configured webhook secret.
+func (a *app) webhookReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("webhook replay", flag.ExitOnError)
+	file := fs.String("file", "", "event payload as the provider sent it, - for stdin")
+	provider := fs.String("provider", "stripe", "payment provider")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("--file is required")
+	}
+
+	var raw []byte
+	var err error
+	if *file == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return fmt.Errorf("reading event: %w", err)
+	}
+
+	processor, ok := a.providers[model.PaymentProvider(*provider)]
+	if !ok {
+		return fmt.Errorf("provider %s is not enabled", *provider)
+	}
+	signer, ok := processor.(ports.IWebhookSigner)
+	if !ok {
+		return fmt.Errorf("provider %s cannot replay webhooks", *provider)
+	}
+	headers := signer.SignWebhook(raw)
+
+	// Parsing alone writes nothing
+	if a.dryRun {
+		event, err := processor.ParseWebhook(ctx, raw, headers)
+		if err != nil {
+			return err
+		}
+		a.out.webhook(event, true)
+		return nil
+	}
+
+	event, err := a.service.ParseWebhook(ctx, model.PaymentProvider(*provider), raw, headers)
+	if err != nil {
+		return err
+	}
+
+	a.out.webhook(event, false)
+	return nil
+}
+
+var reportStatuses = []string{"pending", "payment_succeeded", "payment_failed", "payment_intent.canceled", "partially_refunded", "refunded"}
+
+func (a *app) report(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	since := fs.String("since", time.Now().AddDate(0, 0, -1).Format(time.DateOnly), "start date (inclusive)")
+	until := fs.String("until", "", "end date (exclusive)")
+	fs.Parse(args)
+
+	from, err := time.Parse(time.DateOnly, *since)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	to := time.Now()
+	if *until != "" {
+		if to, err = time.Parse(time.DateOnly, *until); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	var rows []reportRow
+	for _, status := range reportStatuses {
+		txs, err := a.transactions.FindByStatus(ctx, status, from)
+		if err != nil {
+			return err
+		}
+
+		// Charged and settled currencies differ on FX transactions
+		byCurrency := map[[2]string]*reportRow{}
+		for _, tx := range txs {
+			if !tx.CreatedAt.Before(to) {
+				continue
+			}
+			key := [2]string{tx.Currency, tx.SettlementCurrency}
+			row, ok := byCurrency[key]
+			if !ok {
+				row = &reportRow{Status: status, Currency: tx.Currency, SettlementCurrency: tx.SettlementCurrency}
+				byCurrency[key] = row
+			}
+			row.Count++
+			row.Amount += tx.Amount
+			row.SettlementAmount += tx.SettlementAmount
+		}
+		for _, row := range byCurrency {
+			row.Total = model.FormatAmount(row.Amount, row.Currency)
+			row.Settled = model.FormatAmount(row.SettlementAmount, row.SettlementCurrency)
+			rows = append(rows, *row)
+		}
+	}
+
+	// Map order is random, keep the report stable between runs
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Status != rows[j].Status {
+			return rows[i].Status < rows[j].Status
+		}
+		if rows[i].Currency != rows[j].Currency {
+			return rows[i].Currency < rows[j].Currency
+		}
+		return rows[i].SettlementCurrency < rows[j].SettlementCurrency
+	})
+
+	a.out.report(from, to, rows)
+	return nil
+}
+
+func (a *app) recover(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("recover", flag.ExitOnError)
+	olderThan := fs.Duration("older-than", a.recovery.RecoveryGrace, "only attempts not updated for this long")
+	policy := fs.String("policy", a.recovery.RecoveryPolicy, "charged but unrecorded attempts: refund or record")
+	limit := fs.Int("limit", 100, "maximum attempts to process")
+	fs.Parse(args)
+
+	before := time.Now().Add(-*olderThan)
+
+	if a.dryRun {
+		attempts, err := a.attempts.FindStale(ctx, []string{ports.AttemptStarted, ports.AttemptCharged}, before, *limit)
+		if err != nil {
+			return err
+		}
+		a.out.attempts(attempts)
+		a.out.message(fmt.Sprintf("dry-run: would recover %d attempts with policy %s", len(attempts), *policy))
+		return nil
+	}
+
+	results, err := a.service.RecoverChargeAttempts(ctx, before, *limit, *policy)
+	if err != nil {
+		return err
+	}
+
+	a.out.recovery(results)
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"encoding/json"
+	"text/tabwriter"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+type reportRow struct {
+	Status   string `json:"status"`
+	Currency string `json:"currency"`
+	Count    int    `json:"count"`
+	Amount   int64  `json:"amount"`
+	// Amount in major units with the currency code, e.g. "12.50 EUR"
+	Total    string `json:"total"`
+	SettlementCurrency string `json:"settlement_currency"`
+	SettlementAmount   int64  `json:"settlement_amount"`
+	Settled            string `json:"settled"`
+}
+
+// printer renders results as aligned tables for humans or JSON for scripts
+type printer struct {
+	json bool
+}
+
+func newPrinter(format string) *printer {
+	return &printer{json: format == "json"}
+}
+
+func (p *printer) emit(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func (p *printer) table(header string, rows [][]interface{}) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, header)
+	for _, row := range rows {
+		for i, col := range row {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, col)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+func (p *printer) message(msg string) {
+	if p.json {
+		p.emit(map[string]string{"message": msg})
+		return
+	}
+	fmt.Println(msg)
+}
+
+func (p *printer) transaction(tx model.Transaction, refunds []model.Refund) {
+	if p.json {
+		p.emit(map[string]interface{}{"transaction": tx, "refunds": refunds})
+		return
+	}
+
+	save := false
+	if tx.SavePaymentMethod != nil {
+		save = *tx.SavePaymentMethod
+	}
+	p.table("FIELD\tVALUE", [][]interface{}{
+		{"id", tx.ID},
+		{"internal_reference", tx.InternalReference},
+		{"payment_intent_id", tx.PaymentIntentID},
+		{"status", tx.TxStatus},
+		{"amount", model.FormatAmount(tx.Amount, tx.Currency)},
+		{"customer_id", tx.CustomerID},
+		{"save_payment_method", save},
+		{"created_at", tx.CreatedAt.Format(time.RFC3339)},
+		{"updated_at", tx.UpdatedAt.Format(time.RFC3339)},
+	})
+
+	if len(refunds) > 0 {
+		fmt.Println()
+		var rows [][]interface{}
+		for _, rf := range refunds {
+			rows = append(rows, []interface{}{rf.InternalReference, model.FormatAmount(rf.Amount, tx.Currency), rf.RefundStatus, rf.ProviderRefundID, rf.ProcessedAt.Format(time.RFC3339)})
+		}
+		p.table("REFUND\tAMOUNT\tSTATUS\tPROVIDER ID\tPROCESSED AT", rows)
+	}
+}
+
+func (p *printer) resync(res *model.ResyncResult, dryRun bool) {
+	if p.json {
+		p.emit(map[string]interface{}{"result": res, "dry_run": dryRun})
+		return
+	}
+
+	action := "unchanged"
+	if res.Changed && dryRun {
+		action = "would update (dry-run)"
+	} else if res.Changed {
+		action = "updated"
+	}
+	p.table("INTENT\tOURS\tPROVIDER\tNEW\tACTION", [][]interface{}{
+		{res.PaymentIntentID, res.PreviousStatus, res.ProviderStatus, res.NewStatus, action},
+	})
+}
+
+func (p *printer) webhook(event *model.PaymentEvent, dryRun bool) {
+	if p.json {
+		p.emit(map[string]interface{}{"type": event.Type, "payment_intent": event.PaymentIntent, "reference": event.Reference, "dry_run": dryRun})
+		return
+	}
+
+	action := "applied"
+	if dryRun {
+		action = "would apply (dry-run)"
+	}
+	p.table("EVENT	INTENT	REFERENCE	ACTION", [][]interface{}{
+		{event.Type, event.PaymentIntent, event.Reference, action},
+	})
+}
+
+func (p *printer) methods(methods []model.PaymentMethodResponse) {
+	if p.json {
+		p.emit(methods)
+		return
+	}
+
+	var rows [][]interface{}
+	for _, pm := range methods {
+		rows = append(rows, []interface{}{pm.PaymentMethodID, pm.PaymentProvider, pm.PaymentMethodType, pm.PaymentMethodStatus})
+	}
+	p.table("METHOD\tPROVIDER\tTYPE\tSTATUS", rows)
+}
+
+func (p *printer) refund(res *model.RefundResponse) {
+	if p.json {
+		p.emit(res)
+		return
+	}
+
+	p.table("REFUND\tTRANSACTION\tAMOUNT\tSTATUS\tPROVIDER ID", [][]interface{}{
+		{res.InternalReference, res.TransactionID, model.FormatAmount(res.Amount, res.Currency), res.Status, res.ProviderRefundID},
+	})
+}
+
+func (p *printer) attempts(attempts []model.ChargeAttempt) {
+	if p.json {
+		p.emit(attempts)
+		return
+	}
+
+	var rows [][]interface{}
+	for _, at := range attempts {
+		rows = append(rows, []interface{}{at.ID, at.Provider, at.Status, at.PaymentIntentID, model.FormatAmount(at.Amount, at.Currency), at.UpdatedAt.Format(time.RFC3339)})
+	}
+	p.table("ATTEMPT\tPROVIDER\tSTATUS\tINTENT\tAMOUNT\tUPDATED AT", rows)
+}
+
+func (p *printer) recovery(results []model.RecoveryResult) {
+	if p.json {
+		p.emit(results)
+		return
+	}
+
+	var rows [][]interface{}
+	for _, res := range results {
+		rows = append(rows, []interface{}{res.AttemptID, res.PaymentIntentID, res.PreviousStatus, res.NewStatus, res.Error})
+	}
+	p.table("ATTEMPT\tINTENT\tPREVIOUS\tNEW\tERROR", rows)
+}
+
+func (p *printer) report(from, to time.Time, rows []reportRow) {
+	if p.json {
+		p.emit(map[string]interface{}{"from": from, "to": to, "rows": rows})
+		return
+	}
+
+	fmt.Printf("Transactions from %s to %s\n\n", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	var table [][]interface{}
+	for _, row := range rows {
+		table = append(table, []interface{}{row.Status, row.Currency, row.Count, row.Total, row.Settled})
+	}
+	p.table("STATUS\tCURRENCY\tCOUNT\tTOTAL\tSETTLED", table)
+}
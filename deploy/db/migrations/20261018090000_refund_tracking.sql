@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE SEQUENCE refund_reference_seq START 1001;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE refunds
+    ALTER COLUMN internal_reference SET DEFAULT 'REF-' || nextval('refund_reference_seq'),
+    ADD COLUMN provider_refund_id VARCHAR(255),
+    ADD COLUMN refund_status VARCHAR(50) NOT NULL DEFAULT 'pending';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_refunds_transaction_id ON refunds(transaction_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_refunds_transaction_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE refunds
+    ALTER COLUMN internal_reference DROP DEFAULT,
+    DROP COLUMN IF EXISTS refund_status,
+    DROP COLUMN IF EXISTS provider_refund_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP SEQUENCE IF EXISTS refund_reference_seq;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE payment_methods DROP CONSTRAINT IF EXISTS payment_methods_pm_provider_check;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE payment_methods ADD CONSTRAINT payment_methods_pm_provider_check
+    CHECK (pm_provider IN ('stripe', 'paypal', 'needpam', 'fake'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM payment_methods WHERE pm_provider = 'fake';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE payment_methods DROP CONSTRAINT IF EXISTS payment_methods_pm_provider_check;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE payment_methods ADD CONSTRAINT payment_methods_pm_provider_check
+    CHECK (pm_provider IN ('stripe', 'paypal', 'needpam'));
+-- +goose StatementEnd
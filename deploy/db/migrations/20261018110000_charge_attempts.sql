@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS charge_attempts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider VARCHAR(50) NOT NULL,
+    customer_id VARCHAR(50) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    payment_method_id VARCHAR(255),
+    save_payment_method BOOLEAN NOT NULL DEFAULT FALSE,
+    payment_intent_id VARCHAR(255),
+    attempt_status VARCHAR(20) NOT NULL DEFAULT 'started'
+        CHECK (attempt_status IN ('started', 'charged', 'recorded', 'failed', 'refunded', 'unresolved')),
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_charge_attempts_pending ON charge_attempts(updated_at)
+    WHERE attempt_status IN ('started', 'charged');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_charge_attempts_pending;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS charge_attempts;
+-- +goose StatementEnd
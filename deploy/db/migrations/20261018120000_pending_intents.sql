@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions ALTER COLUMN payment_intent_id DROP NOT NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_transactions_payment_intent_id ON transactions(payment_intent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_payment_intent_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE transactions SET payment_intent_id = '' WHERE payment_intent_id IS NULL;
+ALTER TABLE transactions ALTER COLUMN payment_intent_id SET NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE charge_attempts ADD COLUMN metadata JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE charge_attempts DROP COLUMN IF EXISTS metadata;
+-- +goose StatementEnd
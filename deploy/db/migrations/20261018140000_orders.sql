@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE SEQUENCE order_reference_seq START 1001;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    internal_reference VARCHAR(255) NOT NULL UNIQUE DEFAULT 'ORD-' || nextval('order_reference_seq'),
+    customer_id VARCHAR(50) NOT NULL,
+    amount_due BIGINT NOT NULL CHECK (amount_due > 0),
+    currency CHAR(3) NOT NULL,
+    order_status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (order_status IN ('open', 'paid', 'partially_paid', 'refunded', 'cancelled')),
+    metadata JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions ADD COLUMN order_id UUID REFERENCES orders(id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE charge_attempts ADD COLUMN order_id UUID REFERENCES orders(id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_transactions_order_id ON transactions(order_id) WHERE order_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_order_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE charge_attempts DROP COLUMN IF EXISTS order_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions DROP COLUMN IF EXISTS order_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS orders;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP SEQUENCE IF EXISTS order_reference_seq;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS fx_rates (
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL CHECK (rate > 0),
+    source VARCHAR(50) NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (base_currency, quote_currency)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    customer_id VARCHAR(50) NOT NULL,
+    settlement_amount BIGINT NOT NULL CHECK (settlement_amount > 0),
+    settlement_currency CHAR(3) NOT NULL,
+    presentment_amount BIGINT NOT NULL CHECK (presentment_amount > 0),
+    presentment_currency CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL CHECK (rate > 0),
+    quote_status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (quote_status IN ('open', 'used')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN settlement_amount BIGINT,
+    ADD COLUMN settlement_currency CHAR(3),
+    ADD COLUMN fx_rate NUMERIC,
+    ADD COLUMN fx_quote_id UUID UNIQUE REFERENCES fx_quotes(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS fx_quote_id,
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS settlement_currency,
+    DROP COLUMN IF EXISTS settlement_amount;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS fx_quotes;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS fx_rates;
+-- +goose StatementEnd
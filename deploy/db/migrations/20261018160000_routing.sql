@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN provider VARCHAR(50),
+    ADD COLUMN routing_rule VARCHAR(100);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS routing_rule,
+    DROP COLUMN IF EXISTS provider;
+-- +goose StatementEnd
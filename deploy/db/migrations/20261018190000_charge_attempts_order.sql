@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX idx_charge_attempts_open_order ON charge_attempts(order_id)
+    WHERE order_id IS NOT NULL AND attempt_status IN ('started', 'charged');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_charge_attempts_open_order;
+-- +goose StatementEnd
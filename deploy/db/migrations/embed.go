@@ -0,0 +1,10 @@
+package migrations
+
+import (
+	"embed"
+)
+
+// FS holds the goose migrations so the binary can apply them itself
+//
+//go:embed *.sql
+var FS embed.FS
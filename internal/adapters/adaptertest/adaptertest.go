@@ -0,0 +1,517 @@
+// Package adaptertest is a conformance suite for ports.IPaymentProcessor
+// implementations. An adapter test wires the adapter to a local stand-in of
+// its provider API and runs the suite:
+//
+//	func TestStripeAdapter(t *testing.T) {
+//		adaptertest.Processor(t, adaptertest.Stripe())
+//	}
+package adaptertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// Outcomes an Instance must be able to deliver as signed webhooks
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeCanceled  = "canceled"
+	// An event type PaymentService does not act on
+	OutcomeUnhandled = "unhandled"
+)
+
+// How long a hanging provider call may outlive its context deadline
+const cancelGrace = 2 * time.Second
+
+// Instance is one processor wired to a fresh provider stand-in
+type Instance struct {
+	Processor ports.IPaymentProcessor
+	// Webhook returns a signed delivery moving intentID to outcome
+	Webhook func(t *testing.T, intentID string, outcome string) (raw []byte, headers map[string][]string)
+}
+
+type Harness struct {
+	New func(t *testing.T) Instance
+
+	// Tokens the stand-in charges successfully or declines
+	ChargeToken  string
+	DeclineToken string
+	// Amounts the stand-in answers with a server error or never answers
+	FailAmount int64
+	HangAmount int64
+
+	// Values that must never appear in error messages (API keys, webhook secrets)
+	Secrets []string
+}
+
+// Processor checks the behaviour PaymentService relies on
+func Processor(t *testing.T, h Harness) {
+	t.Run("name is set", func(t *testing.T) {
+		p := h.New(t).Processor
+		if p.Name() == "" {
+			t.Fatal("expected a provider name")
+		}
+	})
+
+	t.Run("create returns a confirmable intent", func(t *testing.T) {
+		p := h.New(t).Processor
+		res, err := p.CreatePaymentIntent(context.Background(), intentRequest(1500))
+		if err != nil {
+			t.Fatalf("CreatePaymentIntent: %v", err)
+		}
+		if res.ID == "" || res.ClientSecret == "" || res.Status == "" {
+			t.Fatalf("expected id, client secret and status, got %+v", res)
+		}
+		if res.Amount != 1500 || !strings.EqualFold(res.Currency, "eur") {
+			t.Fatalf("expected 1500 eur, got %d %s", res.Amount, res.Currency)
+		}
+	})
+
+	t.Run("create surfaces provider errors", func(t *testing.T) {
+		p := h.New(t).Processor
+		res, err := p.CreatePaymentIntent(context.Background(), intentRequest(h.FailAmount))
+		h.assertFailed(t, res, err)
+	})
+
+	t.Run("get returns the created intent", func(t *testing.T) {
+		p := h.New(t).Processor
+		created := mustCreate(t, p, 2500)
+
+		got, err := p.GetPaymentIntent(context.Background(), created.ID)
+		if err != nil {
+			t.Fatalf("GetPaymentIntent: %v", err)
+		}
+		if got.ID != created.ID || got.Amount != created.Amount || !strings.EqualFold(got.Currency, created.Currency) {
+			t.Fatalf("expected %+v, got %+v", created, got)
+		}
+	})
+
+	t.Run("get unknown intent fails", func(t *testing.T) {
+		p := h.New(t).Processor
+		res, err := p.GetPaymentIntent(context.Background(), "pi_missing")
+		h.assertFailed(t, res, err)
+	})
+
+	t.Run("charge fills the payment method", func(t *testing.T) {
+		p := h.New(t).Processor
+		res := mustCharge(t, p, h.ChargeToken, 3000)
+		if res.ID == "" || res.Status != "succeeded" {
+			t.Fatalf("expected a succeeded intent, got %+v", res)
+		}
+		if res.PaymentMethodID != h.ChargeToken {
+			t.Fatalf("expected PaymentMethodID %q, got %q", h.ChargeToken, res.PaymentMethodID)
+		}
+		if res.Amount != 3000 || !strings.EqualFold(res.Currency, "eur") {
+			t.Fatalf("expected 3000 eur, got %d %s", res.Amount, res.Currency)
+		}
+	})
+
+	t.Run("charge without token fails", func(t *testing.T) {
+		p := h.New(t).Processor
+		res, err := p.ChargeClient(context.Background(), chargeRequest("", 3000))
+		h.assertFailed(t, res, err)
+	})
+
+	t.Run("charge surfaces declines", func(t *testing.T) {
+		p := h.New(t).Processor
+		res, err := p.ChargeClient(context.Background(), chargeRequest(h.DeclineToken, 3000))
+		h.assertFailed(t, res, err)
+	})
+
+	t.Run("charge surfaces provider errors", func(t *testing.T) {
+		p := h.New(t).Processor
+		res, err := p.ChargeClient(context.Background(), chargeRequest(h.ChargeToken, h.FailAmount))
+		h.assertFailed(t, res, err)
+	})
+
+	for outcome, want := range map[string]string{
+		OutcomeSucceeded: ports.PaymentSucceeded,
+		OutcomeFailed:    ports.PaymentFailed,
+		OutcomeCanceled:  ports.PaymentCancelled,
+	} {
+		t.Run("webhook maps "+outcome, func(t *testing.T) {
+			inst := h.New(t)
+			created := mustCreate(t, inst.Processor, 1200)
+			raw, headers := inst.Webhook(t, created.ID, outcome)
+
+			event, err := inst.Processor.ParseWebhook(context.Background(), raw, headers)
+			if err != nil {
+				t.Fatalf("ParseWebhook: %v", err)
+			}
+			if event.Type != want || event.PaymentIntent != created.ID {
+				t.Fatalf("expected %s for %s, got %s for %s", want, created.ID, event.Type, event.PaymentIntent)
+			}
+			if outcome == OutcomeSucceeded && event.PaymentMethod == "" {
+				t.Fatal("expected the payment method on succeeded events")
+			}
+		})
+	}
+
+	t.Run("webhook carries the internal reference", func(t *testing.T) {
+		inst := h.New(t)
+		req := intentRequest(1200)
+		req.Reference = "PAY-ADAPTERTEST"
+		created, err := inst.Processor.CreatePaymentIntent(context.Background(), req)
+		if err != nil {
+			t.Fatalf("CreatePaymentIntent: %v", err)
+		}
+		raw, headers := inst.Webhook(t, created.ID, OutcomeSucceeded)
+
+		event, err := inst.Processor.ParseWebhook(context.Background(), raw, headers)
+		if err != nil {
+			t.Fatalf("ParseWebhook: %v", err)
+		}
+		if event.Reference != "PAY-ADAPTERTEST" {
+			t.Fatalf("expected reference PAY-ADAPTERTEST, got %q", event.Reference)
+		}
+	})
+
+	t.Run("metadata round-trips without internal keys", func(t *testing.T) {
+		inst := h.New(t)
+		req := intentRequest(1200)
+		req.Metadata = map[string]string{"cart": "42"}
+		req.Reference = "PAY-ADAPTERTEST"
+		created, err := inst.Processor.CreatePaymentIntent(context.Background(), req)
+		if err != nil {
+			t.Fatalf("CreatePaymentIntent: %v", err)
+		}
+
+		got, err := inst.Processor.GetPaymentIntent(context.Background(), created.ID)
+		if err != nil {
+			t.Fatalf("GetPaymentIntent: %v", err)
+		}
+		if len(got.Metadata) != 1 || got.Metadata["cart"] != "42" {
+			t.Fatalf("expected only the caller's metadata, got %v", got.Metadata)
+		}
+
+		raw, headers := inst.Webhook(t, created.ID, OutcomeSucceeded)
+		event, err := inst.Processor.ParseWebhook(context.Background(), raw, headers)
+		if err != nil {
+			t.Fatalf("ParseWebhook: %v", err)
+		}
+		if len(event.Metadata) != 1 || event.Metadata["cart"] != "42" {
+			t.Fatalf("expected the caller's metadata on the event, got %v", event.Metadata)
+		}
+
+		charge := chargeRequest(h.ChargeToken, 900)
+		charge.Metadata = map[string]string{"cart": "43"}
+		charge.AttemptID = "attempt_" + randomHex()
+		charged, err := inst.Processor.ChargeClient(context.Background(), charge)
+		if err != nil {
+			t.Fatalf("ChargeClient: %v", err)
+		}
+		if len(charged.Metadata) != 1 || charged.Metadata["cart"] != "43" {
+			t.Fatalf("expected the caller's metadata on the charge, got %v", charged.Metadata)
+		}
+	})
+
+	t.Run("webhook acknowledges unhandled events", func(t *testing.T) {
+		inst := h.New(t)
+		created := mustCreate(t, inst.Processor, 1200)
+		raw, headers := inst.Webhook(t, created.ID, OutcomeUnhandled)
+
+		event, err := inst.Processor.ParseWebhook(context.Background(), raw, headers)
+		if err != nil {
+			t.Fatalf("ParseWebhook: %v", err)
+		}
+		switch event.Type {
+		case ports.PaymentSucceeded, ports.PaymentFailed, ports.PaymentCancelled:
+			t.Fatalf("expected an unmapped event type, got %s", event.Type)
+		}
+	})
+
+	t.Run("webhook signer signs like the provider", func(t *testing.T) {
+		inst := h.New(t)
+		signer, ok := inst.Processor.(ports.IWebhookSigner)
+		if !ok {
+			t.Skip("processor does not sign webhooks")
+		}
+		created := mustCreate(t, inst.Processor, 1500)
+		raw, _ := inst.Webhook(t, created.ID, OutcomeSucceeded)
+
+		event, err := inst.Processor.ParseWebhook(context.Background(), raw, signer.SignWebhook(raw))
+		if err != nil {
+			t.Fatalf("ParseWebhook: %v", err)
+		}
+		if event.PaymentIntent != created.ID {
+			t.Fatalf("expected an event for %s, got %+v", created.ID, event)
+		}
+	})
+
+	t.Run("webhook rejects missing signature", func(t *testing.T) {
+		inst := h.New(t)
+		created := mustCreate(t, inst.Processor, 1200)
+		raw, _ := inst.Webhook(t, created.ID, OutcomeSucceeded)
+
+		event, err := inst.Processor.ParseWebhook(context.Background(), raw, nil)
+		h.assertFailed(t, event, err)
+	})
+
+	t.Run("webhook rejects forged signature", func(t *testing.T) {
+		inst := h.New(t)
+		created := mustCreate(t, inst.Processor, 1200)
+		raw, headers := inst.Webhook(t, created.ID, OutcomeSucceeded)
+
+		forged := make(map[string][]string, len(headers))
+		for key := range headers {
+			forged[key] = []string{"t=" + strconv.FormatInt(time.Now().Unix(), 10) + ",v1=" + strings.Repeat("0", 64)}
+		}
+		event, err := inst.Processor.ParseWebhook(context.Background(), raw, forged)
+		h.assertFailed(t, event, err)
+	})
+
+	t.Run("webhook rejects tampered body", func(t *testing.T) {
+		inst := h.New(t)
+		created := mustCreate(t, inst.Processor, 1200)
+		raw, headers := inst.Webhook(t, created.ID, OutcomeSucceeded)
+
+		tampered := bytes.Replace(raw, []byte(created.ID), []byte(created.ID+"x"), 1)
+		if bytes.Equal(tampered, raw) {
+			t.Fatalf("webhook body does not reference intent %s", created.ID)
+		}
+		event, err := inst.Processor.ParseWebhook(context.Background(), tampered, headers)
+		h.assertFailed(t, event, err)
+	})
+
+	t.Run("calls honour cancelled contexts", func(t *testing.T) {
+		p := h.New(t).Processor
+		created := mustCreate(t, p, 1200)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		res, err := p.CreatePaymentIntent(ctx, intentRequest(1200))
+		h.assertFailed(t, res, err)
+		res, err = p.GetPaymentIntent(ctx, created.ID)
+		h.assertFailed(t, res, err)
+		res, err = p.ChargeClient(ctx, chargeRequest(h.ChargeToken, 1200))
+		h.assertFailed(t, res, err)
+	})
+
+	t.Run("calls honour deadlines", func(t *testing.T) {
+		p := h.New(t).Processor
+
+		for name, call := range map[string]func(ctx context.Context) (*model.PaymentProcessorResponse, error){
+			"create": func(ctx context.Context) (*model.PaymentProcessorResponse, error) {
+				return p.CreatePaymentIntent(ctx, intentRequest(h.HangAmount))
+			},
+			"charge": func(ctx context.Context) (*model.PaymentProcessorResponse, error) {
+				return p.ChargeClient(ctx, chargeRequest(h.ChargeToken, h.HangAmount))
+			},
+		} {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			start := time.Now()
+			res, err := call(ctx)
+			cancel()
+
+			if elapsed := time.Since(start); elapsed > cancelGrace {
+				t.Fatalf("%s returned %s after its deadline", name, elapsed)
+			}
+			h.assertFailed(t, res, err)
+		}
+	})
+
+	t.Run("errors do not leak sensitive data", func(t *testing.T) {
+		inst := h.New(t)
+		p := inst.Processor
+		created := mustCreate(t, p, 1200)
+		raw, headers := inst.Webhook(t, created.ID, OutcomeSucceeded)
+
+		sensitive := append([]string{created.ClientSecret, h.ChargeToken, h.DeclineToken}, h.Secrets...)
+		var errs []error
+
+		_, err := p.GetPaymentIntent(context.Background(), created.ClientSecret)
+		errs = append(errs, err)
+		_, err = p.ChargeClient(context.Background(), chargeRequest(h.DeclineToken, 1200))
+		errs = append(errs, err)
+		_, err = p.ChargeClient(context.Background(), chargeRequest(h.ChargeToken, h.FailAmount))
+		errs = append(errs, err)
+		_, err = p.ParseWebhook(context.Background(), append(raw, ' '), headers)
+		errs = append(errs, err)
+
+		for _, err := range errs {
+			if err == nil {
+				t.Fatal("expected every call to fail")
+			}
+			for _, secret := range sensitive {
+				if secret != "" && strings.Contains(err.Error(), secret) {
+					t.Fatalf("error %q leaks %q", err, secret)
+				}
+			}
+		}
+	})
+
+	t.Run("refunds respect the captured amount", func(t *testing.T) {
+		p := h.New(t).Processor
+		refunder, ok := p.(ports.IRefunder)
+		if !ok {
+			t.Skip("processor does not support refunds")
+		}
+		charged := mustCharge(t, p, h.ChargeToken, 1000)
+
+		rf, err := refunder.Refund(context.Background(), model.ProviderRefundRequest{PaymentIntentID: charged.ID, Amount: 400, Reason: "duplicate"})
+		if err != nil {
+			t.Fatalf("Refund: %v", err)
+		}
+		if rf.ID == "" || rf.Amount != 400 || rf.Status == "" {
+			t.Fatalf("expected a 400 refund, got %+v", rf)
+		}
+
+		rf, err = refunder.Refund(context.Background(), model.ProviderRefundRequest{PaymentIntentID: charged.ID, Amount: 700})
+		h.assertFailed(t, rf, err)
+
+		rf, err = refunder.Refund(context.Background(), model.ProviderRefundRequest{PaymentIntentID: charged.ID})
+		if err != nil {
+			t.Fatalf("Refund remaining: %v", err)
+		}
+		if rf.Amount != 600 {
+			t.Fatalf("expected the remaining 600 to be refunded, got %d", rf.Amount)
+		}
+	})
+
+	t.Run("cancels only void uncaptured intents", func(t *testing.T) {
+		p := h.New(t).Processor
+		refunder, ok := p.(ports.IRefunder)
+		if !ok {
+			t.Skip("processor does not support refunds")
+		}
+		created, err := p.CreatePaymentIntent(context.Background(), intentRequest(1500))
+		if err != nil {
+			t.Fatalf("CreatePaymentIntent: %v", err)
+		}
+
+		res, err := refunder.Cancel(context.Background(), created.ID)
+		if err != nil {
+			t.Fatalf("Cancel: %v", err)
+		}
+		if res.ID != created.ID || res.Status != "canceled" {
+			t.Fatalf("expected %s to be canceled, got %+v", created.ID, res)
+		}
+
+		res, err = refunder.Cancel(context.Background(), created.ID)
+		h.assertFailed(t, res, err)
+
+		charged := mustCharge(t, p, h.ChargeToken, 1000)
+		res, err = refunder.Cancel(context.Background(), charged.ID)
+		h.assertFailed(t, res, err)
+	})
+
+	t.Run("charges are found by attempt", func(t *testing.T) {
+		p := h.New(t).Processor
+		finder, ok := p.(ports.IChargeFinder)
+		if !ok {
+			t.Skip("processor does not support charge lookup")
+		}
+		req := model.PaymentIntentRequest{Amount: 1500, Currency: "eur", Token: h.ChargeToken, AttemptID: "attempt_" + randomHex()}
+
+		if _, err := finder.FindCharge(context.Background(), req.AttemptID); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound before charging, got %v", err)
+		}
+
+		charged, err := p.ChargeClient(context.Background(), req)
+		if err != nil {
+			t.Fatalf("ChargeClient: %v", err)
+		}
+		found, err := finder.FindCharge(context.Background(), req.AttemptID)
+		if err != nil {
+			t.Fatalf("FindCharge: %v", err)
+		}
+		if found.ID != charged.ID || found.Amount != 1500 || found.Status != charged.Status {
+			t.Fatalf("expected %+v, got %+v", charged, found)
+		}
+
+		// A retried attempt must not charge twice
+		again, err := p.ChargeClient(context.Background(), req)
+		if err != nil {
+			t.Fatalf("ChargeClient retry: %v", err)
+		}
+		if again.ID != charged.ID {
+			t.Fatalf("expected the retry to return %s, got %s", charged.ID, again.ID)
+		}
+	})
+
+	t.Run("ping reaches the provider", func(t *testing.T) {
+		pinger, ok := h.New(t).Processor.(ports.IProviderPinger)
+		if !ok {
+			t.Skip("processor does not support pings")
+		}
+		if err := pinger.Ping(context.Background()); err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+	})
+}
+
+// Failed calls return an error, no result, and nothing sensitive
+func (h Harness) assertFailed(t *testing.T, res interface{}, err error) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected an error, got %+v", res)
+	}
+	if !isNil(res) {
+		t.Fatalf("expected no result alongside %q, got %+v", err, res)
+	}
+	for _, secret := range h.Secrets {
+		if secret != "" && strings.Contains(err.Error(), secret) {
+			t.Fatalf("error %q leaks a secret", err)
+		}
+	}
+}
+
+func isNil(v interface{}) bool {
+	switch r := v.(type) {
+	case nil:
+		return true
+	case *model.PaymentProcessorResponse:
+		return r == nil
+	case *model.PaymentEvent:
+		return r == nil
+	case *model.ProviderRefundResponse:
+		return r == nil
+	}
+	return false
+}
+
+func intentRequest(amount int64) model.PaymentIntentRequest {
+	return model.PaymentIntentRequest{
+		Amount:        amount,
+		Currency:      "eur",
+		CustomerID:    "cus_conformance",
+		PaymentMethod: "card",
+	}
+}
+
+func chargeRequest(token string, amount int64) model.PaymentIntentRequest {
+	return model.PaymentIntentRequest{
+		Amount:     amount,
+		Currency:   "eur",
+		CustomerID: "cus_conformance",
+		Token:      token,
+	}
+}
+
+func mustCreate(t *testing.T, p ports.IPaymentProcessor, amount int64) *model.PaymentProcessorResponse {
+	t.Helper()
+	res, err := p.CreatePaymentIntent(context.Background(), intentRequest(amount))
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent: %v", err)
+	}
+	return res
+}
+
+func mustCharge(t *testing.T, p ports.IPaymentProcessor, token string, amount int64) *model.PaymentProcessorResponse {
+	t.Helper()
+	res, err := p.ChargeClient(context.Background(), chargeRequest(token, amount))
+	if err != nil {
+		t.Fatalf("ChargeClient: %v", err)
+	}
+	return res
+}
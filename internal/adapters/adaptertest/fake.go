@@ -0,0 +1,67 @@
+package adaptertest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+)
+
+const fakeTestWebhook = "whsec_adaptertest_fake"
+
+// Fake runs the suite against FakeAdapter, which is its own stand-in
+func Fake() Harness {
+	return Harness{
+		New: func(t *testing.T) Instance {
+			fake := adapters.NewFakeAdapter(fakeTestWebhook)
+			return Instance{
+				Processor: fake,
+				Webhook: func(t *testing.T, intentID string, outcome string) ([]byte, map[string][]string) {
+					t.Helper()
+					if outcome == OutcomeUnhandled {
+						return fakeUnhandled(t, intentID)
+					}
+					raw, headers, err := fake.SignedWebhook(intentID, outcome, "")
+					if err != nil {
+						t.Fatalf("SignedWebhook: %v", err)
+					}
+					return raw, headers
+				},
+			}
+		},
+		ChargeToken:  adapters.FakeTokenSuccess,
+		DeclineToken: adapters.FakeTokenDeclinePrefix + "do_not_honor",
+		FailAmount:   adapters.FakeAmountProviderError,
+		HangAmount:   adapters.FakeAmountTimeout,
+		Secrets:      []string{fakeTestWebhook},
+	}
+}
+
+// The fake only emits the events it maps, so sign an unhandled one here
+func fakeUnhandled(t *testing.T, intentID string) ([]byte, map[string][]string) {
+	t.Helper()
+
+	raw, err := json.Marshal(map[string]interface{}{
+		"id":      "evt_fake_" + randomHex(),
+		"type":    "payment_intent.created",
+		"created": time.Now().Unix(),
+		"data":    map[string]string{"id": intentID},
+	})
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(fakeTestWebhook))
+	mac.Write([]byte(ts + "."))
+	mac.Write(raw)
+
+	return raw, map[string][]string{
+		adapters.FakeSignatureHeader: {"t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))},
+	}
+}
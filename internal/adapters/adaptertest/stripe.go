@@ -0,0 +1,446 @@
+package adaptertest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v72"
+	"github.com/stripe/stripe-go/v72/webhook"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+)
+
+// Scripted inputs understood by StripeServer
+const (
+	StripeTokenSuccess  = "pm_card_visa"
+	StripeTokenDeclined = "pm_card_chargeDeclined"
+	StripeAmountError   = int64(50000)
+	StripeAmountHang    = int64(50400)
+
+	stripeTestKey     = "sk_test_adaptertest_4eC39HqLyjWDarjtT1zdp7dc"
+	stripeTestWebhook = "whsec_adaptertest_5f1c0b2a"
+)
+
+// Stripe runs the suite against StripeAdapter and a StripeServer. The adapter
+// keeps its client in stripe-go globals, so tests using it must not run in parallel.
+func Stripe() Harness {
+	return Harness{
+		New: func(t *testing.T) Instance {
+			srv := NewStripeServer(t, stripeTestKey, stripeTestWebhook)
+			srv.Install(t)
+			return Instance{
+				Processor: adapters.NewStripeAdapter(stripeTestKey, stripeTestWebhook),
+				Webhook:   srv.Webhook,
+			}
+		},
+		ChargeToken:  StripeTokenSuccess,
+		DeclineToken: StripeTokenDeclined,
+		FailAmount:   StripeAmountError,
+		HangAmount:   StripeAmountHang,
+		Secrets:      []string{stripeTestKey, stripeTestWebhook},
+	}
+}
+
+// StripeServer is an in-memory stand-in for the parts of the Stripe API the
+// adapter uses: payment intents, payment methods, customers, refunds and balance
+type StripeServer struct {
+	*httptest.Server
+
+	apiKey        string
+	webhookSecret string
+
+	mu           sync.Mutex
+	intents      map[string]*stripeIntent
+	methods      map[string]string // payment method -> customer
+	customers    map[string]bool
+	idempotency  map[string]string // idempotency key -> intent
+	customerKeys map[string]string // idempotency key -> customer
+}
+
+type stripeIntent struct {
+	ID            string            `json:"id"`
+	Object        string            `json:"object"`
+	Amount        int64             `json:"amount"`
+	Currency      string            `json:"currency"`
+	Status        string            `json:"status"`
+	ClientSecret  string            `json:"client_secret"`
+	Customer      string            `json:"customer,omitempty"`
+	PaymentMethod string            `json:"payment_method,omitempty"`
+	Metadata      map[string]string `json:"metadata"`
+	Refunded      int64             `json:"-"`
+}
+
+func NewStripeServer(t *testing.T, apiKey string, webhookSecret string) *StripeServer {
+	s := &StripeServer{
+		apiKey:        apiKey,
+		webhookSecret: webhookSecret,
+		intents:       make(map[string]*stripeIntent),
+		methods:       make(map[string]string),
+		customers:     make(map[string]bool),
+		idempotency:   make(map[string]string),
+		customerKeys:  make(map[string]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Install points stripe-go at the server for the duration of the test
+func (s *StripeServer) Install(t *testing.T) {
+	prevKey := stripe.Key
+	prevBackend := stripe.GetBackend(stripe.APIBackend)
+
+	stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		URL:               stripe.String(s.URL),
+		HTTPClient:        s.Client(),
+		MaxNetworkRetries: stripe.Int64(0),
+		LeveledLogger:     &stripe.LeveledLogger{Level: stripe.LevelNull},
+	}))
+
+	t.Cleanup(func() {
+		stripe.Key = prevKey
+		stripe.SetBackend(stripe.APIBackend, prevBackend)
+	})
+}
+
+// Webhook moves an intent to outcome and returns a delivery signed like Stripe's
+func (s *StripeServer) Webhook(t *testing.T, intentID string, outcome string) ([]byte, map[string][]string) {
+	t.Helper()
+
+	s.mu.Lock()
+	pi, ok := s.intents[intentID]
+	if !ok {
+		s.mu.Unlock()
+		t.Fatalf("unknown intent %s", intentID)
+	}
+
+	eventType := ""
+	object := map[string]interface{}{}
+	switch outcome {
+	case OutcomeSucceeded:
+		eventType = "payment_intent.succeeded"
+		pi.Status = "succeeded"
+		if pi.PaymentMethod == "" {
+			pi.PaymentMethod = StripeTokenSuccess
+		}
+	case OutcomeFailed:
+		eventType = "payment_intent.payment_failed"
+		pi.Status = "requires_payment_method"
+		object["last_payment_error"] = map[string]interface{}{
+			"type":           "card_error",
+			"code":           "card_declined",
+			"payment_method": map[string]string{"id": StripeTokenDeclined, "object": "payment_method"},
+		}
+	case OutcomeCanceled:
+		eventType = "payment_intent.canceled"
+		pi.Status = "canceled"
+	case OutcomeUnhandled:
+		eventType = "payment_intent.created"
+	default:
+		s.mu.Unlock()
+		t.Fatalf("unknown outcome %q", outcome)
+	}
+
+	object["id"] = pi.ID
+	object["object"] = "payment_intent"
+	object["amount"] = pi.Amount
+	object["currency"] = pi.Currency
+	object["status"] = pi.Status
+	object["metadata"] = pi.Metadata
+	if pi.PaymentMethod != "" && outcome != OutcomeFailed {
+		object["payment_method"] = pi.PaymentMethod
+	}
+	s.mu.Unlock()
+
+	raw, err := json.Marshal(map[string]interface{}{
+		"id":          "evt_" + randomHex(),
+		"object":      "event",
+		"api_version": stripe.APIVersion,
+		"created":     time.Now().Unix(),
+		"type":        eventType,
+		"data":        map[string]interface{}{"object": object},
+	})
+	if err != nil {
+		t.Fatalf("marshal event: %v", err)
+	}
+
+	now := time.Now()
+	signature := hex.EncodeToString(webhook.ComputeSignature(now, raw, s.webhookSecret))
+	return raw, map[string][]string{
+		"Stripe-Signature": {"t=" + strconv.FormatInt(now.Unix(), 10) + ",v1=" + signature},
+	}
+}
+
+func (s *StripeServer) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		stripeError(w, http.StatusUnauthorized, "invalid_request_error", "", "Invalid API Key provided")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		stripeError(w, http.StatusBadRequest, "invalid_request_error", "", "Invalid request body")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" {
+		stripeError(w, http.StatusNotFound, "invalid_request_error", "", "Unrecognized request URL")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[1] == "balance":
+		writeJSON(w, map[string]interface{}{"object": "balance", "livemode": false})
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "customers":
+		s.createCustomer(w, r)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "payment_methods":
+		s.getPaymentMethod(w, parts[2])
+	case r.Method == http.MethodPost && len(parts) == 4 && parts[1] == "payment_methods" && parts[3] == "attach":
+		s.attachPaymentMethod(w, r, parts[2])
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "payment_intents":
+		s.createIntent(w, r)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "payment_intents" && parts[2] == "search":
+		s.searchIntents(w, r)
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "payment_intents":
+		s.getIntent(w, parts[2])
+	case r.Method == http.MethodPost && len(parts) == 4 && parts[1] == "payment_intents" && parts[3] == "cancel":
+		s.cancelIntent(w, parts[2])
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "refunds":
+		s.createRefund(w, r)
+	default:
+		stripeError(w, http.StatusNotFound, "invalid_request_error", "", "Unrecognized request URL")
+	}
+}
+
+// Replays of an idempotency key return the original customer
+func (s *StripeServer) createCustomer(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Idempotency-Key")
+
+	s.mu.Lock()
+	id, ok := s.customerKeys[key]
+	if !ok || key == "" {
+		id = "cus_" + randomHex()
+		s.customers[id] = true
+		if key != "" {
+			s.customerKeys[key] = id
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{"id": id, "object": "customer"})
+}
+
+// Any pm_ id exists, mirroring Stripe's test payment methods
+func (s *StripeServer) getPaymentMethod(w http.ResponseWriter, id string) {
+	if !strings.HasPrefix(id, "pm_") {
+		stripeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", fmt.Sprintf("No such PaymentMethod: '%s'", id))
+		return
+	}
+
+	s.mu.Lock()
+	customer := s.methods[id]
+	s.mu.Unlock()
+
+	pm := map[string]interface{}{"id": id, "object": "payment_method", "type": "card"}
+	if customer != "" {
+		pm["customer"] = customer
+	}
+	writeJSON(w, pm)
+}
+
+func (s *StripeServer) attachPaymentMethod(w http.ResponseWriter, r *http.Request, id string) {
+	customer := r.PostForm.Get("customer")
+
+	s.mu.Lock()
+	known := s.customers[customer]
+	if known {
+		s.methods[id] = customer
+	}
+	s.mu.Unlock()
+
+	if !known {
+		stripeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", fmt.Sprintf("No such customer: '%s'", customer))
+		return
+	}
+	writeJSON(w, map[string]interface{}{"id": id, "object": "payment_method", "type": "card", "customer": customer})
+}
+
+func (s *StripeServer) createIntent(w http.ResponseWriter, r *http.Request) {
+	amount, _ := strconv.ParseInt(r.PostForm.Get("amount"), 10, 64)
+	paymentMethod := r.PostForm.Get("payment_method")
+	key := r.Header.Get("Idempotency-Key")
+
+	// Replays of an idempotency key return the original intent
+	s.mu.Lock()
+	if id, ok := s.idempotency[key]; ok && key != "" {
+		copied := *s.intents[id]
+		s.mu.Unlock()
+		writeJSON(w, copied)
+		return
+	}
+	s.mu.Unlock()
+
+	switch {
+	case amount == StripeAmountHang:
+		<-r.Context().Done()
+		return
+	case amount == StripeAmountError:
+		stripeError(w, http.StatusInternalServerError, "api_error", "", "An unknown error occurred")
+		return
+	case amount <= 0:
+		stripeError(w, http.StatusBadRequest, "invalid_request_error", "parameter_invalid_integer", "This value must be greater than or equal to 1.")
+		return
+	case r.PostForm.Get("confirm") == "true" && paymentMethod == StripeTokenDeclined:
+		stripeError(w, http.StatusPaymentRequired, "card_error", "card_declined", fmt.Sprintf("Your card was declined. (%s)", paymentMethod))
+		return
+	}
+
+	id := "pi_" + randomHex()
+	pi := &stripeIntent{
+		ID:            id,
+		Object:        "payment_intent",
+		Amount:        amount,
+		Currency:      strings.ToLower(r.PostForm.Get("currency")),
+		Status:        "requires_payment_method",
+		ClientSecret:  id + "_secret_" + randomHex(),
+		Customer:      r.PostForm.Get("customer"),
+		PaymentMethod: paymentMethod,
+		Metadata:      map[string]string{},
+	}
+	if r.PostForm.Get("confirm") == "true" {
+		pi.Status = "succeeded"
+	}
+	for field, values := range r.PostForm {
+		if name, ok := strings.CutPrefix(field, "metadata["); ok && len(values) > 0 {
+			pi.Metadata[strings.TrimSuffix(name, "]")] = values[0]
+		}
+	}
+
+	s.mu.Lock()
+	s.intents[id] = pi
+	if key != "" {
+		s.idempotency[key] = id
+	}
+	copied := *pi
+	s.mu.Unlock()
+
+	writeJSON(w, copied)
+}
+
+func (s *StripeServer) getIntent(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	pi, ok := s.intents[id]
+	var copied stripeIntent
+	if ok {
+		copied = *pi
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		stripeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", fmt.Sprintf("No such payment_intent: '%s'", id))
+		return
+	}
+	writeJSON(w, copied)
+}
+
+// Only the metadata['key']:'value' form of the search language is understood
+func (s *StripeServer) searchIntents(w http.ResponseWriter, r *http.Request) {
+	var key, value string
+	_, err := fmt.Sscanf(strings.NewReplacer("['", " ", "']:'", " ", "'", "").Replace(r.Form.Get("query")), "metadata %s %s", &key, &value)
+	if err != nil {
+		stripeError(w, http.StatusBadRequest, "invalid_request_error", "", "Unsupported search query")
+		return
+	}
+
+	s.mu.Lock()
+	data := []stripeIntent{}
+	for _, pi := range s.intents {
+		if pi.Metadata[key] == value {
+			data = append(data, *pi)
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"object":   "search_result",
+		"data":     data,
+		"has_more": false,
+		"url":      "/v1/payment_intents/search",
+	})
+}
+
+func (s *StripeServer) cancelIntent(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pi, ok := s.intents[id]
+	if !ok {
+		stripeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", fmt.Sprintf("No such payment_intent: '%s'", id))
+		return
+	}
+	if pi.Status == "succeeded" || pi.Status == "canceled" {
+		stripeError(w, http.StatusBadRequest, "invalid_request_error", "payment_intent_unexpected_state", fmt.Sprintf("You cannot cancel this PaymentIntent because it has a status of %s.", pi.Status))
+		return
+	}
+	pi.Status = "canceled"
+
+	writeJSON(w, *pi)
+}
+
+func (s *StripeServer) createRefund(w http.ResponseWriter, r *http.Request) {
+	id := r.PostForm.Get("payment_intent")
+	amount, _ := strconv.ParseInt(r.PostForm.Get("amount"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pi, ok := s.intents[id]
+	if !ok || pi.Status != "succeeded" {
+		stripeError(w, http.StatusBadRequest, "invalid_request_error", "charge_not_refundable", fmt.Sprintf("PaymentIntent %s has no refundable charge", id))
+		return
+	}
+	if amount == 0 {
+		amount = pi.Amount - pi.Refunded
+	}
+	if amount <= 0 || pi.Refunded+amount > pi.Amount {
+		stripeError(w, http.StatusBadRequest, "invalid_request_error", "amount_too_large", "Refund amount is greater than unrefunded amount on charge")
+		return
+	}
+	pi.Refunded += amount
+
+	writeJSON(w, map[string]interface{}{
+		"id":             "re_" + randomHex(),
+		"object":         "refund",
+		"amount":         amount,
+		"currency":       pi.Currency,
+		"payment_intent": pi.ID,
+		"status":         "succeeded",
+	})
+}
+
+func stripeError(w http.ResponseWriter, status int, errType string, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"type": errType, "code": code, "message": message},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomHex() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
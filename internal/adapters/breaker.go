@@ -0,0 +1,91 @@
+package adapters
+
+import (
+	"sync"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// CircuitBreaker stops calls to a provider after threshold consecutive
+// failures. Once cooldown has passed a single probe call is let through: its
+// success closes the circuit, its failure opens it again.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, state: model.CircuitClosed}
+}
+
+// Allow reports whether a call may go through. Every allowed call must be
+// followed by Success, Failure or Release.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case model.CircuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = model.CircuitHalfOpen
+		b.probing = true
+		return true
+	case model.CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// Success records that the provider answered, even with a decline
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = model.CircuitClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records an outage or a timeout
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == model.CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = model.CircuitOpen
+		b.openedAt = time.Now()
+	}
+	b.probing = false
+}
+
+// Release ends a call that says nothing about the provider, e.g. one the
+// caller gave up on
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// State returns the circuit state, the consecutive failures and when the
+// circuit last opened
+func (b *CircuitBreaker) State() (string, int, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state, b.failures, b.openedAt
+}
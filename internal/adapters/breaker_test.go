@@ -0,0 +1,96 @@
+package adapters_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+func assertCircuit(t *testing.T, b *adapters.CircuitBreaker, want string) {
+	t.Helper()
+	if state, _, _ := b.State(); state != want {
+		t.Fatalf("expected the circuit to be %s, got %s", want, state)
+	}
+}
+
+func TestBreakerOpensAtThreshold(t *testing.T) {
+	b := adapters.NewCircuitBreaker(3, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if !b.Allow() {
+			t.Fatalf("expected call %d to be allowed", i+1)
+		}
+		b.Failure()
+	}
+	assertCircuit(t, b, model.CircuitClosed)
+
+	// A success in between starts the count again
+	b.Allow()
+	b.Success()
+	for i := 0; i < 2; i++ {
+		b.Allow()
+		b.Failure()
+	}
+	assertCircuit(t, b, model.CircuitClosed)
+
+	b.Allow()
+	b.Failure()
+	assertCircuit(t, b, model.CircuitOpen)
+	if _, failures, _ := b.State(); failures != 3 {
+		t.Fatalf("expected 3 consecutive failures, got %d", failures)
+	}
+	if b.Allow() {
+		t.Fatal("expected an open circuit to reject calls")
+	}
+}
+
+func TestBreakerLetsOneProbeThrough(t *testing.T) {
+	b := adapters.NewCircuitBreaker(1, time.Millisecond)
+	b.Allow()
+	b.Failure()
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatal("expected a probe once the cooldown passed")
+	}
+	assertCircuit(t, b, model.CircuitHalfOpen)
+	if b.Allow() {
+		t.Fatal("expected only one probe at a time")
+	}
+
+	// A failed probe opens the circuit for another cooldown
+	b.Failure()
+	assertCircuit(t, b, model.CircuitOpen)
+	if b.Allow() {
+		t.Fatal("expected the circuit to stay open during the cooldown")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("expected another probe once the cooldown passed")
+	}
+	b.Success()
+	assertCircuit(t, b, model.CircuitClosed)
+	if !b.Allow() || !b.Allow() {
+		t.Fatal("expected a closed circuit to allow every call")
+	}
+}
+
+func TestBreakerReleaseFreesTheProbe(t *testing.T) {
+	b := adapters.NewCircuitBreaker(1, time.Millisecond)
+	b.Allow()
+	b.Failure()
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatal("expected a probe once the cooldown passed")
+	}
+	// The caller gave up on the probe, which says nothing about the provider
+	b.Release()
+	assertCircuit(t, b, model.CircuitHalfOpen)
+	if !b.Allow() {
+		t.Fatal("expected the next call to probe after a release")
+	}
+}
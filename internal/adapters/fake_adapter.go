@@ -0,0 +1,437 @@
+package adapters
+
+import (
+    "fmt"
+    "sync"
+    "time"
+    "errors"
+    "context"
+    "strconv"
+    "strings"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+
+    "github.com/danielmoisemontezima/zw-payment-service/pkg/utils"
+    "github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+    "github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+const (
+    FakeProvider model.PaymentProvider = "fake"
+    FakeSignatureHeader = "Fake-Signature"
+
+    // Magic amounts (minor units) that script an outcome
+    FakeAmountRequiresAction  int64 = 10001
+    FakeAmountDeclined        int64 = 20001
+    FakeAmountInsufficient    int64 = 20002
+    FakeAmountExpiredCard     int64 = 20003
+    FakeAmountProviderError   int64 = 50000
+    FakeAmountTimeout         int64 = 50400
+
+    // Magic tokens for ChargeClient; pm_fake_decline_<code> declines with <code>
+    FakeTokenSuccess        = "pm_fake_success"
+    FakeTokenRequiresAction = "pm_fake_3ds"
+    FakeTokenDeclinePrefix  = "pm_fake_decline_"
+    FakeTokenProviderError  = "pm_fake_error"
+    FakeTokenTimeout        = "pm_fake_timeout"
+
+    fakeSignatureTolerance = 5 * time.Minute
+)
+
+type fakeIntent struct {
+    model.PaymentProcessorResponse
+    // Everything sent as metadata, including the service's own keys
+    AllMetadata map[string]string
+    Refunded    int64
+}
+
+// FakeAdapter is a scriptable, network-free provider for local development and tests
+type FakeAdapter struct {
+    webhookSecret string
+
+    mu       sync.Mutex
+    intents    map[string]*fakeIntent
+    attempts   map[string]string
+    references map[string]string
+}
+
+func NewFakeAdapter(webhookSecret string) *FakeAdapter {
+    return &FakeAdapter{
+        webhookSecret: webhookSecret,
+        intents:       make(map[string]*fakeIntent),
+        attempts:      make(map[string]string),
+        references:    make(map[string]string),
+    }
+}
+
+func (f *FakeAdapter) Name() model.PaymentProvider {
+    return FakeProvider
+}
+
+func (f *FakeAdapter) Ping(ctx context.Context) error {
+    return ctx.Err()
+}
+
+// Mirrors Stripe so amounts rejected in production are rejected locally too
+func (f *FakeAdapter) AmountLimits(currency model.Currency) model.AmountLimits {
+    return stripeAmountLimits(currency)
+}
+
+func (f *FakeAdapter) CreatePaymentIntent(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error) {
+    if err := f.script(ctx, req.Amount, ""); err != nil {
+        return nil, fmt.Errorf("Payment creation failed #fcpi0: %w", err)
+    }
+
+    // The reference works as an idempotency key, like Stripe's
+    f.mu.Lock()
+    id, ok := f.references[req.Reference]
+    f.mu.Unlock()
+    if ok && req.Reference != "" {
+        return f.GetPaymentIntent(ctx, id)
+    }
+
+    intent := f.store(req, "requires_payment_method", "")
+    return &intent.PaymentProcessorResponse, nil
+}
+
+func (f *FakeAdapter) GetPaymentIntent(ctx context.Context, id string) (*model.PaymentProcessorResponse, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("Collecting payment details failed #fgpi1: %w", err)
+    }
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    intent, ok := f.intents[id]
+    if !ok {
+        return nil, &ports.ProviderError{Class: ports.ErrorValidation, Err: errors.New("Collecting payment details failed #fgpi0")}
+    }
+
+    res := intent.PaymentProcessorResponse
+    res.Metadata = callerMetadata(intent.AllMetadata)
+    return &res, nil
+}
+
+func (f *FakeAdapter) ChargeClient(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error) {
+    if req.Token == "" {
+        return nil, &ports.ProviderError{Class: ports.ErrorValidation, Err: errors.New("Direct charge failed #fcc0: missing payment method token")}
+    }
+
+    // A retried attempt returns the original charge, like an idempotency key
+    if res, err := f.FindCharge(ctx, req.AttemptID); err == nil {
+        return res, nil
+    }
+
+    if err := f.script(ctx, req.Amount, req.Token); err != nil {
+        return nil, fmt.Errorf("Direct charge failed #fcc1: %w", err)
+    }
+
+    status := "succeeded"
+    if req.Amount == FakeAmountRequiresAction || req.Token == FakeTokenRequiresAction {
+        status = "requires_action"
+    }
+
+    intent := f.store(req, status, req.Token)
+    return &intent.PaymentProcessorResponse, nil
+}
+
+func (f *FakeAdapter) FindCharge(ctx context.Context, attemptID string) (*model.PaymentProcessorResponse, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("Finding charge failed #ffc0: %w", err)
+    }
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    id, ok := f.attempts[attemptID]
+    if !ok || attemptID == "" {
+        return nil, fmt.Errorf("Finding charge failed #ffc1: %w", ports.ErrNotFound)
+    }
+
+    res := f.intents[id].PaymentProcessorResponse
+    res.Metadata = callerMetadata(f.intents[id].AllMetadata)
+    return &res, nil
+}
+
+func (f *FakeAdapter) Refund(ctx context.Context, req model.ProviderRefundRequest) (*model.ProviderRefundResponse, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("Refund failed #frf2: %w", err)
+    }
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    intent, ok := f.intents[req.PaymentIntentID]
+    if !ok || intent.Status != "succeeded" {
+        return nil, &ports.ProviderError{Class: ports.ErrorValidation, Err: errors.New("Refund failed #frf0")}
+    }
+
+    amount := req.Amount
+    if amount == 0 {
+        amount = intent.Amount - intent.Refunded
+    }
+    if amount <= 0 || intent.Refunded+amount > intent.Amount {
+        return nil, &ports.ProviderError{Class: ports.ErrorValidation, Err: errors.New("Refund failed #frf1: amount exceeds refundable balance")}
+    }
+    intent.Refunded += amount
+
+    return &model.ProviderRefundResponse{
+        ID:     "re_fake_" + randomID(),
+        Amount: amount,
+        Status: "succeeded",
+    }, nil
+}
+
+func (f *FakeAdapter) Cancel(ctx context.Context, paymentIntentID string) (*model.PaymentProcessorResponse, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, fmt.Errorf("Cancel failed #fcn1: %w", err)
+    }
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    // Like Stripe, money already taken has to be refunded instead
+    intent, ok := f.intents[paymentIntentID]
+    if !ok || intent.Status == "succeeded" || intent.Status == "canceled" {
+        return nil, &ports.ProviderError{Class: ports.ErrorValidation, Err: errors.New("Cancel failed #fcn0")}
+    }
+    intent.Status = "canceled"
+
+    res := intent.PaymentProcessorResponse
+    res.Metadata = callerMetadata(intent.AllMetadata)
+    return &res, nil
+}
+
+func (f *FakeAdapter) ParseWebhook(ctx context.Context, raw []byte, headers map[string][]string) (*model.PaymentEvent, error) {
+    sigHeader := utils.GetHeader(headers, FakeSignatureHeader)
+    if sigHeader == "" {
+        return nil, errors.New("missing fake-signature header")
+    }
+
+    if err := f.verify(raw, sigHeader, time.Now()); err != nil {
+        return nil, errors.New("invalid webhook signature")
+    }
+
+    var event fakeEvent
+    if err := json.Unmarshal(raw, &event); err != nil {
+        return nil, errors.New("failed to parse event data")
+    }
+
+    eventType := ""
+    switch event.Type {
+    case "payment_intent.succeeded":
+        eventType = ports.PaymentSucceeded
+    case "payment_intent.payment_failed":
+        eventType = ports.PaymentFailed
+    case "payment_intent.canceled":
+        eventType = ports.PaymentCancelled
+    default:
+        return &model.PaymentEvent{
+            Type:    event.Type,
+            Payload: "Omitted",
+        }, nil
+    }
+
+    return &model.PaymentEvent{
+        Type:          eventType,
+        PaymentIntent: event.Data.ID,
+        PaymentMethod: event.Data.PaymentMethod,
+        Reference:     event.Data.Metadata[ports.MetadataReference],
+        Metadata:      callerMetadata(event.Data.Metadata),
+        Payload: model.PaymentIntentResponse{
+            ID:       event.Data.ID,
+            Amount:   event.Data.Amount,
+            Currency: event.Data.Currency,
+            Status:   event.Data.Status,
+            Metadata: callerMetadata(event.Data.Metadata),
+        },
+    }, nil
+}
+
+type fakeEvent struct {
+    ID      string `json:"id"`
+    Type    string `json:"type"`
+    Created int64  `json:"created"`
+    Data    struct {
+        ID            string `json:"id"`
+        Amount        int64  `json:"amount"`
+        Currency      string `json:"currency"`
+        Status        string `json:"status"`
+        PaymentMethod string `json:"payment_method"`
+        Metadata      map[string]string `json:"metadata,omitempty"`
+    } `json:"data"`
+}
+
+// SignedWebhook moves a stored intent to outcome (succeeded, failed or canceled)
+// and returns the signed event body and headers, as the provider would send them
+func (f *FakeAdapter) SignedWebhook(intentID string, outcome string, paymentMethod string) ([]byte, map[string][]string, error) {
+    f.mu.Lock()
+    intent, ok := f.intents[intentID]
+    if !ok {
+        f.mu.Unlock()
+        return nil, nil, model.NotFound("intent_not_found", "unknown fake intent %s", intentID)
+    }
+
+    var event fakeEvent
+    switch outcome {
+    case "succeeded":
+        event.Type = "payment_intent.succeeded"
+        intent.Status = "succeeded"
+    case "failed":
+        event.Type = "payment_intent.payment_failed"
+        intent.Status = "requires_payment_method"
+    case "canceled":
+        event.Type = "payment_intent.canceled"
+        intent.Status = "canceled"
+    default:
+        f.mu.Unlock()
+        return nil, nil, model.Invalid("outcome_invalid", "unknown outcome %q, expected succeeded, failed or canceled", outcome)
+    }
+
+    if paymentMethod != "" {
+        intent.PaymentMethodID = paymentMethod
+    }
+    if intent.PaymentMethodID == "" {
+        intent.PaymentMethodID = "pm_fake_" + randomID()
+    }
+
+    event.ID = "evt_fake_" + randomID()
+    event.Created = time.Now().Unix()
+    event.Data.ID = intent.ID
+    event.Data.Amount = intent.Amount
+    event.Data.Currency = intent.Currency
+    event.Data.Status = intent.Status
+    event.Data.PaymentMethod = intent.PaymentMethodID
+    event.Data.Metadata = intent.AllMetadata
+    f.mu.Unlock()
+
+    raw, err := json.Marshal(event)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    headers := map[string][]string{
+        FakeSignatureHeader: {f.sign(raw, time.Now())},
+    }
+    return raw, headers, nil
+}
+
+func (f *FakeAdapter) SignWebhook(raw []byte) map[string][]string {
+    return map[string][]string{FakeSignatureHeader: {f.sign(raw, time.Now())}}
+}
+
+// Signature format mirrors Stripe's: t=<unix>,v1=<hex hmac-sha256 of "t.body">
+func (f *FakeAdapter) sign(raw []byte, at time.Time) string {
+    ts := strconv.FormatInt(at.Unix(), 10)
+    return "t=" + ts + ",v1=" + f.mac(ts, raw)
+}
+
+func (f *FakeAdapter) verify(raw []byte, header string, now time.Time) error {
+    var ts, sig string
+    for _, part := range strings.Split(header, ",") {
+        key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+        switch key {
+        case "t":
+            ts = value
+        case "v1":
+            sig = value
+        }
+    }
+
+    unix, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil || sig == "" {
+        return errors.New("malformed signature header")
+    }
+    if now.Sub(time.Unix(unix, 0)).Abs() > fakeSignatureTolerance {
+        return errors.New("signature timestamp outside tolerance")
+    }
+    if !hmac.Equal([]byte(sig), []byte(f.mac(ts, raw))) {
+        return errors.New("signature mismatch")
+    }
+    return nil
+}
+
+func (f *FakeAdapter) mac(ts string, raw []byte) string {
+    h := hmac.New(sha256.New, []byte(f.webhookSecret))
+    h.Write([]byte(ts))
+    h.Write([]byte("."))
+    h.Write(raw)
+    return hex.EncodeToString(h.Sum(nil))
+}
+
+// script turns magic amounts and tokens into scripted failures
+func (f *FakeAdapter) script(ctx context.Context, amount int64, token string) error {
+    switch {
+    case amount == FakeAmountTimeout || token == FakeTokenTimeout:
+        <-ctx.Done()
+        return ctx.Err()
+    case amount == FakeAmountProviderError || token == FakeTokenProviderError:
+        return &ports.ProviderError{Class: ports.ErrorOutage, Err: errors.New("provider unavailable (503)")}
+    case amount == FakeAmountDeclined:
+        return fakeDecline("card_declined")
+    case amount == FakeAmountInsufficient:
+        return fakeDecline("insufficient_funds")
+    case amount == FakeAmountExpiredCard:
+        return fakeDecline("expired_card")
+    case strings.HasPrefix(token, FakeTokenDeclinePrefix):
+        return fakeDecline(strings.TrimPrefix(token, FakeTokenDeclinePrefix))
+    }
+    return ctx.Err()
+}
+
+func fakeDecline(code string) error {
+    return &ports.ProviderError{Class: ports.ErrorDecline, Err: fmt.Errorf("card declined: %s", code), DeclineCode: code}
+}
+
+func (f *FakeAdapter) store(req model.PaymentIntentRequest, status string, paymentMethod string) *fakeIntent {
+    id := "pi_fake_" + randomID()
+
+    metadata := make(map[string]string, len(req.Metadata)+2)
+    for key, value := range req.Metadata {
+        metadata[key] = value
+    }
+    if req.Reference != "" {
+        metadata[ports.MetadataReference] = req.Reference
+    }
+    if req.AttemptID != "" {
+        metadata[ports.MetadataAttemptID] = req.AttemptID
+    }
+
+    intent := &fakeIntent{
+        PaymentProcessorResponse: model.PaymentProcessorResponse{
+            ID:                id,
+            Amount:            req.Amount,
+            Currency:          req.Currency,
+            Status:            status,
+            ClientSecret:      id + "_secret_" + randomID(),
+            PaymentMethodID:   paymentMethod,
+            PaymentMethodType: "card",
+            PaymentProvider:   string(FakeProvider),
+            Metadata:          callerMetadata(metadata),
+        },
+        AllMetadata: metadata,
+    }
+
+    f.mu.Lock()
+    f.intents[id] = intent
+    if req.AttemptID != "" {
+        f.attempts[req.AttemptID] = id
+    }
+    if req.Reference != "" {
+        f.references[req.Reference] = id
+    }
+    f.mu.Unlock()
+
+    copied := *intent
+    copied.Metadata = callerMetadata(metadata)
+    return &copied
+}
+
+func randomID() string {
+    b := make([]byte, 8)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
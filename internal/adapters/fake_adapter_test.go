@@ -0,0 +1,11 @@
+package adapters_test
+
+import (
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters/adaptertest"
+)
+
+func TestFakeAdapter(t *testing.T) {
+	adaptertest.Processor(t, adaptertest.Fake())
+}
@@ -0,0 +1,20 @@
+package adapters
+
+import (
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// Drops the keys the service sets for its own bookkeeping, callers only see theirs
+func callerMetadata(metadata map[string]string) map[string]string {
+	var out map[string]string
+	for key, value := range metadata {
+		if key == ports.MetadataReference || key == ports.MetadataAttemptID {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(metadata))
+		}
+		out[key] = value
+	}
+	return out
+}
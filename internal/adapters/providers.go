@@ -0,0 +1,43 @@
+package adapters
+
+import (
+	"log"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/core"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+const StripeProvider model.PaymentProvider = "stripe"
+
+// NewProviders builds the adapters enabled in cfg, keyed by provider and not
+// wrapped yet, so callers can reach capabilities the wrapper does not expose
+func NewProviders(cfg config.ProvidersConfig) map[model.PaymentProvider]ports.IPaymentProcessor {
+	providers := make(map[model.PaymentProvider]ports.IPaymentProcessor)
+	if cfg.Stripe.Enabled {
+		providers[StripeProvider] = NewStripeAdapter(string(cfg.Stripe.SecretKey), string(cfg.Stripe.WebhookSecret))
+	}
+	if cfg.Fake.Enabled {
+		log.Printf("Fake payment provider enabled, do not use in production")
+		providers[FakeProvider] = NewFakeAdapter(string(cfg.Fake.WebhookSecret))
+	}
+	if cfg.PayPal.Enabled {
+		log.Printf("PayPal is enabled but no adapter is available yet, skipping")
+		//providers["paypal"] = NewPayPalAdapter(cfg.PayPal.ClientID, string(cfg.PayPal.Secret))
+	}
+	return providers
+}
+
+// NewProviderRegistry registers providers, behind retries and a circuit
+// breaker when resilience is enabled
+func NewProviderRegistry(providers map[model.PaymentProvider]ports.IPaymentProcessor, resilience config.ResilienceConfig) *core.ProviderRegistry {
+	registry := core.NewProviderRegistry()
+	for name, processor := range providers {
+		if resilience.Enabled {
+			processor = NewResilientProcessor(processor, resilience)
+		}
+		registry.Register(name, processor)
+	}
+	return registry
+}
@@ -0,0 +1,133 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var (
+	_ ports.IRateSource = (*FileRateSource)(nil)
+	_ ports.IRateSource = (*HTTPRateSource)(nil)
+)
+
+var decimalRate = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// Rates document shared by the file and HTTP sources:
+// {"base": "eur", "rates": {"usd": "1.0842", "gbp": 0.8571}}
+type ratesDocument struct {
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+// FileRateSource reads rates from a JSON file, re-read on every fetch so it
+// can be replaced while the service runs
+type FileRateSource struct {
+	path string
+}
+
+func NewFileRateSource(path string) *FileRateSource {
+	return &FileRateSource{path: path}
+}
+
+func (s *FileRateSource) Name() string {
+	return "file"
+}
+
+func (s *FileRateSource) FetchRates(ctx context.Context) ([]model.FxRate, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rates file: %w", err)
+	}
+	return parseRates(raw, s.Name(), time.Now())
+}
+
+// HTTPRateSource fetches the same document from a URL
+type HTTPRateSource struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPRateSource(url string, client *http.Client) *HTTPRateSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPRateSource{url: url, client: client}
+}
+
+func (s *HTTPRateSource) Name() string {
+	return "http"
+}
+
+func (s *HTTPRateSource) FetchRates(ctx context.Context) ([]model.FxRate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building rates request: %w", err)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching rates: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching rates: unexpected status %s", res.Status)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading rates: %w", err)
+	}
+	return parseRates(raw, s.Name(), time.Now())
+}
+
+// Rejects the whole document on any bad entry, a partial update would mix
+// rates from different moments
+func parseRates(raw []byte, source string, fetchedAt time.Time) ([]model.FxRate, error) {
+	var doc ratesDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("parsing rates: %w", err)
+	}
+
+	base, err := model.LookupCurrency(doc.Base)
+	if err != nil {
+		return nil, fmt.Errorf("parsing rates: base: %w", err)
+	}
+
+	rates := make([]model.FxRate, 0, len(doc.Rates))
+	for code, value := range doc.Rates {
+		quote, err := model.LookupCurrency(code)
+		if err != nil {
+			return nil, fmt.Errorf("parsing rates: %w", err)
+		}
+		rate := strings.TrimSpace(value.String())
+		if !decimalRate.MatchString(rate) || strings.Trim(rate, "0.") == "" {
+			return nil, fmt.Errorf("parsing rates: %s rate %q is not a positive decimal", quote.Code, rate)
+		}
+		if quote.Code == base.Code {
+			continue
+		}
+		rates = append(rates, model.FxRate{
+			Base:      base.Code,
+			Quote:     quote.Code,
+			Rate:      rate,
+			Source:    source,
+			FetchedAt: fetchedAt,
+		})
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("parsing rates: no rates in document")
+	}
+	return rates, nil
+}
@@ -0,0 +1,276 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var (
+	_ ports.IProviderPinger = (*ResilientProcessor)(nil)
+	_ ports.IProviderStats  = (*ResilientProcessor)(nil)
+	_ ports.IAmountLimiter  = (*ResilientProcessor)(nil)
+	_ ports.IRefunder       = (*resilientRefunder)(nil)
+	_ ports.IChargeFinder   = (*resilientFinder)(nil)
+	_ ports.IRefunder       = (*resilientRefundFinder)(nil)
+	_ ports.IChargeFinder   = (*resilientRefundFinder)(nil)
+)
+
+// ResilientProcessor wraps a processor with a timeout per try, retries with
+// jittered backoff for calls that are safe to repeat, and a circuit breaker
+// that fails calls fast while the provider is down
+type ResilientProcessor struct {
+	inner   ports.IPaymentProcessor
+	cfg     config.ResilienceConfig
+	breaker *CircuitBreaker
+
+	calls    atomic.Int64
+	failures atomic.Int64
+	retries  atomic.Int64
+	rejected atomic.Int64
+}
+
+// The optional capabilities are only exposed when inner has them, so the
+// service sees the same processor it would without the wrapper
+type resilientRefunder struct{ *ResilientProcessor }
+type resilientFinder struct{ *ResilientProcessor }
+type resilientRefundFinder struct{ *ResilientProcessor }
+
+func (p *resilientRefunder) Refund(ctx context.Context, req model.ProviderRefundRequest) (*model.ProviderRefundResponse, error) {
+	return p.refund(ctx, req)
+}
+
+func (p *resilientRefunder) Cancel(ctx context.Context, paymentIntentID string) (*model.PaymentProcessorResponse, error) {
+	return p.cancel(ctx, paymentIntentID)
+}
+
+func (p *resilientFinder) FindCharge(ctx context.Context, attemptID string) (*model.PaymentProcessorResponse, error) {
+	return p.findCharge(ctx, attemptID)
+}
+
+func (p *resilientRefundFinder) Refund(ctx context.Context, req model.ProviderRefundRequest) (*model.ProviderRefundResponse, error) {
+	return p.refund(ctx, req)
+}
+
+func (p *resilientRefundFinder) Cancel(ctx context.Context, paymentIntentID string) (*model.PaymentProcessorResponse, error) {
+	return p.cancel(ctx, paymentIntentID)
+}
+
+func (p *resilientRefundFinder) FindCharge(ctx context.Context, attemptID string) (*model.PaymentProcessorResponse, error) {
+	return p.findCharge(ctx, attemptID)
+}
+
+func NewResilientProcessor(inner ports.IPaymentProcessor, cfg config.ResilienceConfig) ports.IPaymentProcessor {
+	p := &ResilientProcessor{
+		inner:   inner,
+		cfg:     cfg,
+		breaker: NewCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}
+
+	_, refunds := inner.(ports.IRefunder)
+	_, finds := inner.(ports.IChargeFinder)
+	switch {
+	case refunds && finds:
+		return &resilientRefundFinder{p}
+	case refunds:
+		return &resilientRefunder{p}
+	case finds:
+		return &resilientFinder{p}
+	}
+	return p
+}
+
+func (p *ResilientProcessor) Name() model.PaymentProvider {
+	return p.inner.Name()
+}
+
+// Fails while the circuit is open, so readiness shows a provider the service
+// has stopped calling
+func (p *ResilientProcessor) Ping(ctx context.Context) error {
+	if state, _, openedAt := p.breaker.State(); state == model.CircuitOpen {
+		return fmt.Errorf("circuit open since %s", openedAt.UTC().Format(time.RFC3339))
+	}
+	if pinger, ok := p.inner.(ports.IProviderPinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (p *ResilientProcessor) AmountLimits(currency model.Currency) model.AmountLimits {
+	if limiter, ok := p.inner.(ports.IAmountLimiter); ok {
+		return limiter.AmountLimits(currency)
+	}
+	return model.AmountLimits{}
+}
+
+func (p *ResilientProcessor) Stats() model.ProviderStats {
+	state, failures, openedAt := p.breaker.State()
+	stats := model.ProviderStats{
+		Provider:            string(p.inner.Name()),
+		Circuit:             state,
+		ConsecutiveFailures: failures,
+		Calls:               p.calls.Load(),
+		Failures:            p.failures.Load(),
+		Retries:             p.retries.Load(),
+		Rejected:            p.rejected.Load(),
+	}
+	if state != model.CircuitClosed {
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
+
+// Retried only with a reference, the provider then returns the intent the
+// first try created
+func (p *ResilientProcessor) CreatePaymentIntent(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error) {
+	var res *model.PaymentProcessorResponse
+	err := p.call(ctx, p.cfg.TryTimeouts.CreateIntent, req.Reference != "", func(ctx context.Context) (err error) {
+		res, err = p.inner.CreatePaymentIntent(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+func (p *ResilientProcessor) GetPaymentIntent(ctx context.Context, id string) (*model.PaymentProcessorResponse, error) {
+	var res *model.PaymentProcessorResponse
+	err := p.call(ctx, p.cfg.TryTimeouts.GetIntent, true, func(ctx context.Context) (err error) {
+		res, err = p.inner.GetPaymentIntent(ctx, id)
+		return err
+	})
+	return res, err
+}
+
+// Retried only with an attempt id, which the provider uses as the
+// idempotency key so a repeat cannot charge twice
+func (p *ResilientProcessor) ChargeClient(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error) {
+	var res *model.PaymentProcessorResponse
+	err := p.call(ctx, p.cfg.TryTimeouts.Charge, req.AttemptID != "", func(ctx context.Context) (err error) {
+		res, err = p.inner.ChargeClient(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+func (p *ResilientProcessor) ParseWebhook(ctx context.Context, raw []byte, headers map[string][]string) (*model.PaymentEvent, error) {
+	return p.inner.ParseWebhook(ctx, raw, headers)
+}
+
+// Refunds carry no idempotency key and are never retried
+func (p *ResilientProcessor) refund(ctx context.Context, req model.ProviderRefundRequest) (*model.ProviderRefundResponse, error) {
+	var res *model.ProviderRefundResponse
+	err := p.call(ctx, p.cfg.TryTimeouts.Refund, false, func(ctx context.Context) (err error) {
+		res, err = p.inner.(ports.IRefunder).Refund(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+// A retried cancel that already went through fails as a second cancel, so
+// cancels share the refund timeout and are not retried either
+func (p *ResilientProcessor) cancel(ctx context.Context, paymentIntentID string) (*model.PaymentProcessorResponse, error) {
+	var res *model.PaymentProcessorResponse
+	err := p.call(ctx, p.cfg.TryTimeouts.Refund, false, func(ctx context.Context) (err error) {
+		res, err = p.inner.(ports.IRefunder).Cancel(ctx, paymentIntentID)
+		return err
+	})
+	return res, err
+}
+
+func (p *ResilientProcessor) findCharge(ctx context.Context, attemptID string) (*model.PaymentProcessorResponse, error) {
+	var res *model.PaymentProcessorResponse
+	err := p.call(ctx, p.cfg.TryTimeouts.FindCharge, true, func(ctx context.Context) (err error) {
+		res, err = p.inner.(ports.IChargeFinder).FindCharge(ctx, attemptID)
+		return err
+	})
+	return res, err
+}
+
+// Runs fn until it succeeds, fails for a reason a retry cannot fix, or the
+// retries run out. Once any try may have reached the provider the error stays
+// ambiguous, even if a later try failed cleanly.
+func (p *ResilientProcessor) call(ctx context.Context, tryTimeout time.Duration, retry bool, fn func(ctx context.Context) error) error {
+	p.calls.Add(1)
+
+	var (
+		err       error
+		ambiguous bool
+	)
+	for try := 0; ; try++ {
+		if !p.breaker.Allow() {
+			p.rejected.Add(1)
+			if err == nil {
+				return &ports.ProviderError{Class: ports.ErrorOutage, Err: fmt.Errorf("%s is unavailable, circuit open", p.inner.Name())}
+			}
+			break
+		}
+
+		tctx, cancel := context.WithTimeout(ctx, tryTimeout)
+		err = fn(tctx)
+		timedOut := errors.Is(tctx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		switch {
+		case err == nil || errors.Is(err, ports.ErrNotFound):
+			p.breaker.Success()
+			return err
+		case ctx.Err() != nil:
+			// The caller gave up, that says nothing about the provider
+			p.breaker.Release()
+			if ambiguous {
+				return &ports.ProviderError{Class: ports.ErrorAmbiguous, Err: err}
+			}
+			return err
+		case timedOut:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("%w: %w", err, context.DeadlineExceeded)
+			}
+			err = &ports.ProviderError{Class: ports.ErrorAmbiguous, Err: fmt.Errorf("try timed out after %s: %w", tryTimeout, err)}
+		}
+
+		class := ports.ClassifyError(err)
+		if class != ports.ErrorOutage && class != ports.ErrorAmbiguous {
+			// Declines and bad requests mean the provider is up
+			p.breaker.Success()
+			break
+		}
+		p.breaker.Failure()
+		p.failures.Add(1)
+		ambiguous = ambiguous || class == ports.ErrorAmbiguous
+
+		if !retry || try >= p.cfg.MaxRetries || !p.wait(ctx, try) {
+			break
+		}
+		p.retries.Add(1)
+	}
+
+	if ambiguous && ports.ClassifyError(err) != ports.ErrorAmbiguous {
+		return &ports.ProviderError{Class: ports.ErrorAmbiguous, Err: err}
+	}
+	return err
+}
+
+// Sleeps a random duration up to the exponential backoff for try, so callers
+// retrying together spread out. False when ctx ends first.
+func (p *ResilientProcessor) wait(ctx context.Context, try int) bool {
+	backoff := p.cfg.BaseBackoff << try
+	if backoff <= 0 || backoff > p.cfg.MaxBackoff {
+		backoff = p.cfg.MaxBackoff
+	}
+	backoff = time.Duration(rand.Int63n(int64(backoff))) + 1
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
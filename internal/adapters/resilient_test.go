@@ -0,0 +1,262 @@
+package adapters_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var (
+	errOutage  = &ports.ProviderError{Class: ports.ErrorOutage, Err: errors.New("503 from the provider")}
+	errDecline = &ports.ProviderError{Class: ports.ErrorDecline, Err: errors.New("card declined")}
+)
+
+// A step either returns its error right away or, when hang is set, blocks
+// until the try's context ends
+type step struct {
+	err  error
+	hang bool
+}
+
+// A processor answering each call with the next step of its script, success
+// once the script runs out
+type scriptedProcessor struct {
+	mu     sync.Mutex
+	script []step
+	calls  int
+}
+
+func (p *scriptedProcessor) next(ctx context.Context) error {
+	p.mu.Lock()
+	p.calls++
+	var s step
+	if len(p.script) > 0 {
+		s, p.script = p.script[0], p.script[1:]
+	}
+	p.mu.Unlock()
+
+	if s.hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.err
+}
+
+func (p *scriptedProcessor) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *scriptedProcessor) Name() model.PaymentProvider {
+	return "scripted"
+}
+
+func (p *scriptedProcessor) CreatePaymentIntent(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error) {
+	if err := p.next(ctx); err != nil {
+		return nil, err
+	}
+	return &model.PaymentProcessorResponse{ID: "pi_scripted", Status: "requires_payment_method"}, nil
+}
+
+func (p *scriptedProcessor) GetPaymentIntent(ctx context.Context, id string) (*model.PaymentProcessorResponse, error) {
+	if err := p.next(ctx); err != nil {
+		return nil, err
+	}
+	return &model.PaymentProcessorResponse{ID: id, Status: "succeeded"}, nil
+}
+
+func (p *scriptedProcessor) ChargeClient(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error) {
+	if err := p.next(ctx); err != nil {
+		return nil, err
+	}
+	return &model.PaymentProcessorResponse{ID: "pi_scripted", Status: "succeeded"}, nil
+}
+
+func (p *scriptedProcessor) ParseWebhook(ctx context.Context, raw []byte, headers map[string][]string) (*model.PaymentEvent, error) {
+	return nil, errors.New("not scripted")
+}
+
+func (p *scriptedProcessor) Refund(ctx context.Context, req model.ProviderRefundRequest) (*model.ProviderRefundResponse, error) {
+	if err := p.next(ctx); err != nil {
+		return nil, err
+	}
+	return &model.ProviderRefundResponse{ID: "re_scripted", Amount: req.Amount}, nil
+}
+
+func (p *scriptedProcessor) Cancel(ctx context.Context, paymentIntentID string) (*model.PaymentProcessorResponse, error) {
+	if err := p.next(ctx); err != nil {
+		return nil, err
+	}
+	return &model.PaymentProcessorResponse{ID: paymentIntentID, Status: "canceled"}, nil
+}
+
+func newResilient(inner *scriptedProcessor, threshold int, cooldown time.Duration) ports.IPaymentProcessor {
+	try := 20 * time.Millisecond
+	return adapters.NewResilientProcessor(inner, config.ResilienceConfig{
+		Enabled:          true,
+		MaxRetries:       2,
+		BaseBackoff:      time.Millisecond,
+		MaxBackoff:       2 * time.Millisecond,
+		BreakerThreshold: threshold,
+		BreakerCooldown:  cooldown,
+		TryTimeouts:      config.TryTimeouts{CreateIntent: try, GetIntent: try, Charge: try, Refund: try, FindCharge: try},
+	})
+}
+
+func TestResilientRetries(t *testing.T) {
+	ctx := context.Background()
+	charge := model.PaymentIntentRequest{Amount: 1000, Currency: "usd", Token: "tok"}
+	withAttempt := charge
+	withAttempt.AttemptID = "attempt_1"
+
+	tests := []struct {
+		name   string
+		call   func(p ports.IPaymentProcessor) error
+		script []step
+		calls  int
+		class  ports.ErrorClass
+	}{
+		{
+			name:   "charge with an attempt id is retried",
+			call:   func(p ports.IPaymentProcessor) error { _, err := p.ChargeClient(ctx, withAttempt); return err },
+			script: []step{{err: errOutage}, {err: errOutage}},
+			calls:  3,
+		},
+		{
+			name:   "charge with an attempt id gives up after the retries",
+			call:   func(p ports.IPaymentProcessor) error { _, err := p.ChargeClient(ctx, withAttempt); return err },
+			script: []step{{err: errOutage}, {err: errOutage}, {err: errOutage}},
+			calls:  3,
+			class:  ports.ErrorOutage,
+		},
+		{
+			name:   "charge without an attempt id is not retried",
+			call:   func(p ports.IPaymentProcessor) error { _, err := p.ChargeClient(ctx, charge); return err },
+			script: []step{{err: errOutage}},
+			calls:  1,
+			class:  ports.ErrorOutage,
+		},
+		{
+			name: "refund is not retried",
+			call: func(p ports.IPaymentProcessor) error {
+				_, err := p.(ports.IRefunder).Refund(ctx, model.ProviderRefundRequest{Amount: 1000})
+				return err
+			},
+			script: []step{{err: errOutage}},
+			calls:  1,
+			class:  ports.ErrorOutage,
+		},
+		{
+			name:   "cancel is not retried",
+			call:   func(p ports.IPaymentProcessor) error { _, err := p.(ports.IRefunder).Cancel(ctx, "pi_1"); return err },
+			script: []step{{err: errOutage}},
+			calls:  1,
+			class:  ports.ErrorOutage,
+		},
+		{
+			name:   "declines are not retried",
+			call:   func(p ports.IPaymentProcessor) error { _, err := p.ChargeClient(ctx, withAttempt); return err },
+			script: []step{{err: errDecline}},
+			calls:  1,
+			class:  ports.ErrorDecline,
+		},
+		{
+			name:   "a timed out try stays ambiguous",
+			call:   func(p ports.IPaymentProcessor) error { _, err := p.ChargeClient(ctx, withAttempt); return err },
+			script: []step{{hang: true}, {err: errOutage}, {err: errOutage}},
+			calls:  3,
+			class:  ports.ErrorAmbiguous,
+		},
+		{
+			name:   "a timed out charge without an attempt id is ambiguous",
+			call:   func(p ports.IPaymentProcessor) error { _, err := p.ChargeClient(ctx, charge); return err },
+			script: []step{{hang: true}},
+			calls:  1,
+			class:  ports.ErrorAmbiguous,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &scriptedProcessor{script: tt.script}
+			err := tt.call(newResilient(inner, 10, time.Hour))
+
+			if inner.Calls() != tt.calls {
+				t.Fatalf("expected %d calls to the provider, got %d", tt.calls, inner.Calls())
+			}
+			if tt.class == "" {
+				if err != nil {
+					t.Fatalf("expected success, got %v", err)
+				}
+				return
+			}
+			if class := ports.ClassifyError(err); err == nil || class != tt.class {
+				t.Fatalf("expected a %s error, got %v (%s)", tt.class, err, class)
+			}
+		})
+	}
+}
+
+func TestResilientFailsFastWhileOpen(t *testing.T) {
+	ctx := context.Background()
+	inner := &scriptedProcessor{script: []step{{err: errOutage}}}
+	p := newResilient(inner, 1, time.Hour)
+
+	// Not retried, so the open circuit has rejected nothing yet
+	if _, err := p.ChargeClient(ctx, model.PaymentIntentRequest{Amount: 1000, Currency: "usd"}); err == nil {
+		t.Fatal("expected the outage to fail the call")
+	}
+	_, err := p.GetPaymentIntent(ctx, "pi_1")
+	if ports.ClassifyError(err) != ports.ErrorOutage {
+		t.Fatalf("expected an outage while the circuit is open, got %v", err)
+	}
+	if inner.Calls() != 1 {
+		t.Fatalf("expected the open circuit to keep calls from the provider, got %d", inner.Calls())
+	}
+
+	stats := p.(ports.IProviderStats).Stats()
+	if stats.Circuit != model.CircuitOpen || stats.Rejected != 1 {
+		t.Fatalf("expected an open circuit with one rejected call, got %+v", stats)
+	}
+}
+
+func TestResilientReleasesTheProbeOnCallerCancel(t *testing.T) {
+	inner := &scriptedProcessor{script: []step{{err: errOutage}, {hang: true}}}
+	p := newResilient(inner, 1, time.Millisecond)
+
+	if _, err := p.ChargeClient(context.Background(), model.PaymentIntentRequest{Amount: 1000, Currency: "usd"}); err == nil {
+		t.Fatal("expected the outage to fail the call")
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// The probe's caller gives up before the try times out
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetPaymentIntent(ctx, "pi_1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the caller's deadline, got %v", err)
+	}
+
+	stats := p.(ports.IProviderStats).Stats()
+	if stats.Circuit != model.CircuitHalfOpen {
+		t.Fatalf("expected the circuit to stay half open, got %s", stats.Circuit)
+	}
+
+	// Without the release the next probe would be rejected for good
+	if _, err := p.GetPaymentIntent(context.Background(), "pi_1"); err != nil {
+		t.Fatalf("expected the next probe to go through, got %v", err)
+	}
+	if inner.Calls() != 3 {
+		t.Fatalf("expected 3 calls to the provider, got %d", inner.Calls())
+	}
+	if stats := p.(ports.IProviderStats).Stats(); stats.Circuit != model.CircuitClosed {
+		t.Fatalf("expected the successful probe to close the circuit, got %s", stats.Circuit)
+	}
+}
@@ -1,11 +1,17 @@
 package adapters
 
 import (
+    "fmt"
+    "time"
     "errors"
     "context"
+    "strconv"
+    "encoding/hex"
     "encoding/json"
     
     "github.com/stripe/stripe-go/v72"
+    "github.com/stripe/stripe-go/v72/refund"
+    "github.com/stripe/stripe-go/v72/balance"
     "github.com/stripe/stripe-go/v72/webhook"
     "github.com/stripe/stripe-go/v72/customer"
     "github.com/stripe/stripe-go/v72/paymentintent"
@@ -33,20 +39,41 @@ func (s *StripeAdapter) Name() model.PaymentProvider {
     return ppStripe
 }
 
+// Cheap authenticated call used by readiness checks
+func (s *StripeAdapter) Ping(ctx context.Context) error {
+    params := &stripe.BalanceParams{}
+    params.Context = ctx
+
+    if _, err := balance.Get(params); err != nil {
+        return errors.New("Stripe API unreachable #aping0")
+    }
+    return nil
+}
+
 func (s *StripeAdapter) CreatePaymentIntent(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error) {
     params := &stripe.PaymentIntentParams{
         Amount:   stripe.Int64(req.Amount),
         Currency: stripe.String(req.Currency),
     }
+    params.Context = ctx
     
     // Include the payment method in the metadata
 	if req.PaymentMethod != "" {
 		params.PaymentMethodTypes = []*string{stripe.String(req.PaymentMethod)}
 	}
-    
+
+    setStripeDetails(params, req)
+
+    // Lets webhooks find the transaction before the intent ID is stored, and
+    // makes a retried create return the first intent
+    if req.Reference != "" {
+        params.AddMetadata(ports.MetadataReference, req.Reference)
+        params.SetIdempotencyKey(req.Reference)
+    }
+
     pi, err := paymentintent.New(params)
     if err != nil {
-        return nil, errors.New("Payment creation failed #acpi0")
+        return nil, stripeError("Payment creation failed #acpi0", err)
     }
     
     return &model.PaymentProcessorResponse{
@@ -55,14 +82,18 @@ func (s *StripeAdapter) CreatePaymentIntent(ctx context.Context, req model.Payme
         Currency:     string(pi.Currency),
         Status:       string(pi.Status),
         ClientSecret: pi.ClientSecret,
+        Metadata:     callerMetadata(pi.Metadata),
     }, nil
 }
 
 func (s *StripeAdapter) GetPaymentIntent(ctx context.Context, id string) (*model.PaymentProcessorResponse, error) {
-    pi, err := paymentintent.Get(id, nil)
+    params := &stripe.PaymentIntentParams{}
+    params.Context = ctx
+
+    pi, err := paymentintent.Get(id, params)
 
     if err != nil {
-        return nil, errors.New("Collecting payment details failed #agpi0")
+        return nil, stripeError("Collecting payment details failed #agpi0", err)
     }
 
     return &model.PaymentProcessorResponse{
@@ -71,36 +102,53 @@ func (s *StripeAdapter) GetPaymentIntent(ctx context.Context, id string) (*model
         Currency:     string(pi.Currency),
         Status:       string(pi.Status),
         ClientSecret: pi.ClientSecret,
+        Metadata:     callerMetadata(pi.Metadata),
     }, nil
 }
 
 func (s *StripeAdapter) ChargeClient(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error) {
     // Check if PaymentMethod is already attached
-    pm, err := paymentmethod.Get(string(req.Token), nil)
+    if req.Token == "" {
+        return nil, &ports.ProviderError{Class: ports.ErrorValidation, Err: errors.New("Direct charge failed #acc0")}
+    }
+
+    pmParams := &stripe.PaymentMethodParams{}
+    pmParams.Context = ctx
+
+    pm, err := paymentmethod.Get(string(req.Token), pmParams)
     if err != nil {
-       return nil, errors.New("Direct charge failed #acc0")
+       return nil, stripeSetupError("#acc0", err)
     }
 
     //No customer attached. Attach new customer
     if pm.Customer == nil {
         // Create a Customer
         customerParams := &stripe.CustomerParams{}
+        customerParams.Context = ctx
+        // A retried attempt reuses the customer and attachment of the first try
+        if req.AttemptID != "" {
+            customerParams.SetIdempotencyKey(req.AttemptID + "-customer")
+        }
         customer, err := customer.New(customerParams)
         if err != nil {
-            return nil, errors.New("Direct charge failed #acc1")
+            return nil, stripeSetupError("#acc1", err)
         }
 
         // Attach the PaymentMethod to the Customer
         attachParams := &stripe.PaymentMethodAttachParams{
             Customer: stripe.String(customer.ID),
         }
+        attachParams.Context = ctx
+        if req.AttemptID != "" {
+            attachParams.SetIdempotencyKey(req.AttemptID + "-attach")
+        }
 
         _, err = paymentmethod.Attach(string(req.Token), attachParams) // or "pm_123"
         if err != nil {
-            return nil, errors.New("Direct charge failed #acc2")
+            return nil, stripeSetupError("#acc2", err)
         }
 
-        pm.Customer.ID = customer.ID
+        pm.Customer = customer
     }
 
     // Use it in a PaymentIntent
@@ -111,10 +159,24 @@ func (s *StripeAdapter) ChargeClient(ctx context.Context, req model.PaymentInten
         PaymentMethod: stripe.String(req.Token),
         Confirm:  stripe.Bool(true),
     }
+    params.Context = ctx
+    setStripeDetails(params, req)
+
+    // Retries of the same attempt reuse the charge, and recovery can search for it
+    if req.AttemptID != "" {
+        params.SetIdempotencyKey(req.AttemptID)
+        params.AddMetadata(ports.MetadataAttemptID, req.AttemptID)
+    }
 
     pi, err := paymentintent.New(params)
     if err != nil {
-        return nil, errors.New("Direct charge failed #acc3")
+        return nil, stripeChargeError("#acc3", err)
+    }
+
+    // Confirmed intents echo the method back, fall back to the token we charged
+    paymentMethodID := req.Token
+    if pi.PaymentMethod != nil {
+        paymentMethodID = pi.PaymentMethod.ID
     }
 
     return &model.PaymentProcessorResponse{
@@ -122,10 +184,109 @@ func (s *StripeAdapter) ChargeClient(ctx context.Context, req model.PaymentInten
         Amount:       pi.Amount,
         Currency:     string(pi.Currency),
         Status:       string(pi.Status),
-        PaymentMethodID: pi.PaymentMethod.ID,
+        PaymentMethodID: paymentMethodID,
+        Metadata:     callerMetadata(pi.Metadata),
     }, nil
 }
 
+// Caller details shared by intents and direct charges
+func setStripeDetails(params *stripe.PaymentIntentParams, req model.PaymentIntentRequest) {
+    for key, value := range req.Metadata {
+        params.AddMetadata(key, value)
+    }
+    if req.Description != "" {
+        params.Description = stripe.String(req.Description)
+    }
+    if req.ReceiptEmail != "" {
+        params.ReceiptEmail = stripe.String(req.ReceiptEmail)
+    }
+    if req.StatementDescriptorSuffix != "" {
+        params.StatementDescriptorSuffix = stripe.String(req.StatementDescriptorSuffix)
+    }
+}
+
+// Search is eventually consistent, so only look up attempts older than a minute or so
+func (s *StripeAdapter) FindCharge(ctx context.Context, attemptID string) (*model.PaymentProcessorResponse, error) {
+    params := &stripe.PaymentIntentSearchParams{}
+    params.Context = ctx
+    params.Query = fmt.Sprintf("metadata['%s']:'%s'", ports.MetadataAttemptID, attemptID)
+    params.Single = true
+
+    iter := paymentintent.Search(params)
+    if !iter.Next() {
+        if err := iter.Err(); err != nil {
+            return nil, stripeError("Finding charge failed #afc0", err)
+        }
+        return nil, fmt.Errorf("Finding charge failed #afc1: %w", ports.ErrNotFound)
+    }
+
+    pi := iter.PaymentIntent()
+    res := &model.PaymentProcessorResponse{
+        ID:           pi.ID,
+        Amount:       pi.Amount,
+        Currency:     string(pi.Currency),
+        Status:       string(pi.Status),
+        Metadata:     callerMetadata(pi.Metadata),
+    }
+    if pi.PaymentMethod != nil {
+        res.PaymentMethodID = pi.PaymentMethod.ID
+    }
+    return res, nil
+}
+
+func (s *StripeAdapter) Refund(ctx context.Context, req model.ProviderRefundRequest) (*model.ProviderRefundResponse, error) {
+    params := &stripe.RefundParams{
+        PaymentIntent: stripe.String(req.PaymentIntentID),
+    }
+    params.Context = ctx
+
+    // Omitted amount refunds whatever is left on the intent
+    if req.Amount > 0 {
+        params.Amount = stripe.Int64(req.Amount)
+    }
+
+    // Stripe only accepts a fixed set of reasons, keep ours as metadata
+    if req.Reason != "" {
+        params.AddMetadata("reason", req.Reason)
+    }
+
+    rf, err := refund.New(params)
+    if err != nil {
+        return nil, stripeError("Refund failed #arf0", err)
+    }
+
+    return &model.ProviderRefundResponse{
+        ID:     rf.ID,
+        Amount: rf.Amount,
+        Status: string(rf.Status),
+    }, nil
+}
+
+func (s *StripeAdapter) Cancel(ctx context.Context, paymentIntentID string) (*model.PaymentProcessorResponse, error) {
+    params := &stripe.PaymentIntentCancelParams{}
+    params.Context = ctx
+
+    pi, err := paymentintent.Cancel(paymentIntentID, params)
+    if err != nil {
+        return nil, stripeError("Cancel failed #acn0", err)
+    }
+
+    return &model.PaymentProcessorResponse{
+        ID:           pi.ID,
+        Amount:       pi.Amount,
+        Currency:     string(pi.Currency),
+        Status:       string(pi.Status),
+        Metadata:     callerMetadata(pi.Metadata),
+    }, nil
+}
+
+// Same scheme as Stripe-Signature, with the configured endpoint secret
+func (s *StripeAdapter) SignWebhook(raw []byte) map[string][]string {
+    now := time.Now()
+    signature := hex.EncodeToString(webhook.ComputeSignature(now, raw, s.webhookSecret))
+    return map[string][]string{"Stripe-Signature": {"t=" + strconv.FormatInt(now.Unix(), 10) + ",v1=" + signature}}
+}
+
 func (s *StripeAdapter) ParseWebhook(ctx context.Context, raw []byte, headers map[string][]string) (*model.PaymentEvent, error) {
 	// Extract Stripe-Signature header
 	sigHeader := utils.GetHeader(headers, "Stripe-Signature")
@@ -167,11 +328,14 @@ func (s *StripeAdapter) ParseWebhook(ctx context.Context, raw []byte, headers ma
 			Type:          eventType,
 			PaymentIntent: paymentIntent.ID,
 			PaymentMethod: paymentMethodID, // Include Payment Method ID
+			Reference:     paymentIntent.Metadata[ports.MetadataReference],
+			Metadata:      callerMetadata(paymentIntent.Metadata),
 			Payload: model.PaymentIntentResponse{
 				ID:           paymentIntent.ID,
 				Amount:       paymentIntent.Amount,
 				Currency:     string(paymentIntent.Currency),
 				Status:       string(paymentIntent.Status),
+				Metadata:     callerMetadata(paymentIntent.Metadata),
 			},
 		}, nil
 
@@ -0,0 +1,52 @@
+package adapters_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stripe/stripe-go/v72"
+	"github.com/stripe/stripe-go/v72/paymentmethod"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters/adaptertest"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+const (
+	stripeTestKey     = "sk_test_adapters_4eC39HqLyjWDarjtT1zdp7dc"
+	stripeTestWebhook = "whsec_adapters_5f1c0b2a"
+)
+
+func TestStripeAdapter(t *testing.T) {
+	adaptertest.Processor(t, adaptertest.Stripe())
+}
+
+// A retried attempt must not leave a second customer behind
+func TestStripeChargeReusesTheAttemptCustomer(t *testing.T) {
+	srv := adaptertest.NewStripeServer(t, stripeTestKey, stripeTestWebhook)
+	srv.Install(t)
+	adapter := adapters.NewStripeAdapter(stripeTestKey, stripeTestWebhook)
+
+	customers := map[string]bool{}
+	for _, token := range []string{"pm_card_visa", "pm_card_mastercard"} {
+		_, err := adapter.ChargeClient(context.Background(), model.PaymentIntentRequest{
+			Amount: 1000, Currency: "eur", CustomerID: "cus_test", Token: token, AttemptID: "attempt_1",
+		})
+		if err != nil {
+			t.Fatalf("ChargeClient with %s: %v", token, err)
+		}
+
+		pm, err := paymentmethod.Get(token, &stripe.PaymentMethodParams{})
+		if err != nil {
+			t.Fatalf("Get %s: %v", token, err)
+		}
+		if pm.Customer == nil {
+			t.Fatalf("expected %s to be attached", token)
+		}
+		customers[pm.Customer.ID] = true
+	}
+
+	if len(customers) != 1 {
+		t.Fatalf("expected both tries of the attempt to use one customer, got %v", customers)
+	}
+}
@@ -0,0 +1,78 @@
+package adapters
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/stripe/stripe-go/v72"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// Classifies a Stripe failure. Stripe does not process requests it answers
+// with 429 or 503, other 5xx responses and broken connections leave the
+// outcome unknown.
+func stripeErrorClass(err error) ports.ErrorClass {
+	var serr *stripe.Error
+	if errors.As(err, &serr) {
+		switch {
+		case serr.Type == stripe.ErrorTypeCard:
+			return ports.ErrorDecline
+		case serr.HTTPStatusCode == http.StatusTooManyRequests || serr.HTTPStatusCode == http.StatusServiceUnavailable:
+			return ports.ErrorOutage
+		// A bad or revoked key makes Stripe unusable for every payment
+		case serr.Type == stripe.ErrorTypeAuthentication || serr.Type == stripe.ErrorTypePermission:
+			return ports.ErrorOutage
+		case serr.HTTPStatusCode >= http.StatusInternalServerError:
+			return ports.ErrorAmbiguous
+		case serr.HTTPStatusCode >= http.StatusBadRequest:
+			return ports.ErrorValidation
+		}
+		return ports.ErrorAmbiguous
+	}
+
+	// Nothing was sent when the connection could not be opened
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ports.ErrorOutage
+	}
+	return ports.ErrorAmbiguous
+}
+
+// Keeps Stripe's details out of the message, they stay available as the cause
+func stripeError(message string, err error) error {
+	perr := &ports.ProviderError{Class: stripeErrorClass(err), Err: errors.New(message), Cause: err}
+	if perr.Class == ports.ErrorDecline {
+		perr.DeclineCode = stripeDeclineCode(err)
+	}
+	return perr
+}
+
+// Stripe sets decline_code for issuer declines only, other card errors such
+// as expired_card carry the reason in code
+func stripeDeclineCode(err error) string {
+	var serr *stripe.Error
+	if !errors.As(err, &serr) {
+		return ""
+	}
+	if serr.DeclineCode != "" {
+		return string(serr.DeclineCode)
+	}
+	return string(serr.Code)
+}
+
+// Failure of the charge itself
+func stripeChargeError(code string, err error) error {
+	return stripeError("Direct charge failed "+code, err)
+}
+
+// Failure before the charge was sent. Nothing can have been charged, so an
+// unknown outcome is as safe to fail over as an outage.
+func stripeSetupError(code string, err error) error {
+	class := stripeErrorClass(err)
+	if class == ports.ErrorAmbiguous {
+		class = ports.ErrorOutage
+	}
+	return &ports.ProviderError{Class: class, Err: errors.New("Direct charge failed " + code), Cause: err}
+}
@@ -0,0 +1,36 @@
+package adapters
+
+import (
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// Stripe's minimum charge per currency, in minor units. Other currencies are
+// converted and only checked by Stripe.
+var stripeMinimums = map[string]int64{
+	"usd": 50, "aed": 200, "aud": 50, "bgn": 100, "brl": 50, "cad": 50,
+	"chf": 50, "czk": 1500, "dkk": 250, "eur": 50, "gbp": 30, "hkd": 400,
+	"huf": 17500, "inr": 50, "jpy": 50, "mxn": 1000, "myr": 200, "nok": 300,
+	"nzd": 50, "pln": 200, "ron": 200, "sek": 300, "sgd": 50, "thb": 1000,
+}
+
+// Amounts take at most eight digits, IDR twelve
+const (
+	stripeMaxAmount    int64 = 99999999
+	stripeMaxAmountIDR int64 = 999999999999
+)
+
+func stripeAmountLimits(currency model.Currency) model.AmountLimits {
+	limits := model.AmountLimits{Min: stripeMinimums[currency.Code], Max: stripeMaxAmount}
+	if currency.Code == "idr" {
+		limits.Max = stripeMaxAmountIDR
+	}
+	// Three-decimal amounts must end in zero
+	if currency.Exponent == 3 {
+		limits.Step = 10
+	}
+	return limits
+}
+
+func (s *StripeAdapter) AmountLimits(currency model.Currency) model.AmountLimits {
+	return stripeAmountLimits(currency)
+}
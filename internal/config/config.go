@@ -1,49 +1,682 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
-	
+	"strings"
+	"time"
+
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+// Secret hides credentials when the config is logged
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
 type Config struct {
-	StripeSecretKey      string
-	StripeWebhookSecret  string
-	PayPalClientID       string
-	PayPalSecret         string
-	DbUser				 string
-	DbPassword			 string
-	DbHost				 string
-	DbName				 string
-	SSLMode				 string
-	DbPort				 string
-	Port                int
-}
-
-func Load() (*Config, error) {
+	Port       int              `yaml:"port"`
+	Database   DatabaseConfig   `yaml:"database"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	Providers  ProvidersConfig  `yaml:"providers"`
+	Timeouts   TimeoutsConfig   `yaml:"timeouts"`
+	Resilience ResilienceConfig `yaml:"resilience"`
+	Workers    WorkersConfig    `yaml:"workers"`
+	FX         FXConfig         `yaml:"fx"`
+	Routing    RoutingConfig    `yaml:"routing"`
+	Health     HealthConfig     `yaml:"health"`
+	Migrations MigrationsConfig `yaml:"migrations"`
+}
+
+type DatabaseConfig struct {
+	User              string        `yaml:"user"`
+	Password          Secret        `yaml:"password"`
+	Host              string        `yaml:"host"`
+	Port              int           `yaml:"port"`
+	Name              string        `yaml:"name"`
+	SSLMode           string        `yaml:"ssl_mode"`
+	ConnectTimeout    time.Duration `yaml:"connect_timeout"`
+	MaxConns          int32         `yaml:"max_conns"`
+	MinConns          int32         `yaml:"min_conns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+}
+
+type HTTPConfig struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+	MaxBodyBytes      int64         `yaml:"max_body_bytes"`
+	DrainDelay        time.Duration `yaml:"drain_delay"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	// How long the platform waits after SIGTERM before killing the task
+	// (stopTimeout in the ECS task definition)
+	StopTimeout time.Duration `yaml:"stop_timeout"`
+	// How long a /v2 response is replayed for a repeated Idempotency-Key
+	IdempotencyKeyTTL time.Duration `yaml:"idempotency_key_ttl"`
+}
+
+type ProvidersConfig struct {
+	Stripe StripeConfig `yaml:"stripe"`
+	PayPal PayPalConfig `yaml:"paypal"`
+	Fake   FakeConfig   `yaml:"fake"`
+}
+
+type StripeConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	SecretKey     Secret `yaml:"secret_key"`
+	WebhookSecret Secret `yaml:"webhook_secret"`
+}
+
+type PayPalConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	ClientID string `yaml:"client_id"`
+	Secret   Secret `yaml:"secret"`
+}
+
+// Scriptable provider for local development, never enable in production
+type FakeConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	WebhookSecret Secret `yaml:"webhook_secret"`
+}
+
+// Per-request deadlines applied by the controllers and around provider calls
+type TimeoutsConfig struct {
+	Request  time.Duration `yaml:"request"`
+	Charge   time.Duration `yaml:"charge"`
+	Webhook  time.Duration `yaml:"webhook"`
+	Provider time.Duration `yaml:"provider"`
+	// Caps a whole split tender, which must answer before HTTP_WRITE_TIMEOUT
+	SplitTender time.Duration `yaml:"split_tender"`
+}
+
+// Retries and a circuit breaker around every provider call. Provider in
+// TimeoutsConfig bounds a call with all of its retries.
+type ResilienceConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Only reads and creates carrying an idempotency key are retried
+	MaxRetries  int           `yaml:"max_retries"`
+	BaseBackoff time.Duration `yaml:"base_backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	// Consecutive outages or timeouts that open a provider's circuit, and how
+	// long it stays open before one call is let through to probe it
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+	TryTimeouts      TryTimeouts   `yaml:"try_timeouts"`
+}
+
+// Limit on each try of a provider operation
+type TryTimeouts struct {
+	CreateIntent time.Duration `yaml:"create_intent"`
+	GetIntent    time.Duration `yaml:"get_intent"`
+	Charge       time.Duration `yaml:"charge"`
+	Refund       time.Duration `yaml:"refund"`
+	FindCharge   time.Duration `yaml:"find_charge"`
+}
+
+// Intervals for background workers started alongside the HTTP server
+type WorkersConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	RecoveryInterval time.Duration `yaml:"recovery_interval"`
+	// How old an unfinished charge attempt must be before recovery touches it
+	RecoveryGrace time.Duration `yaml:"recovery_grace"`
+	// What to do with charges that succeeded but were never recorded: record or refund
+	RecoveryPolicy string `yaml:"recovery_policy"`
+	// How often idempotency keys past their TTL are deleted
+	IdempotencyPruneInterval time.Duration `yaml:"idempotency_prune_interval"`
+}
+
+// Quotes for customers paying in another currency than the one we settle in
+type FXConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	SettlementCurrency string `yaml:"settlement_currency"`
+	// Where rates come from: file or http
+	Source string `yaml:"source"`
+	// Path of the rates file or URL of the rates feed
+	SourceLocation  string        `yaml:"source_location"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	QuoteTTL        time.Duration `yaml:"quote_ttl"`
+	// Rates older than this are not quoted
+	MaxRateAge time.Duration `yaml:"max_rate_age"`
+}
+
+// Picks the provider for POST /payments/intent. Rules are tried in order and
+// the first match wins; Default is used when none matches.
+type RoutingConfig struct {
+	Default string        `yaml:"default"`
+	Rules   []RoutingRule `yaml:"rules"`
+	// Providers that can charge each other's payment method tokens. A direct
+	// charge hitting an outage at one of them is retried at the next, in order.
+	Failover []string `yaml:"failover"`
+}
+
+// Empty conditions match anything
+type RoutingRule struct {
+	Name           string   `yaml:"name"`
+	Provider       string   `yaml:"provider"`
+	Currencies     []string `yaml:"currencies"`
+	MinAmount      int64    `yaml:"min_amount"`
+	MaxAmount      int64    `yaml:"max_amount"`
+	Countries      []string `yaml:"countries"`
+	PaymentMethods []string `yaml:"payment_methods"`
+	Merchants      []string `yaml:"merchants"`
+	// Share of the matching customers, 1 to 100, taken by this rule; the rest
+	// fall through to the next one. 0 means no split and takes them all, so
+	// a rule cannot be drained by setting 0: remove it from the list instead.
+	Percent int `yaml:"percent"`
+}
+
+type HealthConfig struct {
+	CheckProviders bool          `yaml:"check_providers"`
+	ProviderTTL    time.Duration `yaml:"provider_ttl"`
+	ProbeTimeout   time.Duration `yaml:"probe_timeout"`
+}
+
+type MigrationsConfig struct {
+	// Apply pending migrations before serving (guarded by an advisory lock)
+	AutoMigrate bool `yaml:"auto_migrate"`
+	// Refuse to serve when the schema is behind the embedded migrations
+	RequireCurrent bool `yaml:"require_current"`
+}
+
+func Default() *Config {
+	return &Config{
+		Port: 8080,
+		Database: DatabaseConfig{
+			Port:              5432,
+			SSLMode:           "disable",
+			ConnectTimeout:    180 * time.Second,
+			MaxConns:          25,
+			MinConns:          2,
+			MaxConnLifetime:   1 * time.Hour,
+			MaxConnIdleTime:   30 * time.Minute,
+			HealthCheckPeriod: 1 * time.Minute,
+		},
+		HTTP: HTTPConfig{
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      1 << 20,
+			DrainDelay:        10 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+			StopTimeout:       30 * time.Second,
+			IdempotencyKeyTTL: 24 * time.Hour,
+		},
+		Providers: ProvidersConfig{
+			Stripe: StripeConfig{Enabled: true},
+			Fake:   FakeConfig{WebhookSecret: "whsec_fake"},
+		},
+		Timeouts: TimeoutsConfig{
+			Request:  5 * time.Second,
+			Charge:   10 * time.Second,
+			Webhook:  5 * time.Second,
+			Provider: 8 * time.Second,
+
+			SplitTender: 25 * time.Second,
+		},
+		Resilience: ResilienceConfig{
+			Enabled:          true,
+			MaxRetries:       2,
+			BaseBackoff:      100 * time.Millisecond,
+			MaxBackoff:       1 * time.Second,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+			TryTimeouts: TryTimeouts{
+				CreateIntent: 3 * time.Second,
+				GetIntent:    2 * time.Second,
+				Charge:       4 * time.Second,
+				Refund:       4 * time.Second,
+				FindCharge:   3 * time.Second,
+			},
+		},
+		Workers: WorkersConfig{
+			Enabled:          true,
+			RecoveryInterval: 1 * time.Minute,
+			RecoveryGrace:    5 * time.Minute,
+			RecoveryPolicy:   "refund",
+
+			IdempotencyPruneInterval: 1 * time.Hour,
+		},
+		FX: FXConfig{
+			SettlementCurrency: "eur",
+			Source:             "file",
+			RefreshInterval:    1 * time.Hour,
+			QuoteTTL:           10 * time.Minute,
+			MaxRateAge:         26 * time.Hour,
+		},
+		Health: HealthConfig{
+			ProviderTTL:  30 * time.Second,
+			ProbeTimeout: 3 * time.Second,
+		},
+	}
+}
+
+// Load builds the config from defaults, an optional YAML file, the environment
+// and *_FILE secret files, in increasing order of precedence. path may be a
+// file or a directory holding config.yaml; when empty CONFIG_FILE is used.
+func Load(path string) (*Config, error) {
 	// Load .env file (only in development)
-    if err := godotenv.Load(); err != nil {
-        log.Println("No .env file found, using system environment variables")
-    }
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := Default()
 
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
-	if port == 0 {
-		port = 8080
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if err := cfg.loadFile(path); err != nil {
+		return nil, err
 	}
 
-	return &Config{
-		StripeSecretKey:      os.Getenv("STRIPE_SECRET_KEY"),
-		StripeWebhookSecret:  os.Getenv("STRIPE_WEBHOOK_SECRET"),
-		PayPalClientID:       os.Getenv("PAYPAL_CLIENT_ID"),
-		PayPalSecret:         os.Getenv("PAYPAL_SECRET"),
-		DbUser:				  os.Getenv("DB_USER"),
-		DbPassword:			  os.Getenv("DB_PASSWORD"),
-		DbHost:				  os.Getenv("DB_HOST"),
-		DbName:				  os.Getenv("DB_NAME"),
-		DbPort:				  os.Getenv("DB_PORT"),
-		SSLMode:			  os.Getenv("SSL_MODE"),
-		Port:                 port,
-	}, nil
-}
\ No newline at end of file
+	// Parse and validation errors are reported together
+	errs := append(cfg.loadEnv(), cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return cfg, nil
+}
+
+// LoadDatabase loads like Load but only reads and validates the database
+// settings, for commands such as migrate that never reach the providers.
+func LoadDatabase(path string) (*DatabaseConfig, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if err := cfg.loadFile(path); err != nil {
+		return nil, err
+	}
+
+	env := &envReader{}
+	cfg.Database.loadEnv(env)
+	errs := append(env.errs, cfg.Database.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return &cfg.Database, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, "config.yaml")
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() []error {
+	env := &envReader{}
+
+	env.int("PORT", &c.Port)
+
+	c.Database.loadEnv(env)
+
+	env.duration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	env.duration("HTTP_READ_HEADER_TIMEOUT", &c.HTTP.ReadHeaderTimeout)
+	env.duration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	env.duration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	env.int("HTTP_MAX_HEADER_BYTES", &c.HTTP.MaxHeaderBytes)
+	env.int64("HTTP_MAX_BODY_BYTES", &c.HTTP.MaxBodyBytes)
+	env.duration("SHUTDOWN_DRAIN_DELAY", &c.HTTP.DrainDelay)
+	env.duration("SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+	env.duration("STOP_TIMEOUT", &c.HTTP.StopTimeout)
+	env.duration("IDEMPOTENCY_KEY_TTL", &c.HTTP.IdempotencyKeyTTL)
+
+	env.bool("STRIPE_ENABLED", &c.Providers.Stripe.Enabled)
+	env.secret("STRIPE_SECRET_KEY", &c.Providers.Stripe.SecretKey)
+	env.secret("STRIPE_WEBHOOK_SECRET", &c.Providers.Stripe.WebhookSecret)
+	env.bool("PAYPAL_ENABLED", &c.Providers.PayPal.Enabled)
+	env.string("PAYPAL_CLIENT_ID", &c.Providers.PayPal.ClientID)
+	env.secret("PAYPAL_SECRET", &c.Providers.PayPal.Secret)
+	env.bool("FAKE_PROVIDER_ENABLED", &c.Providers.Fake.Enabled)
+	env.secret("FAKE_WEBHOOK_SECRET", &c.Providers.Fake.WebhookSecret)
+
+	env.duration("REQUEST_TIMEOUT", &c.Timeouts.Request)
+	env.duration("CHARGE_TIMEOUT", &c.Timeouts.Charge)
+	env.duration("WEBHOOK_TIMEOUT", &c.Timeouts.Webhook)
+	env.duration("PROVIDER_TIMEOUT", &c.Timeouts.Provider)
+	env.duration("SPLIT_TENDER_TIMEOUT", &c.Timeouts.SplitTender)
+
+	env.bool("RESILIENCE_ENABLED", &c.Resilience.Enabled)
+	env.int("PROVIDER_MAX_RETRIES", &c.Resilience.MaxRetries)
+	env.duration("PROVIDER_BASE_BACKOFF", &c.Resilience.BaseBackoff)
+	env.duration("PROVIDER_MAX_BACKOFF", &c.Resilience.MaxBackoff)
+	env.int("BREAKER_THRESHOLD", &c.Resilience.BreakerThreshold)
+	env.duration("BREAKER_COOLDOWN", &c.Resilience.BreakerCooldown)
+	env.duration("CREATE_INTENT_TRY_TIMEOUT", &c.Resilience.TryTimeouts.CreateIntent)
+	env.duration("GET_INTENT_TRY_TIMEOUT", &c.Resilience.TryTimeouts.GetIntent)
+	env.duration("CHARGE_TRY_TIMEOUT", &c.Resilience.TryTimeouts.Charge)
+	env.duration("REFUND_TRY_TIMEOUT", &c.Resilience.TryTimeouts.Refund)
+	env.duration("FIND_CHARGE_TRY_TIMEOUT", &c.Resilience.TryTimeouts.FindCharge)
+
+	env.bool("WORKERS_ENABLED", &c.Workers.Enabled)
+	env.duration("RECOVERY_INTERVAL", &c.Workers.RecoveryInterval)
+	env.duration("RECOVERY_GRACE", &c.Workers.RecoveryGrace)
+	env.string("RECOVERY_POLICY", &c.Workers.RecoveryPolicy)
+	env.duration("IDEMPOTENCY_PRUNE_INTERVAL", &c.Workers.IdempotencyPruneInterval)
+
+	env.bool("FX_ENABLED", &c.FX.Enabled)
+	env.string("FX_SETTLEMENT_CURRENCY", &c.FX.SettlementCurrency)
+	env.string("FX_SOURCE", &c.FX.Source)
+	env.string("FX_SOURCE_LOCATION", &c.FX.SourceLocation)
+	env.duration("FX_REFRESH_INTERVAL", &c.FX.RefreshInterval)
+	env.duration("FX_QUOTE_TTL", &c.FX.QuoteTTL)
+	env.duration("FX_MAX_RATE_AGE", &c.FX.MaxRateAge)
+	env.string("ROUTING_DEFAULT", &c.Routing.Default)
+
+	env.bool("MIGRATE_ON_START", &c.Migrations.AutoMigrate)
+	env.bool("MIGRATIONS_REQUIRE_CURRENT", &c.Migrations.RequireCurrent)
+
+	env.bool("READY_CHECK_PROVIDERS", &c.Health.CheckProviders)
+	env.duration("READY_PROVIDER_TTL", &c.Health.ProviderTTL)
+	env.duration("READY_PROBE_TIMEOUT", &c.Health.ProbeTimeout)
+
+	return env.errs
+}
+
+// Validate reports every problem at once instead of failing at first use
+func (c *Config) Validate() error {
+	if errs := c.validate(); len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+	require := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	require(c.Port > 0 && c.Port < 65536, "PORT must be a valid TCP port, got %d", c.Port)
+
+	errs = append(errs, c.Database.validate()...)
+
+	if c.Providers.Stripe.Enabled {
+		require(c.Providers.Stripe.SecretKey != "", "STRIPE_SECRET_KEY is required when stripe is enabled")
+		require(c.Providers.Stripe.WebhookSecret != "", "STRIPE_WEBHOOK_SECRET is required when stripe is enabled")
+	}
+	if c.Providers.PayPal.Enabled {
+		require(c.Providers.PayPal.ClientID != "", "PAYPAL_CLIENT_ID is required when paypal is enabled")
+		require(c.Providers.PayPal.Secret != "", "PAYPAL_SECRET is required when paypal is enabled")
+	}
+	if c.Providers.Fake.Enabled {
+		require(c.Providers.Fake.WebhookSecret != "", "FAKE_WEBHOOK_SECRET is required when the fake provider is enabled")
+	}
+	require(c.Providers.Stripe.Enabled || c.Providers.PayPal.Enabled || c.Providers.Fake.Enabled,
+		"at least one payment provider must be enabled")
+
+	durations := map[string]time.Duration{
+		"HTTP_READ_TIMEOUT":        c.HTTP.ReadTimeout,
+		"HTTP_READ_HEADER_TIMEOUT": c.HTTP.ReadHeaderTimeout,
+		"HTTP_WRITE_TIMEOUT":       c.HTTP.WriteTimeout,
+		"SHUTDOWN_TIMEOUT":         c.HTTP.ShutdownTimeout,
+		"STOP_TIMEOUT":             c.HTTP.StopTimeout,
+		"IDEMPOTENCY_KEY_TTL":      c.HTTP.IdempotencyKeyTTL,
+		"REQUEST_TIMEOUT":          c.Timeouts.Request,
+		"CHARGE_TIMEOUT":           c.Timeouts.Charge,
+		"WEBHOOK_TIMEOUT":          c.Timeouts.Webhook,
+		"PROVIDER_TIMEOUT":         c.Timeouts.Provider,
+		"SPLIT_TENDER_TIMEOUT":     c.Timeouts.SplitTender,
+	}
+	for _, key := range sortedKeys(durations) {
+		require(durations[key] > 0, "%s must be positive", key)
+	}
+	// A deadline past the write timeout answers into a closed connection
+	require(c.Timeouts.SplitTender < c.HTTP.WriteTimeout,
+		"SPLIT_TENDER_TIMEOUT must be shorter than HTTP_WRITE_TIMEOUT (%s)", c.HTTP.WriteTimeout)
+	require(c.HTTP.DrainDelay >= 0, "SHUTDOWN_DRAIN_DELAY must not be negative")
+	// Killed mid-shutdown, a worker can be left between a provider call and its write
+	require(c.HTTP.DrainDelay+c.HTTP.ShutdownTimeout < c.HTTP.StopTimeout,
+		"SHUTDOWN_DRAIN_DELAY plus SHUTDOWN_TIMEOUT must be shorter than STOP_TIMEOUT (%s)", c.HTTP.StopTimeout)
+	if c.Resilience.Enabled {
+		require(c.Resilience.MaxRetries >= 0, "PROVIDER_MAX_RETRIES must not be negative")
+		require(c.Resilience.BaseBackoff > 0 && c.Resilience.BaseBackoff <= c.Resilience.MaxBackoff,
+			"PROVIDER_BASE_BACKOFF must be positive and at most PROVIDER_MAX_BACKOFF (%s)", c.Resilience.MaxBackoff)
+		require(c.Resilience.BreakerThreshold > 0, "BREAKER_THRESHOLD must be positive")
+		require(c.Resilience.BreakerCooldown > 0, "BREAKER_COOLDOWN must be positive")
+		tries := map[string]time.Duration{
+			"CREATE_INTENT_TRY_TIMEOUT": c.Resilience.TryTimeouts.CreateIntent,
+			"GET_INTENT_TRY_TIMEOUT":    c.Resilience.TryTimeouts.GetIntent,
+			"CHARGE_TRY_TIMEOUT":        c.Resilience.TryTimeouts.Charge,
+			"REFUND_TRY_TIMEOUT":        c.Resilience.TryTimeouts.Refund,
+			"FIND_CHARGE_TRY_TIMEOUT":   c.Resilience.TryTimeouts.FindCharge,
+		}
+		for _, key := range sortedKeys(tries) {
+			require(tries[key] > 0 && tries[key] <= c.Timeouts.Provider,
+				"%s must be positive and at most PROVIDER_TIMEOUT (%s)", key, c.Timeouts.Provider)
+		}
+	}
+	if c.Workers.Enabled {
+		require(c.Workers.RecoveryInterval > 0, "RECOVERY_INTERVAL must be positive when workers are enabled")
+		// Younger attempts may still have a charge in flight
+		require(c.Workers.RecoveryGrace > c.Timeouts.Charge,
+			"RECOVERY_GRACE must be longer than CHARGE_TIMEOUT (%s)", c.Timeouts.Charge)
+		require(c.Workers.RecoveryPolicy == "record" || c.Workers.RecoveryPolicy == "refund",
+			"RECOVERY_POLICY must be record or refund, got %q", c.Workers.RecoveryPolicy)
+		require(c.Workers.IdempotencyPruneInterval > 0, "IDEMPOTENCY_PRUNE_INTERVAL must be positive when workers are enabled")
+	}
+	if c.FX.Enabled {
+		require(len(c.FX.SettlementCurrency) == 3, "FX_SETTLEMENT_CURRENCY must be a three-letter code, got %q", c.FX.SettlementCurrency)
+		require(c.FX.Source == "file" || c.FX.Source == "http", "FX_SOURCE must be file or http, got %q", c.FX.Source)
+		require(c.FX.SourceLocation != "", "FX_SOURCE_LOCATION is required when fx is enabled")
+		require(c.FX.RefreshInterval > 0, "FX_REFRESH_INTERVAL must be positive when fx is enabled")
+		require(c.FX.QuoteTTL > 0, "FX_QUOTE_TTL must be positive when fx is enabled")
+		// Otherwise every rate goes stale before the next refresh
+		require(c.FX.MaxRateAge > c.FX.RefreshInterval,
+			"FX_MAX_RATE_AGE must be longer than FX_REFRESH_INTERVAL (%s)", c.FX.RefreshInterval)
+	}
+	names := map[string]bool{}
+	for i, rule := range c.Routing.Rules {
+		require(rule.Name != "", "routing rule %d needs a name", i+1)
+		require(!names[rule.Name], "routing rule name %q is used twice", rule.Name)
+		names[rule.Name] = true
+		require(rule.Provider != "", "routing rule %q needs a provider", rule.Name)
+		require(rule.MinAmount >= 0 && rule.MaxAmount >= 0, "routing rule %q amounts must not be negative", rule.Name)
+		require(rule.MaxAmount == 0 || rule.MinAmount <= rule.MaxAmount, "routing rule %q min_amount is above max_amount", rule.Name)
+		require(rule.Percent >= 0 && rule.Percent <= 100, "routing rule %q percent must be between 0 and 100, got %d", rule.Name, rule.Percent)
+	}
+	failover := map[string]bool{}
+	for _, provider := range c.Routing.Failover {
+		require(!failover[provider], "routing failover lists %q twice", provider)
+		failover[provider] = true
+	}
+
+	return errs
+}
+
+// loadEnv applies the DB_* overrides, shared by Load and LoadDatabase
+func (d *DatabaseConfig) loadEnv(env *envReader) {
+	env.string("DB_USER", &d.User)
+	env.secret("DB_PASSWORD", &d.Password)
+	env.string("DB_HOST", &d.Host)
+	env.int("DB_PORT", &d.Port)
+	env.string("DB_NAME", &d.Name)
+	env.string("SSL_MODE", &d.SSLMode)
+	env.duration("DB_CONNECT_TIMEOUT", &d.ConnectTimeout)
+	env.int32("DB_MAX_CONNS", &d.MaxConns)
+	env.int32("DB_MIN_CONNS", &d.MinConns)
+	env.duration("DB_MAX_CONN_LIFETIME", &d.MaxConnLifetime)
+	env.duration("DB_MAX_CONN_IDLE_TIME", &d.MaxConnIdleTime)
+	env.duration("DB_HEALTH_CHECK_PERIOD", &d.HealthCheckPeriod)
+}
+
+// validate checks the connection settings on their own, so migrate can run
+// without the rest of the config
+func (d DatabaseConfig) validate() []error {
+	var errs []error
+	require := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	require(d.Host != "", "DB_HOST is required")
+	require(d.User != "", "DB_USER is required")
+	require(d.Name != "", "DB_NAME is required")
+	require(d.Port > 0 && d.Port < 65536, "DB_PORT must be a valid TCP port, got %d", d.Port)
+	require(d.MaxConns > 0, "DB_MAX_CONNS must be positive")
+	require(d.MinConns >= 0 && d.MinConns <= d.MaxConns,
+		"DB_MIN_CONNS must be between 0 and DB_MAX_CONNS (%d)", d.MaxConns)
+	return errs
+}
+
+// DSN builds the pgx connection string, escaping credentials
+func (d DatabaseConfig) DSN() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, string(d.Password)),
+		Host:   fmt.Sprintf("%s:%d", d.Host, d.Port),
+		Path:   "/" + d.Name,
+	}
+
+	q := url.Values{}
+	q.Set("sslmode", d.SSLMode)
+	q.Set("connect_timeout", strconv.Itoa(int(d.ConnectTimeout.Seconds())))
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// envReader reads KEY, or the contents of the file named by KEY_FILE
+// (Docker/ECS secrets), and collects parse errors
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) lookup(key string) (string, bool) {
+	if file := os.Getenv(key + "_FILE"); file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s_FILE: %w", key, err))
+			return "", false
+		}
+		return strings.TrimSpace(string(raw)), true
+	}
+	return os.LookupEnv(key)
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if v, ok := e.lookup(key); ok {
+		*dst = v
+	}
+}
+
+func (e *envReader) secret(key string, dst *Secret) {
+	if v, ok := e.lookup(key); ok {
+		*dst = Secret(v)
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	if v, ok := e.lookup(key); ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) int32(key string, dst *int32) {
+	if v, ok := e.lookup(key); ok && v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+			return
+		}
+		*dst = int32(n)
+	}
+}
+
+func (e *envReader) int64(key string, dst *int64) {
+	if v, ok := e.lookup(key); ok && v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	if v, ok := e.lookup(key); ok && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: invalid boolean %q", key, v))
+			return
+		}
+		*dst = b
+	}
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	if v, ok := e.lookup(key); ok && v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: invalid duration %q", key, v))
+			return
+		}
+		*dst = d
+	}
+}
+
+func sortedKeys(m map[string]time.Duration) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,183 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+)
+
+// The settings every test relies on come from the test, not the machine
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"CONFIG_FILE", "PORT", "DB_HOST", "DB_USER", "DB_NAME", "DB_PASSWORD",
+		"STRIPE_ENABLED", "STRIPE_SECRET_KEY", "STRIPE_WEBHOOK_SECRET",
+		"STRIPE_SECRET_KEY_FILE", "STRIPE_WEBHOOK_SECRET_FILE",
+		"SHUTDOWN_DRAIN_DELAY", "SHUTDOWN_TIMEOUT", "STOP_TIMEOUT",
+	} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+const minimalYAML = `
+database:
+  host: yaml-host
+  user: yaml-user
+  name: payments
+providers:
+  stripe:
+    secret_key: sk_yaml
+    webhook_secret: whsec_yaml
+`
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", minimalYAML+`
+port: 9000
+http:
+  read_header_timeout: 7s
+`)
+
+	t.Setenv("PORT", "9100")
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("STRIPE_SECRET_KEY", "sk_env")
+	t.Setenv("STRIPE_SECRET_KEY_FILE", writeFile(t, "stripe_key", "sk_file\n"))
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	checks := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"default", cfg.HTTP.ReadTimeout, 15 * time.Second},
+		{"yaml over default", cfg.HTTP.ReadHeaderTimeout, 7 * time.Second},
+		{"yaml", cfg.Database.User, "yaml-user"},
+		{"env over yaml", cfg.Port, 9100},
+		{"env over yaml", cfg.Database.Host, "env-host"},
+		{"secret file over env", cfg.Providers.Stripe.SecretKey, config.Secret("sk_file")},
+		{"yaml secret", cfg.Providers.Stripe.WebhookSecret, config.Secret("whsec_yaml")},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, c.got)
+		}
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", minimalYAML)
+
+	cfg, err := config.Load(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Database.Host != "yaml-host" {
+		t.Fatalf("expected config.yaml in the directory to be read, got %q", cfg.Database.Host)
+	}
+}
+
+func TestLoadExampleConfig(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STRIPE_SECRET_KEY", "sk_test")
+	t.Setenv("STRIPE_WEBHOOK_SECRET", "whsec_test")
+
+	if _, err := config.Load("../../deploy/config/config.example.yaml"); err != nil {
+		t.Fatalf("expected the example config to load, got %v", err)
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", minimalYAML+`
+http:
+  shutdown_timout: 10s
+`)
+
+	_, err := config.Load(path)
+	if err == nil || !strings.Contains(err.Error(), "shutdown_timout") {
+		t.Fatalf("expected the misspelt field to be rejected, got %v", err)
+	}
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+routing:
+  rules:
+    - name: over
+      provider: stripe
+      percent: 150
+    - name: under
+      provider: stripe
+      percent: -1
+`)
+	t.Setenv("PORT", "eighty")
+	t.Setenv("SHUTDOWN_TIMEOUT", "soon")
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "20s")
+
+	_, err := config.Load(path)
+	if err == nil {
+		t.Fatal("expected the config to be rejected")
+	}
+
+	for _, want := range []string{
+		`PORT: invalid integer "eighty"`,
+		`SHUTDOWN_TIMEOUT: invalid duration "soon"`,
+		"DB_HOST is required",
+		"DB_USER is required",
+		"DB_NAME is required",
+		"STRIPE_SECRET_KEY is required",
+		"STRIPE_WEBHOOK_SECRET is required",
+		`routing rule "over" percent must be between 0 and 100, got 150`,
+		`routing rule "under" percent must be between 0 and 100, got -1`,
+		"SHUTDOWN_DRAIN_DELAY plus SHUTDOWN_TIMEOUT must be shorter than STOP_TIMEOUT",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadDatabase(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+database:
+  host: yaml-host
+  user: yaml-user
+  name: payments
+`)
+	t.Setenv("DB_USER", "env-user")
+
+	// Stripe is enabled without keys, which only the full config cares about
+	db, err := config.LoadDatabase(path)
+	if err != nil {
+		t.Fatalf("LoadDatabase: %v", err)
+	}
+	if db.Host != "yaml-host" || db.User != "env-user" {
+		t.Fatalf("expected the yaml host and the env user, got %+v", db)
+	}
+
+	t.Setenv("DB_MAX_CONNS", "many")
+	if _, err := config.LoadDatabase(path); err == nil || !strings.Contains(err.Error(), "DB_MAX_CONNS") {
+		t.Fatalf("expected the bad DB_MAX_CONNS to be rejected, got %v", err)
+	}
+}
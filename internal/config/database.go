@@ -8,18 +8,19 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func InitPostgresPool(connectionString string) (*pgxpool.Pool, error) {
+func InitPostgresPool(db DatabaseConfig) (*pgxpool.Pool, error) {
 	// Parse configuration
-	config, err := pgxpool.ParseConfig(connectionString)
+	config, err := pgxpool.ParseConfig(db.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse DB config: %w", err)
 	}
 
 	// Configure connection pool (optimized for payments)
-	config.MaxConns = 25                  // Default is usually too high
-	config.MinConns = 2                   // Keep some warm connections
-	config.MaxConnLifetime = 1 * time.Hour // Refresh connections periodically
-	config.HealthCheckPeriod = 1 * time.Minute
+	config.MaxConns = db.MaxConns                  // Default is usually too high
+	config.MinConns = db.MinConns                  // Keep some warm connections
+	config.MaxConnLifetime = db.MaxConnLifetime    // Refresh connections periodically
+	config.MaxConnIdleTime = db.MaxConnIdleTime
+	config.HealthCheckPeriod = db.HealthCheckPeriod
 
 	// Create the pool
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
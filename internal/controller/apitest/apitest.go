@@ -0,0 +1,113 @@
+// Package apitest runs the real router on in-memory repositories and the fake
+// provider, and checks the handlers against the OpenAPI document in api/:
+//
+//	func TestAPIContract(t *testing.T) {
+//		apitest.Contract(t)
+//	}
+//
+// NewServer is the same router on a local listener, for clients of the API.
+package apitest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/controller"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/core"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/repository/memory"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/server"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+)
+
+// Rates the FX service starts with, settled in eur
+const ratesDocument = `{"base": "eur", "rates": {"usd": "1.0842", "gbp": "0.8571"}}`
+
+// Service is every route wired to the fake provider
+type Service struct {
+	Handler http.Handler
+	// The router inside Handler, to list its routes
+	Routes chi.Routes
+	Fake   *adapters.FakeAdapter
+	Config *config.Config
+}
+
+// Server is a Service listening on URL, closed when the test ends
+type Server struct {
+	*httptest.Server
+	Service
+}
+
+// NewService builds the router the way cmd/api does, with the fake provider
+// as the only one, wrapped with retries and a circuit breaker, as the routing
+// default, with FX enabled and Idempotency-Key support
+func NewService(t *testing.T) Service {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Providers.Fake.Enabled = true
+	cfg.Routing.Default = string(adapters.FakeProvider)
+	cfg.FX.Enabled = true
+
+	registry := core.NewProviderRegistry()
+	fake := adapters.NewFakeAdapter(string(cfg.Providers.Fake.WebhookSecret))
+	registry.Register(adapters.FakeProvider, adapters.NewResilientProcessor(fake, cfg.Resilience))
+	router, err := core.NewRouter(registry, cfg.Routing)
+	if err != nil {
+		t.Fatalf("router: %v", err)
+	}
+
+	transactions := memory.NewTransactionRepository()
+	paymentMethods := memory.NewPaymentMethodRepository()
+	refunds := memory.NewRefundRepository(transactions)
+	attempts := memory.NewChargeAttemptRepository()
+	orders := memory.NewOrderRepository()
+	fxQuotes := memory.NewFxQuoteRepository()
+	unitOfWork := memory.NewUnitOfWork(transactions, paymentMethods, refunds, attempts, orders, fxQuotes)
+
+	paymentService := service.NewPaymentService(registry, router, transactions, paymentMethods, refunds, attempts, orders, fxQuotes, unitOfWork, cfg.Timeouts.Provider)
+	orderService := service.NewOrderService(orders, transactions, refunds, unitOfWork)
+	healthService := service.NewHealthService()
+
+	ratesFile := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(ratesFile, []byte(ratesDocument), 0o600); err != nil {
+		t.Fatalf("rates: %v", err)
+	}
+	fxService := service.NewFxService(memory.NewFxRateRepository(), fxQuotes, adapters.NewFileRateSource(ratesFile), cfg.FX.SettlementCurrency, cfg.FX.QuoteTTL, cfg.FX.MaxRateAge)
+	if _, err := fxService.RefreshRates(context.Background()); err != nil {
+		t.Fatalf("rates: %v", err)
+	}
+
+	handler := controller.NewRouter(controller.Controllers{
+		Payments: controller.NewPaymentController(paymentService, cfg.Timeouts),
+		Orders:   controller.NewOrderController(orderService, cfg.Timeouts),
+		Health:   controller.NewHealthController(healthService, cfg.Health.ProbeTimeout),
+		Fx:       controller.NewFxController(fxService, cfg.Timeouts),
+		Fake:     controller.NewFakeController(fake, paymentService, cfg.Timeouts.Webhook),
+
+		Idempotency: service.NewIdempotencyService(memory.NewIdempotencyRepository(), cfg.HTTP.IdempotencyKeyTTL),
+	})
+
+	return Service{
+		Handler: server.LimitBody(cfg.HTTP.MaxBodyBytes, handler),
+		Routes:  handler,
+		Fake:    fake,
+		Config:  cfg,
+	}
+}
+
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+
+	svc := NewService(t)
+	srv := httptest.NewServer(svc.Handler)
+	t.Cleanup(srv.Close)
+	return &Server{Server: srv, Service: svc}
+}
@@ -0,0 +1,315 @@
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+const customer = "cus_contract"
+
+// Request types and the schema documenting each
+var requestSchemas = map[string]any{
+	"PaymentIntentRequest":       model.PaymentIntentRequest{},
+	"CreatePaymentIntentRequest": model.CreatePaymentIntentRequest{},
+	"PaymentInfoRequest":         model.PaymentInfoRequest{},
+	"SplitTenderRequest":         model.SplitTenderRequest{},
+	"TenderLeg":                  model.TenderLeg{},
+	"CreateRefundRequest":        model.CreateRefundRequest{},
+	"OrderRequest":               model.OrderRequest{},
+	"FxQuoteRequest":             model.FxQuoteRequest{},
+	"FakeWebhookRequest":         model.FakeWebhookRequest{},
+}
+
+// Response types and the schema documenting each
+var responseSchemas = map[string]any{
+	"PaymentIntentResponse": model.PaymentIntentResponse{},
+	"ProviderAttempt":       model.ProviderAttempt{},
+	"SplitTenderResponse":   model.SplitTenderResponse{},
+	"TenderLegResult":       model.TenderLegResult{},
+	"PaymentEvent":          model.PaymentEvent{},
+	"PaymentMethodResponse": model.PaymentMethodResponse{},
+	"ProviderStats":         model.ProviderStats{},
+	"RefundResponse":        model.RefundResponse{},
+	"OrderResponse":         model.OrderResponse{},
+	"OrderAttempt":          model.OrderAttempt{},
+	"FxQuoteResponse":       model.FxQuoteResponse{},
+	"HealthReport":          model.HealthReport{},
+	"HealthCheckResult":     model.HealthCheckResult{},
+	"ErrorResponse":         model.ErrorResponse{},
+	"ErrorEnvelope":         model.ErrorEnvelope{},
+	"ErrorDetail":           model.ErrorDetail{},
+	"FieldError":            model.FieldError{},
+}
+
+// Contract fails when the document and the code disagree: a route or model
+// field one of them lacks, or a real response the document does not describe.
+// Every documented operation must be called by the scenario below.
+func Contract(t *testing.T) {
+	spec := LoadSpec(t)
+
+	t.Run("schemas match the models", func(t *testing.T) {
+		for name, v := range requestSchemas {
+			for _, problem := range spec.matchStruct(name, v, true) {
+				t.Error(problem)
+			}
+		}
+		for name, v := range responseSchemas {
+			for _, problem := range spec.matchStruct(name, v, false) {
+				t.Error(problem)
+			}
+		}
+	})
+
+	t.Run("every route is documented", func(t *testing.T) {
+		routed := map[string]bool{}
+		err := chi.Walk(NewService(t).Routes, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			routed[strings.ToLower(method)+" "+route] = true
+			if _, ok := spec.Paths[route][strings.ToLower(method)]; !ok {
+				t.Errorf("%s %s is routed but not documented", method, route)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("walk: %v", err)
+		}
+		for _, op := range spec.operations() {
+			if !routed[op] {
+				t.Errorf("%s is documented but not routed", op)
+			}
+		}
+	})
+
+	t.Run("responses match the document", func(t *testing.T) {
+		c := &contractClient{t: t, spec: spec, svc: NewService(t), called: map[string]bool{}}
+		c.scenario()
+		for _, op := range spec.operations() {
+			if !c.called[op] {
+				t.Errorf("%s is never called, add it to the scenario", op)
+			}
+		}
+	})
+}
+
+// "method path" of every documented operation, sorted
+func (s *Spec) operations() []string {
+	var ops []string
+	for path, methods := range s.Paths {
+		for method := range methods {
+			ops = append(ops, method+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+type contractClient struct {
+	t      *testing.T
+	spec   *Spec
+	svc    Service
+	called map[string]bool
+}
+
+// Sends a request, checks the status is want and that the request and the
+// response match the operation the router picked
+func (c *contractClient) do(method string, path string, body any, want int, headers ...http.Header) []byte {
+	c.t.Helper()
+
+	var raw []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		raw = []byte(b)
+	case []byte:
+		raw = b
+	default:
+		raw, _ = json.Marshal(b)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(string(raw)))
+	for _, h := range headers {
+		for k, v := range h {
+			req.Header[k] = v
+		}
+	}
+	rctx := chi.NewRouteContext()
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	c.svc.Handler.ServeHTTP(rec, req)
+
+	name := method + " " + path
+	if rec.Code != want {
+		c.t.Errorf("%s: status %d, want %d: %s", name, rec.Code, want, rec.Body.String())
+	}
+
+	pattern := rctx.RoutePattern()
+	op, ok := c.spec.Paths[pattern][strings.ToLower(method)]
+	if !ok {
+		c.t.Errorf("%s: %s %s is not documented", name, method, pattern)
+		return rec.Body.Bytes()
+	}
+	c.called[strings.ToLower(method)+" "+pattern] = true
+
+	// Requests meant to be rejected are not expected to match
+	if op.RequestBody != nil && raw != nil && want < http.StatusBadRequest {
+		for _, problem := range c.spec.Validate(raw, op.RequestBody.Content["application/json"].Schema) {
+			c.t.Errorf("%s: request %s", name, problem)
+		}
+	}
+
+	res, ok := op.Response(rec.Code)
+	if !ok {
+		c.t.Errorf("%s: status %d is not documented for %s", name, rec.Code, op.OperationID)
+		return rec.Body.Bytes()
+	}
+	if content, ok := res.Content["application/json"]; ok {
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			c.t.Errorf("%s: Content-Type %q", name, got)
+		}
+		for _, problem := range c.spec.Validate(rec.Body.Bytes(), content.Schema) {
+			c.t.Errorf("%s: response %s", name, problem)
+		}
+	}
+	if deprecated := rec.Header().Get("Deprecation") != ""; deprecated != op.Deprecated {
+		c.t.Errorf("%s: Deprecation header is %t, the document says deprecated is %t", name, deprecated, op.Deprecated)
+	}
+	return rec.Body.Bytes()
+}
+
+// Reads a field out of a response, through data for /v2 ones
+func (c *contractClient) field(raw []byte, name string) string {
+	c.t.Helper()
+
+	var body map[string]any
+	if err := json.Unmarshal(raw, &body); err != nil {
+		c.t.Fatalf("decoding %s: %v", raw, err)
+	}
+	if data, ok := body["data"].(map[string]any); ok {
+		body = data
+	}
+	value, _ := body[name].(string)
+	if value == "" {
+		c.t.Fatalf("response has no %s: %s", name, raw)
+	}
+	return value
+}
+
+func intent(extra map[string]any) map[string]any {
+	body := map[string]any{"amount": 1250, "currency": "usd", "customer_id": customer, "payment_method": "card"}
+	for k, v := range extra {
+		body[k] = v
+	}
+	return body
+}
+
+func charge(extra map[string]any) map[string]any {
+	body := map[string]any{"amount": 1250, "currency": "usd", "customer_id": customer, "token": "pm_fake_visa", "remember_me": true}
+	for k, v := range extra {
+		body[k] = v
+	}
+	return body
+}
+
+func split(declineLast bool) map[string]any {
+	last := map[string]any{"provider": "fake", "token": "pm_fake_visa", "amount": 500}
+	if declineLast {
+		last["token"] = "pm_fake_decline_insufficient_funds"
+	}
+	return map[string]any{
+		"customer_id": customer,
+		"currency":    "usd",
+		"legs":        []any{map[string]any{"provider": "fake", "token": "pm_fake_mastercard", "amount": 750}, last},
+	}
+}
+
+func (c *contractClient) scenario() {
+	c.do("GET", "/openapi.json", nil, http.StatusOK)
+	c.do("GET", "/livez", nil, http.StatusOK)
+	c.do("GET", "/payments/health", nil, http.StatusOK)
+	c.do("GET", "/readyz", nil, http.StatusOK)
+
+	// v1
+	routed := c.field(c.do("POST", "/payments/intent", intent(nil), http.StatusOK), "id")
+	pinned := c.field(c.do("POST", "/payments/fake/intent", intent(map[string]any{"remember_me": false}), http.StatusOK), "id")
+	c.do("GET", "/payments/fake/intent", map[string]any{"id": routed}, http.StatusOK)
+	c.do("GET", "/payments/fake/intent", map[string]any{}, http.StatusUnprocessableEntity)
+	c.do("POST", "/payments/stripe/intent", intent(nil), http.StatusNotFound)
+
+	c.do("POST", "/payments/fake/charge", charge(nil), http.StatusOK)
+	c.do("POST", "/payments/fake/charge", charge(map[string]any{"amount": 20002}), http.StatusPaymentRequired)
+	c.do("POST", "/payments/fake/charge", charge(map[string]any{"amount": -1, "token": ""}), http.StatusUnprocessableEntity)
+	c.do("POST", "/payments/fake/charge", `{"amount":`, http.StatusBadRequest)
+	c.do("POST", "/payments/split", split(false), http.StatusOK)
+	c.do("POST", "/payments/split", split(true), http.StatusPaymentRequired)
+
+	c.do("POST", "/fake/intents/"+routed+"/webhook", map[string]any{"outcome": "succeeded", "payment_method": "pm_fake_hook"}, http.StatusOK)
+	c.do("POST", "/fake/intents/"+routed+"/webhook", map[string]any{"outcome": "failed", "delay": "10ms"}, http.StatusAccepted)
+	raw, headers, err := c.svc.Fake.SignedWebhook(pinned, "succeeded", "pm_fake_signed")
+	if err != nil {
+		c.t.Fatalf("signing webhook: %v", err)
+	}
+	c.do("POST", "/webhooks/fake", raw, http.StatusOK, headers)
+	c.do("POST", "/webhooks/fake", raw, http.StatusBadRequest)
+
+	c.do("GET", "/payments/methods/"+customer, nil, http.StatusOK)
+	c.do("GET", "/payments/methods/cus_nobody", nil, http.StatusOK)
+	c.do("GET", "/payments/providers", nil, http.StatusOK)
+
+	order := c.field(c.do("POST", "/orders", map[string]any{"customer_id": customer, "amount_due": 2000, "currency": "usd"}, http.StatusCreated), "id")
+	c.do("GET", "/orders/"+order, nil, http.StatusOK)
+	c.do("POST", "/orders/"+order+"/cancel", nil, http.StatusOK)
+	c.do("GET", "/orders/00000000-0000-0000-0000-000000000000", nil, http.StatusNotFound)
+
+	quote := c.field(c.do("POST", "/fx/quotes", map[string]any{"customer_id": customer, "amount": 1000, "presentment_currency": "usd"}, http.StatusCreated), "id")
+	c.do("GET", "/fx/quotes/"+quote, nil, http.StatusOK)
+
+	// v2
+	c.do("POST", "/v2/payment-intents", intent(nil), http.StatusCreated)
+	created := c.field(c.do("POST", "/v2/payment-intents", intent(map[string]any{"provider": "fake"}), http.StatusCreated), "id")
+	c.do("POST", "/v2/payment-intents", intent(map[string]any{"payment_method": "", "extra": true}), http.StatusUnprocessableEntity)
+	c.do("GET", "/v2/payment-intents/"+created, nil, http.StatusOK)
+	c.do("GET", "/v2/payment-intents/pi_missing", nil, http.StatusNotFound)
+
+	charged := c.field(c.do("POST", "/v2/charges", charge(map[string]any{"provider": "fake"}), http.StatusCreated), "id")
+	c.do("POST", "/v2/charges", charge(map[string]any{"provider": "fake", "amount": 20001}), http.StatusPaymentRequired)
+	c.do("POST", "/v2/charges", charge(nil), http.StatusUnprocessableEntity)
+
+	// A retry with the same key is answered from the first response, a new charge would have a new id
+	key := http.Header{"Idempotency-Key": {"contract-charge"}}
+	first := c.do("POST", "/v2/charges", charge(map[string]any{"provider": "fake", "amount": 1300}), http.StatusCreated, key)
+	if replay := c.do("POST", "/v2/charges", charge(map[string]any{"provider": "fake", "amount": 1300}), http.StatusCreated, key); string(replay) != string(first) {
+		c.t.Errorf("retry with Idempotency-Key was not replayed: %s, first %s", replay, first)
+	}
+	c.do("POST", "/v2/charges", charge(map[string]any{"provider": "fake", "amount": 1400}), http.StatusUnprocessableEntity, key)
+
+	c.do("POST", "/v2/payment-intents/"+charged+"/refunds", map[string]any{"amount": 250, "reason": "requested_by_customer"}, http.StatusCreated)
+	c.do("POST", "/v2/payment-intents/"+charged+"/refunds", map[string]any{}, http.StatusCreated)
+	c.do("POST", "/v2/payment-intents/"+charged+"/refunds", map[string]any{}, http.StatusConflict)
+	next := c.field(c.do("GET", "/v2/payment-intents/"+charged+"/refunds?limit=1", nil, http.StatusOK), "next_cursor")
+	c.do("GET", "/v2/payment-intents/"+charged+"/refunds?limit=1&cursor="+next, nil, http.StatusOK)
+	c.do("GET", "/v2/payment-intents/"+charged+"/refunds?limit=1000", nil, http.StatusUnprocessableEntity)
+
+	c.do("POST", "/v2/split-tenders", split(false), http.StatusCreated)
+	c.do("POST", "/v2/split-tenders", split(true), http.StatusPaymentRequired)
+
+	c.do("GET", "/v2/customers/"+customer+"/payment-methods?limit=1", nil, http.StatusOK)
+	c.do("GET", "/v2/customers/cus_nobody/payment-methods", nil, http.StatusOK)
+	c.do("GET", "/v2/providers", nil, http.StatusOK)
+
+	order = c.field(c.do("POST", "/v2/orders", map[string]any{"customer_id": customer, "amount_due": 2000, "currency": "usd"}, http.StatusCreated), "id")
+	c.do("GET", "/v2/orders/"+order, nil, http.StatusOK)
+	c.do("POST", "/v2/orders/"+order+"/cancel", nil, http.StatusOK)
+	c.do("GET", "/v2/orders/not-an-order", nil, http.StatusNotFound)
+
+	quote = c.field(c.do("POST", "/v2/fx-quotes", map[string]any{"customer_id": customer, "amount": 1000, "presentment_currency": "gbp"}, http.StatusCreated), "id")
+	c.do("GET", "/v2/fx-quotes/"+quote, nil, http.StatusOK)
+	c.do("POST", "/v2/payment-intents", map[string]any{"customer_id": customer, "payment_method": "card", "fx_quote": quote}, http.StatusCreated)
+}
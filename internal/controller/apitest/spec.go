@@ -0,0 +1,298 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/api"
+)
+
+// Spec is the parsed OpenAPI document. Schemas are checked with the subset of
+// JSON Schema the document uses.
+type Spec struct {
+	Paths   map[string]map[string]Operation `json:"paths"`
+	Schemas map[string]Schema
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Deprecated  bool                `json:"deprecated"`
+	RequestBody *Body               `json:"requestBody"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Body struct {
+	Content map[string]struct {
+		Schema Schema `json:"schema"`
+	} `json:"content"`
+}
+
+type Response struct {
+	Body
+	Headers map[string]any `json:"headers"`
+}
+
+type Schema map[string]any
+
+// LoadSpec parses the embedded document and fails the test when it is not
+// an OpenAPI 3 document or a $ref points nowhere
+func LoadSpec(t *testing.T) *Spec {
+	t.Helper()
+
+	var doc struct {
+		OpenAPI    string                          `json:"openapi"`
+		Paths      map[string]map[string]Operation `json:"paths"`
+		Components struct {
+			Schemas map[string]Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(api.Spec))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("openapi.json: version %q is not OpenAPI 3", doc.OpenAPI)
+	}
+
+	spec := &Spec{Paths: doc.Paths, Schemas: doc.Components.Schemas}
+	for _, ref := range refs(api.Spec) {
+		if _, ok := spec.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]; !ok {
+			t.Errorf("openapi.json: $ref %s does not exist", ref)
+		}
+	}
+	return spec
+}
+
+func refs(raw []byte) []string {
+	var out []string
+	for _, part := range strings.Split(string(raw), `"$ref": "`)[1:] {
+		out = append(out, part[:strings.IndexByte(part, '"')])
+	}
+	return out
+}
+
+// Response returns the schema documented for status, falling back to the
+// default response. ok is false when neither is documented.
+func (op Operation) Response(status int) (Response, bool) {
+	if res, ok := op.Responses[strconv.Itoa(status)]; ok {
+		return res, true
+	}
+	res, ok := op.Responses["default"]
+	return res, ok
+}
+
+// Validate checks a JSON document against schema and returns every mismatch
+func (s *Spec) Validate(raw []byte, schema Schema) []string {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return []string{fmt.Sprintf("not JSON: %v", err)}
+	}
+	var problems []string
+	s.validate(value, schema, "$", &problems)
+	return problems
+}
+
+func (s *Spec) validate(value any, schema Schema, path string, problems *[]string) {
+	fail := func(format string, args ...any) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		s.validate(value, s.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")], path, problems)
+		return
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !slices.Contains(types, jsonType(value)) {
+		if !(jsonType(value) == "integer" && slices.Contains(types, "number")) {
+			fail("got %s, want %s", jsonType(value), strings.Join(types, " or "))
+			return
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		fail("%v is not one of %v", value, enum)
+	}
+
+	switch v := value.(type) {
+	case string:
+		if n, ok := number(schema["maxLength"]); ok && float64(utf8.RuneCountInString(v)) > n {
+			fail("longer than %v", n)
+		}
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				fail("%q is not a date-time", v)
+			}
+		case "uuid":
+			if _, err := uuid.Parse(v); err != nil {
+				fail("%q is not a uuid", v)
+			}
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if min, ok := number(schema["minimum"]); ok && n < min {
+			fail("%v is below the minimum %v", v, min)
+		}
+		if max, ok := number(schema["maximum"]); ok && n > max {
+			fail("%v is above the maximum %v", v, max)
+		}
+	case []any:
+		if n, ok := number(schema["maxItems"]); ok && float64(len(v)) > n {
+			fail("more than %v items", n)
+		}
+		if n, ok := number(schema["minItems"]); ok && float64(len(v)) < n {
+			fail("fewer than %v items", n)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				s.validate(item, items, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case map[string]any:
+		s.validateObject(v, schema, path, problems)
+	}
+}
+
+func (s *Spec) validateObject(v map[string]any, schema Schema, path string, problems *[]string) {
+	required, _ := schema["required"].([]any)
+	for _, name := range required {
+		if _, ok := v[name.(string)]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: %s is required", path, name))
+		}
+	}
+	if n, ok := number(schema["maxProperties"]); ok && float64(len(v)) > n {
+		*problems = append(*problems, fmt.Sprintf("%s: more than %v properties", path, n))
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	for name, value := range v {
+		if property, ok := properties[name].(map[string]any); ok {
+			s.validate(value, property, path+"."+name, problems)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				*problems = append(*problems, fmt.Sprintf("%s: %s is not documented", path, name))
+			}
+		case map[string]any:
+			s.validate(value, extra, path+"."+name, problems)
+		}
+	}
+}
+
+// Checks that the properties of the named schema are the JSON fields of v's
+// type, and that it requires the fields Go always sends (responses) or the
+// validation rules require (requests)
+func (s *Spec) matchStruct(name string, v any, request bool) []string {
+	schema, ok := s.Schemas[name]
+	if !ok {
+		return []string{name + " is not documented"}
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	required, _ := schema["required"].([]any)
+
+	var problems []string
+	fields := structFields(reflect.TypeOf(v), request)
+	for field, mustSend := range fields {
+		if _, ok := properties[field]; !ok {
+			problems = append(problems, fmt.Sprintf("%s.%s is not documented", name, field))
+		}
+		if mustSend != slices.Contains(required, any(field)) {
+			problems = append(problems, fmt.Sprintf("%s.%s: required is %t in the document, %t in the code", name, field, slices.Contains(required, any(field)), mustSend))
+		}
+	}
+	for property := range properties {
+		if _, ok := fields[property]; !ok {
+			problems = append(problems, fmt.Sprintf("%s.%s is documented but not a field of %T", name, property, v))
+		}
+	}
+	if request && schema["additionalProperties"] != false {
+		problems = append(problems, name+" must set additionalProperties to false, unknown fields are rejected")
+	}
+	return problems
+}
+
+// JSON names of t's fields, true for those always required. Response fields
+// without omitempty are always sent; request fields are required by their
+// validate tag.
+func structFields(t reflect.Type, request bool) map[string]bool {
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			for name, required := range structFields(field.Type, request) {
+				fields[name] = required
+			}
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		if request {
+			fields[name] = slices.Contains(strings.Split(field.Tag.Get("validate"), ","), "required")
+		} else {
+			fields[name] = !strings.Contains(options, "omitempty")
+		}
+	}
+	return fields
+}
+
+func schemaTypes(schema Schema) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, len(t))
+		for i, v := range t {
+			types[i], _ = v.(string)
+		}
+		return types
+	}
+	return nil
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+func number(v any) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
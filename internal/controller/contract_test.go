@@ -0,0 +1,12 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/controller/apitest"
+)
+
+// Fails when a handler drifts from api/openapi.json
+func TestAPIContract(t *testing.T) {
+	apitest.Contract(t)
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/pkg/utils"
+)
+
+var categoryStatus = map[model.ErrorCategory]int{
+	model.CategoryInvalidRequest:      http.StatusBadRequest,
+	model.CategoryValidation:          http.StatusUnprocessableEntity,
+	model.CategoryDecline:             http.StatusPaymentRequired,
+	model.CategoryNotFound:            http.StatusNotFound,
+	model.CategoryConflict:            http.StatusConflict,
+	model.CategoryTooLarge:            http.StatusRequestEntityTooLarge,
+	model.CategoryProviderError:       http.StatusBadGateway,
+	model.CategoryProviderUnavailable: http.StatusServiceUnavailable,
+	model.CategoryInternal:            http.StatusInternalServerError,
+}
+
+// Writes err as an ErrorResponse with the status of its category
+func respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	status, apiErr := errorStatus(r, err)
+	utils.RespondWithJSON(w, status, model.ErrorResponse{
+		Error:       apiErr.Message,
+		Code:        apiErr.Code,
+		Category:    string(apiErr.Category),
+		DeclineCode: apiErr.DeclineCode,
+		Fields:      apiErr.Fields,
+	})
+}
+
+// Classifies err and picks its status. Server side failures are logged with
+// their cause, which the client never sees.
+func errorStatus(r *http.Request, err error) (int, *model.Error) {
+	apiErr := ports.AsError(err)
+
+	status, ok := categoryStatus[apiErr.Category]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	if status >= http.StatusInternalServerError {
+		// Provider errors keep the provider's own error out of their text
+		detail := err.Error()
+		if apiErr.Cause != nil && !strings.Contains(detail, apiErr.Cause.Error()) {
+			detail += ": " + apiErr.Cause.Error()
+		}
+		log.Printf("%s %s failed with %s: %s", r.Method, r.URL.Path, apiErr.Code, detail)
+	}
+	return status, apiErr
+}
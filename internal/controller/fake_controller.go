@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"log"
+	"sync"
+	"time"
+	"context"
+	"net/http"
+
+	"github.com/danielmoisemontezima/zw-payment-service/pkg/utils"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+)
+
+// FakeController drives the fake provider; only routed when it is enabled
+type FakeController struct {
+	adapter *adapters.FakeAdapter
+	service *service.PaymentService
+	webhookTimeout time.Duration
+
+	// Delayed webhooks not delivered yet, and deliveries running
+	mu      sync.Mutex
+	pending map[*time.Timer]string
+	stopped bool
+	running sync.WaitGroup
+}
+
+func NewFakeController(adapter *adapters.FakeAdapter, service *service.PaymentService, webhookTimeout time.Duration) *FakeController {
+	return &FakeController{adapter: adapter, service: service, webhookTimeout: webhookTimeout, pending: make(map[*time.Timer]string)}
+}
+
+// Run blocks until ctx is cancelled, then drops the delayed webhooks still
+// waiting and waits for the ones being delivered
+func (c *FakeController) Run(ctx context.Context) {
+	<-ctx.Done()
+
+	c.mu.Lock()
+	c.stopped = true
+	for timer, intentID := range c.pending {
+		if timer.Stop() {
+			log.Printf("fake webhook for %s dropped on shutdown", intentID)
+			c.running.Done()
+		}
+	}
+	clear(c.pending)
+	c.mu.Unlock()
+
+	c.running.Wait()
+}
+
+func (c *FakeController) schedule(delay time.Duration, intentID string, raw []byte, headers map[string][]string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return false
+	}
+
+	c.running.Add(1)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		defer c.running.Done()
+
+		c.mu.Lock()
+		delete(c.pending, timer)
+		c.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.webhookTimeout)
+		defer cancel()
+
+		if _, err := c.service.ParseWebhook(ctx, adapters.FakeProvider, raw, headers); err != nil {
+			log.Printf("fake webhook for %s failed: %v", intentID, err)
+		}
+	})
+	c.pending[timer] = intentID
+	return true
+}
+
+// TriggerWebhook signs an event for a stored intent and feeds it through
+// the regular webhook flow, optionally after a delay
+func (c *FakeController) TriggerWebhook(w http.ResponseWriter, r *http.Request) {
+	intentID := r.PathValue("id")
+
+	var req model.FakeWebhookRequest
+	if !decodeRequest(w, r, &req, "") {
+		return
+	}
+
+	var delay time.Duration
+	if req.Delay != "" {
+		d, err := time.ParseDuration(req.Delay)
+		if err != nil {
+			respondWithError(w, r, fieldError("delay", "invalid", "delay must be a duration such as 30s"))
+			return
+		}
+		delay = d
+	}
+
+	raw, headers, err := c.adapter.SignedWebhook(intentID, req.Outcome, req.PaymentMethod)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	// Once shutting down nothing is left to deliver it later, so it goes now
+	if delay > 0 && c.schedule(delay, intentID, raw, headers) {
+		utils.RespondWithJSON(w, http.StatusAccepted, map[string]string{
+			"status": "scheduled",
+			"delay":  delay.String(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), c.webhookTimeout)
+	defer cancel()
+
+	event, err := c.service.ParseWebhook(ctx, adapters.FakeProvider, raw, headers)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, event)
+}
@@ -0,0 +1,114 @@
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/controller"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/core"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/repository/memory"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+)
+
+// A fake controller over memory repositories, with one pending intent
+func newFakeController(t *testing.T) (*controller.FakeController, *memory.TransactionRepository, string) {
+	t.Helper()
+
+	registry := core.NewProviderRegistry()
+	fake := adapters.NewFakeAdapter("whsec_test")
+	registry.Register(adapters.FakeProvider, fake)
+
+	transactions := memory.NewTransactionRepository()
+	paymentMethods := memory.NewPaymentMethodRepository()
+	refunds := memory.NewRefundRepository(transactions)
+	attempts := memory.NewChargeAttemptRepository()
+	orders := memory.NewOrderRepository()
+	quotes := memory.NewFxQuoteRepository()
+	uow := memory.NewUnitOfWork(transactions, paymentMethods, refunds, attempts, orders, quotes)
+	payments := service.NewPaymentService(registry, nil, transactions, paymentMethods, refunds, attempts, orders, quotes, uow, time.Second)
+
+	intent, err := payments.CreatePaymentIntent(context.Background(), adapters.FakeProvider, model.PaymentIntentRequest{
+		Amount: 1250, Currency: "usd", CustomerID: "cus_test", PaymentMethod: "card",
+	})
+	if err != nil {
+		t.Fatalf("CreatePaymentIntent: %v", err)
+	}
+	return controller.NewFakeController(fake, payments, time.Second), transactions, intent.ID
+}
+
+func triggerWebhook(c *controller.FakeController, intentID string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/fake/intents/"+intentID+"/webhook", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("id", intentID)
+	w := httptest.NewRecorder()
+	c.TriggerWebhook(w, r)
+	return w
+}
+
+func TestFakeControllerDropsDelayedWebhooksOnShutdown(t *testing.T) {
+	c, transactions, intentID := newFakeController(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(stopped)
+	}()
+
+	if w := triggerWebhook(c, intentID, `{"outcome": "succeeded", "delay": "1h"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return with a webhook still scheduled")
+	}
+
+	tx, err := transactions.FindByPaymentIntent(context.Background(), intentID)
+	if err != nil {
+		t.Fatalf("FindByPaymentIntent: %v", err)
+	}
+	if tx.TxStatus != ports.Pending {
+		t.Fatalf("expected the dropped webhook not to be delivered, got %s", tx.TxStatus)
+	}
+}
+
+func TestFakeControllerDeliversDelayedWebhooks(t *testing.T) {
+	c, transactions, intentID := newFakeController(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(stopped)
+	}()
+
+	if w := triggerWebhook(c, intentID, `{"outcome": "succeeded", "delay": "1ms"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-stopped
+
+	tx, err := transactions.FindByPaymentIntent(context.Background(), intentID)
+	if err != nil {
+		t.Fatalf("FindByPaymentIntent: %v", err)
+	}
+	if tx.TxStatus != ports.PaymentSucceeded {
+		t.Fatalf("expected the webhook to be delivered, got %s", tx.TxStatus)
+	}
+
+	// Nothing delivers later once stopped, so the webhook goes right away
+	if w := triggerWebhook(c, intentID, `{"outcome": "succeeded", "delay": "1h"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 once stopped, got %d: %s", w.Code, w.Body)
+	}
+}
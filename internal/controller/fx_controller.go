@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+	"github.com/danielmoisemontezima/zw-payment-service/pkg/utils"
+	"net/http"
+)
+
+type FxController struct {
+	service  *service.FxService
+	timeouts config.TimeoutsConfig
+}
+
+func NewFxController(service *service.FxService, timeouts config.TimeoutsConfig) *FxController {
+	return &FxController{service: service, timeouts: timeouts}
+}
+
+// Locks a rate for a settlement amount, pay it with fx_quote on an intent
+func (c *FxController) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	var req model.FxQuoteRequest
+	if !decodeRequest(w, r, &req, "") {
+		return
+	}
+
+	response, err := c.service.CreateQuote(ctx, req)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+func (c *FxController) GetQuote(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	response, err := c.service.GetQuote(ctx, r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
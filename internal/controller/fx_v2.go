@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// POST /v2/fx-quotes
+func (c *FxController) CreateQuoteV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	var req model.FxQuoteRequest
+	if !decodeRequestV2(w, r, &req, "") {
+		return
+	}
+
+	response, err := c.service.CreateQuote(ctx, req)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusCreated, response)
+}
+
+// GET /v2/fx-quotes/{id}
+func (c *FxController) GetQuoteV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	response, err := c.service.GetQuote(ctx, r.PathValue("id"))
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusOK, response)
+}
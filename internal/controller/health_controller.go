@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+	"github.com/danielmoisemontezima/zw-payment-service/pkg/utils"
+)
+
+type HealthController struct {
+	service      *service.HealthService
+	probeTimeout time.Duration
+}
+
+func NewHealthController(service *service.HealthService, probeTimeout time.Duration) *HealthController {
+	return &HealthController{service: service, probeTimeout: probeTimeout}
+}
+
+// Liveness only reports that the process can serve HTTP
+func (c *HealthController) GetLiveness(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, c.service.Liveness())
+}
+
+// Readiness reports dependency status, 503 when any check fails or while draining
+func (c *HealthController) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	// Keep the probe well under the ALB health check timeout
+	ctx, cancel := context.WithTimeout(r.Context(), c.probeTimeout)
+	defer cancel()
+
+	report := c.service.Readiness(ctx)
+	if report.Status != model.HealthUp {
+		utils.RespondWithJSON(w, http.StatusServiceUnavailable, report)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, report)
+}
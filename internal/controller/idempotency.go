@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+)
+
+const (
+	// Set by clients on a /v2 POST so it can be retried safely
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// Set on a response that was stored by an earlier request with the same key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotent runs a POST sent with an Idempotency-Key once and replays its
+// response to every retry with the same key and body. Other requests pass
+// through.
+func Idempotent(keys *service.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				respondWithErrorV2(w, r, model.NewError(model.CategoryInvalidRequest, "idempotency_key_invalid",
+					"%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				respondWithErrorV2(w, r, bodyError(err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			stored, err := keys.Begin(r.Context(), key, fingerprint(r, body))
+			if err != nil {
+				respondWithErrorV2(w, r, err)
+				return
+			}
+			if stored != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(stored.ResponseStatus)
+				w.Write(stored.ResponseBody)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			// Stored even when the client went away, its retry gets the outcome
+			if err := keys.Finish(context.WithoutCancel(r.Context()), key, rec.status, rec.body.Bytes()); err != nil {
+				log.Printf("Error storing the response for idempotency key %q: %v", key, err)
+			}
+		})
+	}
+}
+
+// Identifies what a key was first used for
+func fingerprint(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// Writes through to the client while keeping a copy of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,145 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/controller"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/repository/memory"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+)
+
+// Answers with status and the number of requests it ran
+type countingHandler struct {
+	runs    atomic.Int32
+	status  func(run int32) int
+	release chan struct{}
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	run := h.runs.Add(1)
+	if h.release != nil {
+		<-h.release
+	}
+	status := http.StatusCreated
+	if h.status != nil {
+		status = h.status(run)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"run": run}})
+}
+
+func idempotent(next http.Handler) http.Handler {
+	keys := service.NewIdempotencyService(memory.NewIdempotencyRepository(), time.Hour)
+	return controller.Idempotent(keys)(next)
+}
+
+func post(t *testing.T, h http.Handler, key string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v2/charges", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(controller.IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var envelope model.ErrorEnvelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	return envelope.Error.Code
+}
+
+func TestIdempotentReplaysTheFirstResponse(t *testing.T) {
+	next := &countingHandler{}
+	h := idempotent(next)
+
+	first := post(t, h, "key_1", `{"amount":1250}`)
+	again := post(t, h, "key_1", `{"amount":1250}`)
+	if first.Code != http.StatusCreated || again.Code != http.StatusCreated {
+		t.Fatalf("expected 201 twice, got %d and %d", first.Code, again.Code)
+	}
+	if again.Body.String() != first.Body.String() || next.runs.Load() != 1 {
+		t.Fatalf("expected one run replayed, got %d runs: %s then %s", next.runs.Load(), first.Body, again.Body)
+	}
+	if first.Header().Get(controller.IdempotentReplayedHeader) != "" || again.Header().Get(controller.IdempotentReplayedHeader) != "true" {
+		t.Fatalf("expected only the replay to be marked, got %q and %q",
+			first.Header().Get(controller.IdempotentReplayedHeader), again.Header().Get(controller.IdempotentReplayedHeader))
+	}
+
+	if rec := post(t, h, "key_1", `{"amount":1300}`); rec.Code != http.StatusUnprocessableEntity || errorCode(t, rec) != "idempotency_key_reused" {
+		t.Fatalf("expected idempotency_key_reused for another body, got %d %s", rec.Code, rec.Body)
+	}
+	if next.runs.Load() != 1 {
+		t.Fatalf("expected a reused key not to run, got %d runs", next.runs.Load())
+	}
+}
+
+func TestIdempotentRejectsRetriesWhileRunning(t *testing.T) {
+	next := &countingHandler{release: make(chan struct{})}
+	h := idempotent(next)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(t, h, "key_1", `{}`) }()
+	for next.runs.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if rec := post(t, h, "key_1", `{}`); rec.Code != http.StatusConflict || errorCode(t, rec) != "idempotency_key_in_use" {
+		t.Fatalf("expected idempotency_key_in_use, got %d %s", rec.Code, rec.Body)
+	}
+	close(next.release)
+	if rec := <-done; rec.Code != http.StatusCreated {
+		t.Fatalf("expected the first request to finish, got %d", rec.Code)
+	}
+	if rec := post(t, h, "key_1", `{}`); rec.Header().Get(controller.IdempotentReplayedHeader) != "true" {
+		t.Fatalf("expected a replay once finished, got %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestIdempotentRunsAgainAfterUnavailable(t *testing.T) {
+	next := &countingHandler{status: func(run int32) int {
+		if run == 1 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusCreated
+	}}
+	h := idempotent(next)
+
+	if rec := post(t, h, "key_1", `{}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if rec := post(t, h, "key_1", `{}`); rec.Code != http.StatusCreated || next.runs.Load() != 2 {
+		t.Fatalf("expected the retry to run, got %d after %d runs", rec.Code, next.runs.Load())
+	}
+}
+
+func TestIdempotentPassesOtherRequestsThrough(t *testing.T) {
+	next := &countingHandler{}
+	h := idempotent(next)
+
+	post(t, h, "", `{}`)
+	post(t, h, "", `{}`)
+	get := httptest.NewRequest(http.MethodGet, "/v2/providers", nil)
+	get.Header.Set(controller.IdempotencyKeyHeader, "key_1")
+	h.ServeHTTP(httptest.NewRecorder(), get)
+	h.ServeHTTP(httptest.NewRecorder(), get)
+	if next.runs.Load() != 4 {
+		t.Fatalf("expected every request without a key or not a POST to run, got %d runs", next.runs.Load())
+	}
+
+	if rec := post(t, h, strings.Repeat("k", 256), `{}`); rec.Code != http.StatusBadRequest || errorCode(t, rec) != "idempotency_key_invalid" {
+		t.Fatalf("expected idempotency_key_invalid, got %d %s", rec.Code, rec.Body)
+	}
+}
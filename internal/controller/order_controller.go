@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"github.com/danielmoisemontezima/zw-payment-service/pkg/utils"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+)
+
+type OrderController struct {
+	service *service.OrderService
+	timeouts config.TimeoutsConfig
+}
+
+func NewOrderController(service *service.OrderService, timeouts config.TimeoutsConfig) *OrderController {
+	return &OrderController{service: service, timeouts: timeouts}
+}
+
+func (c *OrderController) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	var req model.OrderRequest
+	if !decodeRequest(w, r, &req, "") {
+		return
+	}
+
+	response, err := c.service.CreateOrder(ctx, req)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+// Returns the order with all of its payment attempts
+func (c *OrderController) GetOrder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	response, err := c.service.GetOrder(ctx, r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+func (c *OrderController) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	response, err := c.service.CancelOrder(ctx, r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// POST /v2/orders
+func (c *OrderController) CreateOrderV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	var req model.OrderRequest
+	if !decodeRequestV2(w, r, &req, "") {
+		return
+	}
+
+	response, err := c.service.CreateOrder(ctx, req)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusCreated, response)
+}
+
+// GET /v2/orders/{id}
+func (c *OrderController) GetOrderV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	response, err := c.service.GetOrder(ctx, r.PathValue("id"))
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusOK, response)
+}
+
+// POST /v2/orders/{id}/cancel
+func (c *OrderController) CancelOrderV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	response, err := c.service.CancelOrder(ctx, r.PathValue("id"))
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusOK, response)
+}
@@ -6,18 +6,21 @@ import (
 	"time"
 	"context"
 	"net/http"
-	"encoding/json"
 	"github.com/danielmoisemontezima/zw-payment-service/pkg/utils"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/validation"
 )
 
 type PaymentController struct {
 	service *service.PaymentService
+	timeouts config.TimeoutsConfig
 }
 
-func NewPaymentController(service *service.PaymentService) *PaymentController {
-	return &PaymentController{service: service}
+func NewPaymentController(service *service.PaymentService, timeouts config.TimeoutsConfig) *PaymentController {
+	return &PaymentController{service: service, timeouts: timeouts}
 }
 
 func (c *PaymentController) CreatePaymentIntent(w http.ResponseWriter, r *http.Request) {
@@ -26,13 +29,12 @@ func (c *PaymentController) CreatePaymentIntent(w http.ResponseWriter, r *http.R
 	// Create base context from the HTTP request
     ctx := r.Context()
     
-    // Add payment-service-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    // Add payment-specific timeout
+    ctx, cancel := context.WithTimeout(ctx, c.timeouts.Request)
     defer cancel()
 
 	var req model.PaymentIntentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request")
+	if !decodeRequest(w, r, &req, validation.ScenarioIntent) {
 		return
 	}
 
@@ -44,7 +46,31 @@ func (c *PaymentController) CreatePaymentIntent(w http.ResponseWriter, r *http.R
 
 	response, err := c.service.CreatePaymentIntent(ctx, provider, req)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// Same as CreatePaymentIntent with the provider picked by the routing rules
+func (c *PaymentController) RoutePaymentIntent(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	var req model.PaymentIntentRequest
+	if !decodeRequest(w, r, &req, validation.ScenarioIntent) {
+		return
+	}
+
+	if req.RememberMe == nil {
+		defaultRememberMe := false
+		req.RememberMe = &defaultRememberMe
+	}
+
+	response, err := c.service.RoutePaymentIntent(ctx, req)
+	if err != nil {
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -57,44 +83,65 @@ func (c *PaymentController) ChargeClient(w http.ResponseWriter, r *http.Request)
 	// Create base context from the HTTP request
     ctx := r.Context()
     
-    // Add payment-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+    // Add payment-specific timeout
+    ctx, cancel := context.WithTimeout(ctx, c.timeouts.Charge)
     defer cancel()
 
 	var req model.PaymentIntentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request")
+	if !decodeRequest(w, r, &req, validation.ScenarioCharge) {
 		return
 	}
 
 	response, err := c.service.ChargeClient(ctx, provider, req)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
 	utils.RespondWithJSON(w, http.StatusOK, response)
 }
 
+// Charges every leg of a split tender, the response lists what happened to each
+func (c *PaymentController) SplitTender(w http.ResponseWriter, r *http.Request) {
+	var req model.SplitTenderRequest
+	if !decodeRequest(w, r, &req, "") {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), c.splitTenderTimeout(len(req.Legs)))
+	defer cancel()
+
+	response, err := c.service.SplitTender(ctx, req)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+
+	if response.Status != ports.SplitSucceeded {
+		utils.RespondWithJSON(w, http.StatusPaymentRequired, response)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
 func (c *PaymentController) GetPaymentIntent(w http.ResponseWriter, r *http.Request) {
 	var provider model.PaymentProvider = model.PaymentProvider(r.PathValue("provider"))
 
 	// Create base context from the HTTP request
     ctx := r.Context()
     
-    // Add payment-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    // Add payment-specific timeout
+    ctx, cancel := context.WithTimeout(ctx, c.timeouts.Request)
     defer cancel()
 
 	var req model.PaymentInfoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request")
+	if !decodeRequest(w, r, &req, "") {
 		return
 	}
 
 	response, err := c.service.GetPaymentIntent(ctx, provider, req.ID)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
@@ -107,23 +154,23 @@ func (c *PaymentController) ParseWebhook(w http.ResponseWriter, r *http.Request)
 	// Create base context from the HTTP request
     ctx := r.Context()
     
-    // Add payment-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    // Add payment-specific timeout
+    ctx, cancel := context.WithTimeout(ctx, c.timeouts.Webhook)
     defer cancel()
 
 	rawBody, err := io.ReadAll(r.Body)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid body")
+		respondWithError(w, r, bodyError(err))
 		return
 	}
 
 	event, err := c.service.ParseWebhook(ctx, provider, rawBody, r.Header)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
-	log.Printf("webhook--Event: %+v", event)
+	log.Printf("webhook--Event: %s for %s", event.Type, event.PaymentIntent)
 	utils.RespondWithJSON(w, http.StatusOK, event)
 }
 
@@ -133,25 +180,26 @@ func (c *PaymentController) GetUserPMethods(w http.ResponseWriter, r *http.Reque
 	// Create base context from the HTTP request
     ctx := r.Context()
     
-    // Add payment-service-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    // Add payment-specific timeout
+    ctx, cancel := context.WithTimeout(ctx, c.timeouts.Request)
     defer cancel()
 
 	response, err := c.service.GetUserPMethods(ctx, userId)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, r, err)
 		return
 	}
 
 	utils.RespondWithJSON(w, http.StatusOK, response)
 }
 
-func (c *PaymentController) GetHealthCheck(w http.ResponseWriter, r *http.Request) {
-    // Create the health check response
-    response := map[string]string{
-        "status": "OK",
-    }
-    
-    // Send JSON response with 200 status
-    utils.RespondWithJSON(w, http.StatusOK, response)
+// Circuit state and call counters per provider
+func (c *PaymentController) GetProviderStats(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, c.service.ProviderStats())
+}
+
+// Each leg is a charge and may need a refund when a later one fails, capped
+// so the response is written before the server's write timeout
+func (c *PaymentController) splitTenderTimeout(legs int) time.Duration {
+	return min(c.timeouts.Charge*time.Duration(2*legs), c.timeouts.SplitTender)
 }
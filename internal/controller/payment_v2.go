@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/validation"
+)
+
+// POST /v2/payment-intents, routed unless the body names a provider
+func (c *PaymentController) CreatePaymentIntentV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	var req model.CreatePaymentIntentRequest
+	if !decodeRequestV2(w, r, &req, validation.ScenarioIntent) {
+		return
+	}
+
+	if req.RememberMe == nil {
+		defaultRememberMe := false
+		req.RememberMe = &defaultRememberMe
+	}
+
+	var (
+		response *model.PaymentIntentResponse
+		err      error
+	)
+	if req.Provider == "" {
+		response, err = c.service.RoutePaymentIntent(ctx, req.PaymentIntentRequest)
+	} else {
+		response, err = c.service.CreatePaymentIntent(ctx, model.PaymentProvider(req.Provider), req.PaymentIntentRequest)
+	}
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusCreated, response)
+}
+
+// POST /v2/charges charges a saved payment method with the named provider
+func (c *PaymentController) ChargeV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Charge)
+	defer cancel()
+
+	var req model.CreatePaymentIntentRequest
+	if !decodeRequestV2(w, r, &req, validation.ScenarioCharge) {
+		return
+	}
+
+	response, err := c.service.ChargeClient(ctx, model.PaymentProvider(req.Provider), req.PaymentIntentRequest)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusCreated, response)
+}
+
+// POST /v2/split-tenders. A split that did not succeed is answered with 402
+// and the legs, so the client sees what was charged and unwound.
+func (c *PaymentController) SplitTenderV2(w http.ResponseWriter, r *http.Request) {
+	var req model.SplitTenderRequest
+	if !decodeRequestV2(w, r, &req, "") {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), c.splitTenderTimeout(len(req.Legs)))
+	defer cancel()
+
+	response, err := c.service.SplitTender(ctx, req)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	if response.Status != ports.SplitSucceeded {
+		respondWithResource(w, http.StatusPaymentRequired, response)
+		return
+	}
+	respondWithResource(w, http.StatusCreated, response)
+}
+
+// GET /v2/payment-intents/{id}
+func (c *PaymentController) GetPaymentIntentV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	response, err := c.service.FindPaymentIntent(ctx, r.PathValue("id"))
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusOK, response)
+}
+
+// POST /v2/payment-intents/{id}/refunds
+func (c *PaymentController) CreateRefundV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	var req model.CreateRefundRequest
+	if !decodeRequestV2(w, r, &req, "") {
+		return
+	}
+
+	response, err := c.service.RefundPaymentIntent(ctx, r.PathValue("id"), req)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithResource(w, http.StatusCreated, response)
+}
+
+// GET /v2/payment-intents/{id}/refunds
+func (c *PaymentController) ListRefundsV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	page, err := pageFromQuery(r)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	response, err := c.service.ListRefunds(ctx, r.PathValue("id"), page)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithList(w, response)
+}
+
+// GET /v2/customers/{id}/payment-methods
+func (c *PaymentController) ListPaymentMethodsV2(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeouts.Request)
+	defer cancel()
+
+	page, err := pageFromQuery(r)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	response, err := c.service.ListPaymentMethods(ctx, r.PathValue("id"), page)
+	if err != nil {
+		respondWithErrorV2(w, r, err)
+		return
+	}
+
+	respondWithList(w, response)
+}
+
+// GET /v2/providers, one entry per provider wrapped with a circuit breaker
+func (c *PaymentController) ListProvidersV2(w http.ResponseWriter, r *http.Request) {
+	respondWithList(w, &model.List[model.ProviderStats]{Data: c.service.ProviderStats()})
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/validation"
+)
+
+// Reads the JSON body into dst and validates it for scenario. Writes the error
+// response and returns false when the body is unusable.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst any, scenario string) bool {
+	if err := readRequest(r, dst, scenario); err != nil {
+		respondWithError(w, r, err)
+		return false
+	}
+	return true
+}
+
+func readRequest(r *http.Request, dst any, scenario string) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && dec.More() {
+		err = errors.New("body must contain a single JSON object")
+	}
+	if err != nil {
+		return bodyError(err)
+	}
+	return validation.Struct(dst, scenario)
+}
+
+// Turns a decoding failure into the error the client gets
+func bodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &tooLarge):
+		return model.NewError(model.CategoryTooLarge, "body_too_large", "body exceeds %d bytes", tooLarge.Limit)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fieldError(typeErr.Field, "type_invalid", fmt.Sprintf("%s must be a %s", typeErr.Field, jsonType(typeErr.Type.Kind().String())))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return fieldError(field, "unknown_field", fmt.Sprintf("%s is not a known field", field))
+	}
+	return &model.Error{Category: model.CategoryInvalidRequest, Code: "invalid_request", Message: "Invalid request", Cause: err}
+}
+
+func fieldError(field string, code string, message string) error {
+	return &model.Error{
+		Category: model.CategoryValidation,
+		Code:     validation.CodeValidationFailed,
+		Message:  message,
+		Fields:   []model.FieldError{{Field: field, Code: code, Message: message}},
+	}
+}
+
+// Names Go kinds the way JSON clients know them
+func jsonType(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "map", kind == "struct":
+		return "object"
+	case kind == "slice", kind == "array":
+		return "list"
+	case kind == "bool":
+		return "boolean"
+	}
+	return kind
+}
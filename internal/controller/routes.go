@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/danielmoisemontezima/zw-payment-service/api"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/service"
+)
+
+// Controllers routed by NewRouter. Fx and Fake are nil when disabled, without
+// Idempotency the Idempotency-Key header is ignored.
+type Controllers struct {
+	Payments    *PaymentController
+	Orders      *OrderController
+	Health      *HealthController
+	Fx          *FxController
+	Fake        *FakeController
+	Idempotency *service.IdempotencyService
+}
+
+// NewRouter maps every route to its handler. The v1 routes keep working,
+// marked deprecated in favour of /v2.
+func NewRouter(c Controllers) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/openapi.json", GetSpec)
+
+	r.Post("/payments/intent", Deprecated("/v2/payment-intents", c.Payments.RoutePaymentIntent))
+	r.Post("/payments/{provider}/intent", Deprecated("/v2/payment-intents", c.Payments.CreatePaymentIntent))
+	r.Post("/payments/{provider}/charge", Deprecated("/v2/charges", c.Payments.ChargeClient))
+	r.Post("/payments/split", Deprecated("/v2/split-tenders", c.Payments.SplitTender))
+	r.Post("/webhooks/{provider}", c.Payments.ParseWebhook)
+	r.Post("/orders", Deprecated("/v2/orders", c.Orders.CreateOrder))
+	r.Post("/orders/{id}/cancel", Deprecated("/v2/orders/{id}/cancel", c.Orders.CancelOrder))
+
+	r.Get("/livez", c.Health.GetLiveness)
+	r.Get("/readyz", c.Health.GetReadiness)
+	r.Get("/payments/health", c.Health.GetLiveness)
+	r.Get("/payments/{provider}/intent", Deprecated("/v2/payment-intents/{id}", c.Payments.GetPaymentIntent))
+	r.Get("/payments/methods/{id}", Deprecated("/v2/customers/{id}/payment-methods", c.Payments.GetUserPMethods))
+	r.Get("/payments/providers", Deprecated("/v2/providers", c.Payments.GetProviderStats))
+	r.Get("/orders/{id}", Deprecated("/v2/orders/{id}", c.Orders.GetOrder))
+
+	if c.Fx != nil {
+		r.Post("/fx/quotes", Deprecated("/v2/fx-quotes", c.Fx.CreateQuote))
+		r.Get("/fx/quotes/{id}", Deprecated("/v2/fx-quotes/{id}", c.Fx.GetQuote))
+	}
+
+	v2 := chi.NewRouter()
+	v2.NotFound(NotFoundV2)
+	// Grouped so the middleware runs after routing and sees the route pattern
+	v2.Group(func(v2 chi.Router) {
+		if c.Idempotency != nil {
+			v2.Use(Idempotent(c.Idempotency))
+		}
+		v2.Post("/payment-intents", c.Payments.CreatePaymentIntentV2)
+		v2.Get("/payment-intents/{id}", c.Payments.GetPaymentIntentV2)
+		v2.Post("/payment-intents/{id}/refunds", c.Payments.CreateRefundV2)
+		v2.Get("/payment-intents/{id}/refunds", c.Payments.ListRefundsV2)
+		v2.Post("/charges", c.Payments.ChargeV2)
+		v2.Post("/split-tenders", c.Payments.SplitTenderV2)
+		v2.Get("/customers/{id}/payment-methods", c.Payments.ListPaymentMethodsV2)
+		v2.Get("/providers", c.Payments.ListProvidersV2)
+		v2.Post("/orders", c.Orders.CreateOrderV2)
+		v2.Get("/orders/{id}", c.Orders.GetOrderV2)
+		v2.Post("/orders/{id}/cancel", c.Orders.CancelOrderV2)
+
+		if c.Fx != nil {
+			v2.Post("/fx-quotes", c.Fx.CreateQuoteV2)
+			v2.Get("/fx-quotes/{id}", c.Fx.GetQuoteV2)
+		}
+	})
+	r.Mount("/v2", v2)
+
+	if c.Fake != nil {
+		r.Post("/fake/intents/{id}/webhook", c.Fake.TriggerWebhook)
+	}
+	return r
+}
+
+// Serves the OpenAPI document describing these routes
+func GetSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(api.Spec)
+}
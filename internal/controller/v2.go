@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/pkg/utils"
+)
+
+// When the v1 routes were deprecated in favour of /v2
+var v1DeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// Deprecated marks a v1 route. Responses carry a Deprecation header and a
+// Link to successor, where {id} is replaced by the route's id.
+func Deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", v1DeprecatedAt.Unix())
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := strings.ReplaceAll(successor, "{id}", r.PathValue("id"))
+		w.Header().Set("Deprecation", deprecation)
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, link))
+		next(w, r)
+	}
+}
+
+func respondWithResource[T any](w http.ResponseWriter, status int, data T) {
+	utils.RespondWithJSON(w, status, model.Resource[T]{Data: data})
+}
+
+func respondWithList[T any](w http.ResponseWriter, list *model.List[T]) {
+	utils.RespondWithJSON(w, http.StatusOK, list)
+}
+
+// Writes err in the /v2 envelope with the status of its category
+func respondWithErrorV2(w http.ResponseWriter, r *http.Request, err error) {
+	status, apiErr := errorStatus(r, err)
+	utils.RespondWithJSON(w, status, model.ErrorEnvelope{Error: model.ErrorDetail{
+		Message:     apiErr.Message,
+		Code:        apiErr.Code,
+		Category:    string(apiErr.Category),
+		DeclineCode: apiErr.DeclineCode,
+		Fields:      apiErr.Fields,
+	}})
+}
+
+// decodeRequest for /v2 handlers
+func decodeRequestV2(w http.ResponseWriter, r *http.Request, dst any, scenario string) bool {
+	if err := readRequest(r, dst, scenario); err != nil {
+		respondWithErrorV2(w, r, err)
+		return false
+	}
+	return true
+}
+
+// Reads the limit and cursor query parameters of a list
+func pageFromQuery(r *http.Request) (model.Page, error) {
+	page := model.Page{Cursor: r.URL.Query().Get("cursor")}
+
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > model.MaxPageLimit {
+			return page, fieldError("limit", "invalid", fmt.Sprintf("limit must be a number from 1 to %d", model.MaxPageLimit))
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// Answers requests for /v2 paths that have no route
+func NotFoundV2(w http.ResponseWriter, r *http.Request) {
+	respondWithErrorV2(w, r, model.NotFound("route_not_found", "no route for %s %s", r.Method, r.URL.Path))
+}
@@ -3,36 +3,9 @@ package core
 import (
     "github.com/danielmoisemontezima/zw-payment-service/internal/ports"
     "github.com/danielmoisemontezima/zw-payment-service/internal/model"
-    "fmt"
+    "sort"
 )
 
-const (
-	TransactionRepo = "transactionRepo"
-	PaymentRepo    = "paymentRepo"
-	RefundRepo    = "refundRepo"
-)
-
-type RepositoryRegistry struct {
-    repo map[string]ports.ITransactionRepository
-}
-
-func NewRepositoryRegistry() *RepositoryRegistry {
-    return &RepositoryRegistry{
-        repo: make(map[string]ports.ITransactionRepository),
-    }
-}
-
-func (r *RepositoryRegistry) Register(repoTab string, repo ports.ITransactionRepository) {
-    r.repo[repoTab] = repo
-}
-
-func (r *RepositoryRegistry) Get(tab string) (ports.ITransactionRepository, error) {
-    if rp, exists := r.repo[tab]; exists {
-        return rp, nil
-    }
-    return nil, fmt.Errorf("repository %s not configured", tab)
-}
-
 type ProviderRegistry struct {
     processors map[model.PaymentProvider]ports.IPaymentProcessor
 }
@@ -47,9 +20,20 @@ func (r *ProviderRegistry) Register(provider model.PaymentProvider, processor po
     r.processors[provider] = processor
 }
 
+func (r *ProviderRegistry) All() []ports.IPaymentProcessor {
+    processors := make([]ports.IPaymentProcessor, 0, len(r.processors))
+    for _, p := range r.processors {
+        processors = append(processors, p)
+    }
+    sort.Slice(processors, func(i, j int) bool {
+        return processors[i].Name() < processors[j].Name()
+    })
+    return processors
+}
+
 func (r *ProviderRegistry) Get(provider model.PaymentProvider) (ports.IPaymentProcessor, error) {
     if p, exists := r.processors[provider]; exists {
         return p, nil
     }
-    return nil, fmt.Errorf("provider %s not configured", provider)
+    return nil, model.NotFound("provider_not_configured", "provider %s not configured", provider)
 }
\ No newline at end of file
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// Name recorded when no rule matched and the default provider was used
+const DefaultRoute = "default"
+
+// Router picks a provider for a payment from the configured rules
+type Router struct {
+	registry *ProviderRegistry
+	rules    []config.RoutingRule
+	fallback model.PaymentProvider
+	failover []model.PaymentProvider
+}
+
+// NewRouter fails when a rule, the failover group or the default names a
+// provider that is not registered, so a typo shows up at startup instead of
+// on a payment
+func NewRouter(registry *ProviderRegistry, cfg config.RoutingConfig) (*Router, error) {
+	for _, rule := range cfg.Rules {
+		if _, err := registry.Get(model.PaymentProvider(rule.Provider)); err != nil {
+			return nil, fmt.Errorf("routing rule %q: %w", rule.Name, err)
+		}
+	}
+	failover := make([]model.PaymentProvider, 0, len(cfg.Failover))
+	for _, name := range cfg.Failover {
+		if _, err := registry.Get(model.PaymentProvider(name)); err != nil {
+			return nil, fmt.Errorf("routing failover: %w", err)
+		}
+		failover = append(failover, model.PaymentProvider(name))
+	}
+	if cfg.Default != "" {
+		if _, err := registry.Get(model.PaymentProvider(cfg.Default)); err != nil {
+			return nil, fmt.Errorf("routing default: %w", err)
+		}
+	}
+
+	return &Router{
+		registry: registry,
+		rules:    cfg.Rules,
+		fallback: model.PaymentProvider(cfg.Default),
+		failover: failover,
+	}, nil
+}
+
+// Failover returns the providers to try, in order, when provider has an
+// outage. Empty unless provider is in the failover group.
+func (r *Router) Failover(provider model.PaymentProvider) []model.PaymentProvider {
+	if !slices.Contains(r.failover, provider) {
+		return nil
+	}
+
+	next := make([]model.PaymentProvider, 0, len(r.failover)-1)
+	for _, p := range r.failover {
+		if p != provider {
+			next = append(next, p)
+		}
+	}
+	return next
+}
+
+// Route returns the provider for req and the name of the rule that chose it
+func (r *Router) Route(req model.PaymentIntentRequest) (model.PaymentProvider, string, error) {
+	for _, rule := range r.rules {
+		if matches(rule, req) {
+			return model.PaymentProvider(rule.Provider), rule.Name, nil
+		}
+	}
+	if r.fallback == "" {
+		return "", "", model.Invalid("no_route", "no routing rule matches this payment and no default provider is configured")
+	}
+	return r.fallback, DefaultRoute, nil
+}
+
+func matches(rule config.RoutingRule, req model.PaymentIntentRequest) bool {
+	switch {
+	case len(rule.Currencies) > 0 && !containsFold(rule.Currencies, req.Currency):
+		return false
+	case rule.MinAmount > 0 && req.Amount < rule.MinAmount:
+		return false
+	case rule.MaxAmount > 0 && req.Amount > rule.MaxAmount:
+		return false
+	case len(rule.Countries) > 0 && !containsFold(rule.Countries, req.Country):
+		return false
+	case len(rule.PaymentMethods) > 0 && !containsFold(rule.PaymentMethods, req.PaymentMethod):
+		return false
+	case len(rule.Merchants) > 0 && !slices.Contains(rule.Merchants, req.Merchant):
+		return false
+	case rule.Percent > 0 && bucket(rule.Name, req.CustomerID) >= rule.Percent:
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+}
+
+// Places a customer in one of 100 buckets per rule, so a customer keeps
+// getting the same side of a split and each rule splits independently
+func bucket(rule string, customerID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(rule))
+	h.Write([]byte{0})
+	h.Write([]byte(customerID))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,147 @@
+package core_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/config"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/core"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// A registry with fakes standing in for every provider the rules name
+func newRouter(t *testing.T, cfg config.RoutingConfig) *core.Router {
+	t.Helper()
+	registry := core.NewProviderRegistry()
+	for _, name := range []model.PaymentProvider{"stripe", "paypal", "adyen"} {
+		registry.Register(name, adapters.NewFakeAdapter("whsec_test"))
+	}
+
+	router, err := core.NewRouter(registry, cfg)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	return router
+}
+
+func TestRouterRules(t *testing.T) {
+	router := newRouter(t, config.RoutingConfig{
+		Default: "stripe",
+		Rules: []config.RoutingRule{
+			{Name: "jpy", Provider: "adyen", Currencies: []string{"JPY"}},
+			{Name: "large-eur", Provider: "paypal", Currencies: []string{"eur"}, MinAmount: 100000},
+			{Name: "small", Provider: "adyen", MaxAmount: 100},
+			{Name: "dutch-ideal", Provider: "paypal", Countries: []string{"NL"}, PaymentMethods: []string{"ideal"}},
+			{Name: "acme", Provider: "adyen", Merchants: []string{"acme"}},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		req      model.PaymentIntentRequest
+		provider model.PaymentProvider
+		rule     string
+	}{
+		{name: "currency ignores case", req: model.PaymentIntentRequest{Currency: "jpy", Amount: 5000}, provider: "adyen", rule: "jpy"},
+		{name: "min amount reached", req: model.PaymentIntentRequest{Currency: "EUR", Amount: 100000}, provider: "paypal", rule: "large-eur"},
+		{name: "min amount missed", req: model.PaymentIntentRequest{Currency: "eur", Amount: 99999}, provider: "stripe", rule: core.DefaultRoute},
+		{name: "max amount reached", req: model.PaymentIntentRequest{Currency: "usd", Amount: 100}, provider: "adyen", rule: "small"},
+		{name: "country and method", req: model.PaymentIntentRequest{Currency: "usd", Amount: 500, Country: "nl", PaymentMethod: "iDEAL"}, provider: "paypal", rule: "dutch-ideal"},
+		{name: "country without method", req: model.PaymentIntentRequest{Currency: "usd", Amount: 500, Country: "NL", PaymentMethod: "card"}, provider: "stripe", rule: core.DefaultRoute},
+		{name: "missing country", req: model.PaymentIntentRequest{Currency: "usd", Amount: 500, PaymentMethod: "ideal"}, provider: "stripe", rule: core.DefaultRoute},
+		{name: "merchant", req: model.PaymentIntentRequest{Currency: "usd", Amount: 500, Merchant: "acme"}, provider: "adyen", rule: "acme"},
+		{name: "merchant is case sensitive", req: model.PaymentIntentRequest{Currency: "usd", Amount: 500, Merchant: "ACME"}, provider: "stripe", rule: core.DefaultRoute},
+		{name: "first match wins", req: model.PaymentIntentRequest{Currency: "jpy", Amount: 50, Merchant: "acme"}, provider: "adyen", rule: "jpy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, rule, err := router.Route(tt.req)
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if provider != tt.provider || rule != tt.rule {
+				t.Fatalf("expected %s via %s, got %s via %s", tt.provider, tt.rule, provider, rule)
+			}
+		})
+	}
+}
+
+func TestRouterWithoutDefault(t *testing.T) {
+	router := newRouter(t, config.RoutingConfig{
+		Rules: []config.RoutingRule{{Name: "eur", Provider: "paypal", Currencies: []string{"eur"}}},
+	})
+
+	_, _, err := router.Route(model.PaymentIntentRequest{Currency: "usd", Amount: 500})
+	var merr *model.Error
+	if !errors.As(err, &merr) || merr.Code != "no_route" {
+		t.Fatalf("expected no_route, got %v", err)
+	}
+}
+
+func TestRouterPercentSplit(t *testing.T) {
+	router := newRouter(t, config.RoutingConfig{
+		Default: "stripe",
+		Rules: []config.RoutingRule{
+			{Name: "paypal-half", Provider: "paypal", Percent: 50},
+		},
+	})
+	everyone := newRouter(t, config.RoutingConfig{
+		Default: "stripe",
+		Rules: []config.RoutingRule{
+			{Name: "paypal-all", Provider: "paypal", Percent: 100},
+		},
+	})
+
+	split := 0
+	for i := 0; i < 1000; i++ {
+		req := model.PaymentIntentRequest{CustomerID: fmt.Sprintf("cus_%d", i), Currency: "usd", Amount: 500}
+
+		first, _, _ := router.Route(req)
+		for j := 0; j < 3; j++ {
+			if again, _, _ := router.Route(req); again != first {
+				t.Fatalf("expected %s to stay on %s, got %s", req.CustomerID, first, again)
+			}
+		}
+		if first == "paypal" {
+			split++
+		}
+
+		if provider, _, _ := everyone.Route(req); provider != "paypal" {
+			t.Fatalf("expected 100%% to take %s, got %s", req.CustomerID, provider)
+		}
+	}
+
+	if split < 400 || split > 600 {
+		t.Fatalf("expected about half of the customers on paypal, got %d of 1000", split)
+	}
+}
+
+func TestRouterFailover(t *testing.T) {
+	router := newRouter(t, config.RoutingConfig{Failover: []string{"stripe", "adyen", "paypal"}})
+
+	if got := router.Failover("adyen"); !reflect.DeepEqual(got, []model.PaymentProvider{"stripe", "paypal"}) {
+		t.Fatalf("expected the rest of the group in order, got %v", got)
+	}
+
+	other := newRouter(t, config.RoutingConfig{Failover: []string{"stripe", "adyen"}})
+	if got := other.Failover("paypal"); len(got) != 0 {
+		t.Fatalf("expected no failover outside the group, got %v", got)
+	}
+}
+
+func TestNewRouterRejectsUnknownProviders(t *testing.T) {
+	configs := map[string]config.RoutingConfig{
+		"rule":     {Rules: []config.RoutingRule{{Name: "typo", Provider: "strpe"}}},
+		"failover": {Failover: []string{"stripe", "strpe"}},
+		"default":  {Default: "strpe"},
+	}
+	for name, cfg := range configs {
+		if _, err := core.NewRouter(core.NewProviderRegistry(), cfg); err == nil {
+			t.Fatalf("expected an unknown provider in the %s to fail", name)
+		}
+	}
+}
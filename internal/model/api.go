@@ -0,0 +1,53 @@
+package model
+
+// Body of every successful /v2 response that holds one resource
+type Resource[T any] struct {
+	Data T `json:"data"`
+}
+
+// Body of every /v2 list. NextCursor is set when HasMore is, pass it as
+// cursor to get the next page.
+type List[T any] struct {
+	Data       []T    `json:"data"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Body of every /v2 error response
+type ErrorEnvelope struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Message     string       `json:"message"`
+	Code        string       `json:"code"`
+	Category    string       `json:"category"`
+	DeclineCode string       `json:"decline_code,omitempty"`
+	Fields      []FieldError `json:"fields,omitempty"`
+}
+
+// Page of a /v2 list, read from the limit and cursor query parameters
+type Page struct {
+	Limit int
+	// ID of the last item of the previous page, empty for the first page
+	Cursor string
+}
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Creates a payment intent through /v2. The provider is picked by the routing
+// rules unless Provider names one.
+type CreatePaymentIntentRequest struct {
+	Provider string `json:"provider" validate:"required_on=charge"`
+	PaymentIntentRequest
+}
+
+// Refunds (part of) a payment intent through /v2, the whole remaining
+// balance when Amount is zero
+type CreateRefundRequest struct {
+	Amount int64  `json:"amount" validate:"min=1"`
+	Reason string `json:"reason" validate:"maxlen=500"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+// A charge persisted before the provider is called, so a charge that
+// succeeds but cannot be recorded is never lost
+type ChargeAttempt struct {
+	ID                string
+	Provider          string
+	CustomerID        string
+	OrderID           string
+	Amount            int64
+	Currency          string
+	PaymentMethodID   string
+	SavePaymentMethod bool
+	PaymentIntentID   string
+	Status            string
+	LastError         string
+	Metadata          map[string]string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// What the recovery worker did with one orphaned attempt
+type RecoveryResult struct {
+	AttemptID       string `json:"attempt_id"`
+	PaymentIntentID string `json:"payment_intent_id"`
+	PreviousStatus  string `json:"previous_status"`
+	NewStatus       string `json:"new_status"`
+	Error           string `json:"error,omitempty"`
+}
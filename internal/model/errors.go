@@ -0,0 +1,79 @@
+package model
+
+import "fmt"
+
+// What kind of failure an Error is, each category has one HTTP status
+type ErrorCategory string
+
+const (
+	// The request could not be read at all, e.g. malformed JSON
+	CategoryInvalidRequest ErrorCategory = "invalid_request"
+	CategoryValidation     ErrorCategory = "validation"
+	CategoryDecline        ErrorCategory = "decline"
+	CategoryNotFound       ErrorCategory = "not_found"
+	CategoryConflict       ErrorCategory = "conflict"
+	// The body is larger than the server accepts
+	CategoryTooLarge ErrorCategory = "request_too_large"
+	// Nothing reached the provider, the same request can be retried later
+	CategoryProviderUnavailable ErrorCategory = "provider_unavailable"
+	// The provider failed and the outcome may be unknown
+	CategoryProviderError ErrorCategory = "provider_error"
+	CategoryInternal      ErrorCategory = "internal"
+)
+
+// Error is a failure reported to API clients. Code is stable and meant for
+// programs, Message is safe to show and Cause is only logged.
+type Error struct {
+	Category ErrorCategory
+	Code     string
+	Message  string
+	// Set for declines, the provider's reason such as "insufficient_funds"
+	DeclineCode string
+	// Every invalid field when a request failed validation
+	Fields []FieldError
+	Cause  error
+}
+
+// One invalid field of a request. Field is the JSON path, e.g. "legs[1].amount".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Cause }
+
+func NewError(category ErrorCategory, code string, format string, args ...any) *Error {
+	return &Error{Category: category, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Invalid reports a request that is well formed but cannot be accepted
+func Invalid(code string, format string, args ...any) *Error {
+	return NewError(CategoryValidation, code, format, args...)
+}
+
+func NotFound(code string, format string, args ...any) *Error {
+	return NewError(CategoryNotFound, code, format, args...)
+}
+
+// Conflict reports a request the resource's current state does not allow
+func Conflict(code string, format string, args ...any) *Error {
+	return NewError(CategoryConflict, code, format, args...)
+}
+
+// Body of every error response. Error holds the message, as it did before
+// the other fields were added.
+type ErrorResponse struct {
+	Error       string       `json:"error"`
+	Code        string       `json:"code"`
+	Category    string       `json:"category"`
+	DeclineCode string       `json:"decline_code,omitempty"`
+	Fields      []FieldError `json:"fields,omitempty"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+)
+
+// Units of Quote one unit of Base buys, as a decimal string to keep precision
+type FxRate struct {
+	Base      string
+	Quote     string
+	Rate      string
+	Source    string
+	FetchedAt time.Time
+}
+
+// A locked conversion from what we settle in to what the customer pays in.
+// A quote pays for one payment intent before it expires.
+type FxQuote struct {
+	ID                  string
+	CustomerID          string
+	SettlementAmount    int64
+	SettlementCurrency  string
+	PresentmentAmount   int64
+	PresentmentCurrency string
+	Rate                string
+	Status              string
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+	CreatedAt           time.Time
+}
+
+type FxQuoteRequest struct {
+	CustomerID string `json:"customer_id" validate:"required,maxlen=50"`
+	// Amount and currency we settle in, currency defaults to the configured one
+	Amount              int64  `json:"amount" validate:"required,min=1"`
+	Currency            string `json:"currency" validate:"currency"`
+	PresentmentCurrency string `json:"presentment_currency" validate:"required,currency"`
+}
+
+type FxQuoteResponse struct {
+	ID                  string    `json:"id"`
+	CustomerID          string    `json:"customer_id"`
+	Amount              int64     `json:"amount"`
+	Currency            string    `json:"currency"`
+	PresentmentAmount   int64     `json:"presentment_amount"`
+	PresentmentCurrency string    `json:"presentment_currency"`
+	PresentmentDisplay  string    `json:"presentment_display"`
+	Rate                string    `json:"rate"`
+	Status              string    `json:"status"`
+	ExpiresAt           time.Time `json:"expires_at"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// The outcome of a /v2 POST, replayed when its Idempotency-Key is sent again
+type IdempotencyRecord struct {
+	Key string
+	// Hash of the method, path and body the key was first used with
+	Fingerprint string
+	// Zero while the first request is still running
+	ResponseStatus int
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// An ISO 4217 currency. Exponent is the number of minor unit digits, so 2 for
+// EUR (cents), 0 for JPY and 3 for KWD.
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+// Provider bounds on a single charge, in minor units. Step is the multiple
+// amounts must be in, 0 when any amount is accepted.
+type AmountLimits struct {
+	Min  int64
+	Max  int64
+	Step int64
+}
+
+// Active ISO 4217 codes whose minor unit is not 2
+var currencyExponents = map[string]int{
+	"bif": 0, "clp": 0, "djf": 0, "gnf": 0, "isk": 0, "jpy": 0, "kmf": 0, "krw": 0,
+	"pyg": 0, "rwf": 0, "ugx": 0, "uyi": 0, "vnd": 0, "vuv": 0, "xaf": 0, "xof": 0,
+	"xpf": 0,
+	"bhd": 3, "iqd": 3, "jod": 3, "kwd": 3, "lyd": 3, "omr": 3, "tnd": 3,
+	"clf": 4, "uyw": 4,
+}
+
+// Active ISO 4217 codes with two minor unit digits
+var twoDecimalCurrencies = strings.Fields(`
+	aed afn all amd ang aoa ars aud awg azn bam bbd bdt bgn bmd bnd bob bov brl
+	bsd btn bwp byn bzd cad cdf che chf chw cny cop cou crc cup cve czk dkk dop
+	dzd egp ern etb eur fjd fkp gbp gel ghs gip gmd gtq gyd hkd hnl htg huf idr
+	ils inr irr jmd kes kgs khr kpw kyd kzt lak lbp lkr lrd lsl mad mdl mga mkd
+	mmk mnt mop mru mur mvr mwk mxn mxv myr mzn nad ngn nio nok npr nzd pab pen
+	pgk php pkr pln qar ron rsd rub sar sbd scr sdg sek sgd shp sle sos srd ssp
+	stn svc syp szl thb tjs tmt top try ttd twd tzs uah usd usn uyu uzs ves wst
+	xcd yer zar zmw zwl
+`)
+
+func init() {
+	for _, code := range twoDecimalCurrencies {
+		currencyExponents[code] = 2
+	}
+}
+
+// LookupCurrency finds an ISO 4217 code, case-insensitively
+func LookupCurrency(code string) (Currency, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	exponent, ok := currencyExponents[code]
+	if !ok {
+		return Currency{}, Invalid("currency_invalid", "unknown currency %q, expected an ISO 4217 code", code)
+	}
+	return Currency{Code: code, Exponent: exponent}, nil
+}
+
+// An amount in minor units of an ISO 4217 currency
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func NewMoney(amount int64, currency string) (Money, error) {
+	cur, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: cur}, nil
+}
+
+// ParseMoney reads a decimal amount in major units, "12.50" EUR is 1250. More
+// decimals than the currency has are rejected rather than rounded.
+func ParseMoney(value string, currency string) (Money, error) {
+	cur, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(value, "-"), ".")
+	if whole == "" || len(frac) > cur.Exponent || strings.Trim(whole+frac, "0123456789") != "" {
+		return Money{}, Invalid("amount_invalid", "%q is not a valid %s amount with at most %d decimals", value, strings.ToUpper(cur.Code), cur.Exponent)
+	}
+
+	digits := whole + frac + strings.Repeat("0", cur.Exponent-len(frac))
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, Invalid("amount_invalid", "%q is out of range for %s", value, strings.ToUpper(cur.Code))
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: cur}, nil
+}
+
+// Decimal renders the amount in major units, 1250 EUR is "12.50"
+func (m Money) Decimal() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	if m.Currency.Exponent == 0 {
+		return sign + digits
+	}
+	if pad := m.Currency.Exponent + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	split := len(digits) - m.Currency.Exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// String renders the amount with its code, "12.50 EUR"
+func (m Money) String() string {
+	return m.Decimal() + " " + strings.ToUpper(m.Currency.Code)
+}
+
+// FormatAmount renders minor units for display, falling back to the raw
+// amount for codes outside ISO 4217
+func FormatAmount(amount int64, currency string) string {
+	m, err := NewMoney(amount, currency)
+	if err != nil {
+		return fmt.Sprintf("%d %s", amount, strings.ToUpper(strings.TrimSpace(currency)))
+	}
+	return m.String()
+}
+
+// Check validates the amount against provider limits
+func (m Money) Check(limits AmountLimits) error {
+	if m.Amount <= 0 {
+		return Invalid("amount_invalid", "amount must be positive")
+	}
+	if limits.Min > 0 && m.Amount < limits.Min {
+		return Invalid("amount_too_small", "amount %s is below the minimum of %s", m, Money{Amount: limits.Min, Currency: m.Currency})
+	}
+	if limits.Max > 0 && m.Amount > limits.Max {
+		return Invalid("amount_too_large", "amount %s exceeds the maximum of %s", m, Money{Amount: limits.Max, Currency: m.Currency})
+	}
+	if limits.Step > 1 && m.Amount%limits.Step != 0 {
+		return Invalid("amount_invalid", "amount %s must be a multiple of %s", m, Money{Amount: limits.Step, Currency: m.Currency})
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package model_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+func errorCode(err error) string {
+	var merr *model.Error
+	if errors.As(err, &merr) {
+		return merr.Code
+	}
+	return ""
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		amount   int64
+		code     string
+	}{
+		{value: "12.50", currency: "EUR", amount: 1250},
+		{value: "12.5", currency: "eur", amount: 1250},
+		{value: "12", currency: "EUR", amount: 1200},
+		{value: " 0.05 ", currency: "EUR", amount: 5},
+		{value: "007.50", currency: "EUR", amount: 750},
+		{value: "-3.10", currency: "EUR", amount: -310},
+		{value: "12.505", currency: "EUR", code: "amount_invalid"},
+		{value: "1500", currency: "JPY", amount: 1500},
+		{value: "1500.0", currency: "JPY", code: "amount_invalid"},
+		{value: "1.005", currency: "KWD", amount: 1005},
+		{value: "0.005", currency: "KWD", amount: 5},
+		{value: "1.0005", currency: "KWD", code: "amount_invalid"},
+		{value: "-", currency: "EUR", code: "amount_invalid"},
+		{value: "", currency: "EUR", code: "amount_invalid"},
+		{value: ".50", currency: "EUR", code: "amount_invalid"},
+		{value: "+1", currency: "EUR", code: "amount_invalid"},
+		{value: "1,50", currency: "EUR", code: "amount_invalid"},
+		{value: "1e3", currency: "JPY", code: "amount_invalid"},
+		{value: "92233720368547758.07", currency: "EUR", amount: 9223372036854775807},
+		{value: "92233720368547758.08", currency: "EUR", code: "amount_invalid"},
+		{value: "9223372036854775808", currency: "JPY", code: "amount_invalid"},
+		{value: "12.50", currency: "EURO", code: "currency_invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.currency, func(t *testing.T) {
+			m, err := model.ParseMoney(tt.value, tt.currency)
+			if tt.code != "" {
+				if errorCode(err) != tt.code {
+					t.Fatalf("expected %s, got %v", tt.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney: %v", err)
+			}
+			if m.Amount != tt.amount {
+				t.Fatalf("expected %d, got %d", tt.amount, m.Amount)
+			}
+		})
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		decimal  string
+	}{
+		{amount: 1250, currency: "eur", decimal: "12.50"},
+		{amount: 5, currency: "eur", decimal: "0.05"},
+		{amount: 50, currency: "eur", decimal: "0.50"},
+		{amount: 0, currency: "eur", decimal: "0.00"},
+		{amount: -5, currency: "eur", decimal: "-0.05"},
+		{amount: 1500, currency: "jpy", decimal: "1500"},
+		{amount: 5, currency: "jpy", decimal: "5"},
+		{amount: 5, currency: "kwd", decimal: "0.005"},
+		{amount: 1005, currency: "kwd", decimal: "1.005"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.decimal+" "+tt.currency, func(t *testing.T) {
+			m, err := model.NewMoney(tt.amount, tt.currency)
+			if err != nil {
+				t.Fatalf("NewMoney: %v", err)
+			}
+			if got := m.Decimal(); got != tt.decimal {
+				t.Fatalf("expected %q, got %q", tt.decimal, got)
+			}
+
+			back, err := model.ParseMoney(m.Decimal(), tt.currency)
+			if err != nil {
+				t.Fatalf("ParseMoney(%q): %v", m.Decimal(), err)
+			}
+			if back.Amount != tt.amount {
+				t.Fatalf("expected %q to parse back to %d, got %d", m.Decimal(), tt.amount, back.Amount)
+			}
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	if got := model.FormatAmount(1250, "eur"); got != "12.50 EUR" {
+		t.Fatalf("expected 12.50 EUR, got %q", got)
+	}
+	if got := model.FormatAmount(1250, "xyz"); got != "1250 XYZ" {
+		t.Fatalf("expected the raw amount for an unknown code, got %q", got)
+	}
+}
+
+func TestMoneyCheck(t *testing.T) {
+	limits := model.AmountLimits{Min: 50, Max: 10000, Step: 10}
+
+	tests := []struct {
+		name   string
+		amount int64
+		limits model.AmountLimits
+		code   string
+	}{
+		{name: "within limits", amount: 1000, limits: limits},
+		{name: "at the minimum", amount: 50, limits: limits},
+		{name: "at the maximum", amount: 10000, limits: limits},
+		{name: "below the minimum", amount: 40, limits: limits, code: "amount_too_small"},
+		{name: "above the maximum", amount: 10010, limits: limits, code: "amount_too_large"},
+		{name: "off step", amount: 1005, limits: limits, code: "amount_invalid"},
+		{name: "zero", amount: 0, limits: limits, code: "amount_invalid"},
+		{name: "negative", amount: -100, code: "amount_invalid"},
+		{name: "no limits", amount: 1, limits: model.AmountLimits{}},
+		{name: "step of one", amount: 7, limits: model.AmountLimits{Step: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := model.NewMoney(tt.amount, "eur")
+			if err != nil {
+				t.Fatalf("NewMoney: %v", err)
+			}
+			err = m.Check(tt.limits)
+			if errorCode(err) != tt.code {
+				t.Fatalf("expected %q, got %v", tt.code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+)
+
+// What a customer owes for one checkout, paid by one or more transactions
+type Order struct {
+	ID                string
+	InternalReference string
+	CustomerID        string
+	AmountDue         int64
+	Currency          string
+	Status            string
+	Metadata          map[string]string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type OrderRequest struct {
+	CustomerID string            `json:"customer_id" validate:"required,maxlen=50"`
+	AmountDue  int64             `json:"amount_due" validate:"required,min=1"`
+	Currency   string            `json:"currency" validate:"required,currency"`
+	Metadata   map[string]string `json:"metadata" validate:"metadata"`
+}
+
+type OrderResponse struct {
+	ID                string            `json:"id"`
+	InternalReference string            `json:"internal_reference"`
+	CustomerID        string            `json:"customer_id"`
+	AmountDue         int64             `json:"amount_due"`
+	AmountPaid        int64             `json:"amount_paid"`
+	Currency          string            `json:"currency"`
+	Status            string            `json:"status"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	Attempts          []OrderAttempt    `json:"attempts"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+// One transaction made towards an order, newest first in OrderResponse
+type OrderAttempt struct {
+	TransactionID     string    `json:"transaction_id"`
+	InternalReference string    `json:"internal_reference"`
+	PaymentIntentID   string    `json:"payment_intent_id"`
+	Amount            int64     `json:"amount"`
+	Refunded          int64     `json:"refunded"`
+	Status            string    `json:"status"`
+	CreatedAt         time.Time `json:"created_at"`
+}
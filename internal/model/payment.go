@@ -3,25 +3,52 @@ package model
 type PaymentProvider string
 
 type PaymentInfoRequest struct {
-	ID string `json:"id"`
+	ID string `json:"id" validate:"required"`
 }
 
 type PaymentIntentRequest struct {
-	Amount     	int64				`json:"amount"`
-	Currency   	string 				`json:"currency"`
-	CustomerID 	string				`json:"customer_id"`
-	Token	   	string				`json:"token"`
+	Amount     	int64				`json:"amount" validate:"required_without=fx_quote,min=1"`
+	Currency   	string 				`json:"currency" validate:"required_without=fx_quote,currency"`
+	CustomerID 	string				`json:"customer_id" validate:"required,maxlen=50"`
+	Token	   	string				`json:"token" validate:"required_on=charge"`
 	RememberMe	*bool				`json:"remember_me"`
-	Metadata   	map[string]string	`json:"metadata"`
-	PaymentMethod string				`json:"payment_method"`
+	Metadata   	map[string]string	`json:"metadata" validate:"metadata"`
+	PaymentMethod string				`json:"payment_method" validate:"required_on=intent"`
+	OrderID		string				`json:"order_id" validate:"maxlen=500"`
+	// ID of one of our orders this payment goes towards, OrderID is the caller's own
+	Order		string				`json:"order" validate:"uuid"`
+	// Locked FX quote; the customer pays its presentment amount and currency
+	FxQuote		string				`json:"fx_quote" validate:"uuid"`
+	// Routing inputs for /payments/intent: ISO 3166 country and the selling merchant
+	Country		string				`json:"country" validate:"maxlen=2"`
+	Merchant	string				`json:"merchant"`
+	Description	string				`json:"description" validate:"maxlen=1000"`
+	ReceiptEmail	string				`json:"receipt_email" validate:"email"`
+	StatementDescriptorSuffix	string	`json:"statement_descriptor_suffix" validate:"descriptor"`
+	// Set by the service; adapters use it as idempotency key and charge reference
+	AttemptID	string				`json:"-"`
+	// Our internal_reference, sent to the provider so webhooks can be matched before the intent is stored
+	Reference	string				`json:"-"`
 }
 
 type PaymentIntentResponse struct {
 	ID           string		`json:"id"`
 	Amount       int64		`json:"amount"`
+	// Amount in major units with the currency code, e.g. "12.50 EUR"
+	AmountDisplay string	`json:"amount_display"`
 	Currency     string		`json:"currency"`
 	Status       string		`json:"status"`
 	ClientSecret string		`json:"client_secret"`
+	Metadata     map[string]string	`json:"metadata,omitempty"`
+	// What we receive, equal to amount and currency unless paid through an FX quote
+	SettlementAmount	int64	`json:"settlement_amount,omitempty"`
+	SettlementCurrency	string	`json:"settlement_currency,omitempty"`
+	FxRate				string	`json:"fx_rate,omitempty"`
+	Provider			string	`json:"provider,omitempty"`
+	// Rule that picked the provider, set for /payments/intent
+	RoutingRule			string	`json:"routing_rule,omitempty"`
+	// Providers tried for a direct charge, in order
+	Attempts			[]ProviderAttempt	`json:"attempts,omitempty"`
 }
 
 type PaymentProcessorResponse struct {
@@ -34,12 +61,16 @@ type PaymentProcessorResponse struct {
 	PaymentMethodType	string
 	PaymentProvider		string
 	PaymentMethodStatus	string
+	Metadata			map[string]string
 }
 
 type PaymentEvent struct {
 	Type    			string		`json:"type"`
 	PaymentIntent		string		`json:"payment_intent"`
 	PaymentMethod		string		`json:"payment_method"`
+	// internal_reference from the intent metadata, when we created the intent
+	Reference			string		`json:"reference,omitempty"`
+	Metadata			map[string]string	`json:"metadata,omitempty"`
 	Payload 			interface{}
 }
 
@@ -49,4 +80,10 @@ type PaymentMethodResponse struct {
 	PaymentProvider		string		`json:"payment_provider"`
 	PaymentMethodType	string		`json:"payment_method_type"`
 	PaymentMethodStatus	string		`json:"payment_method_status"`
-}
\ No newline at end of file
+}
+// Triggers a signed webhook from the fake provider
+type FakeWebhookRequest struct {
+	Outcome			string		`json:"outcome" validate:"required,oneof=succeeded failed canceled"`
+	PaymentMethod	string		`json:"payment_method"`
+	Delay			string		`json:"delay"`
+}
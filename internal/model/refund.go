@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+)
+
+type Refund struct {
+	ID                string
+	InternalReference string
+	TransactionID     string
+	Amount            int64
+	Reason            string
+	ProviderRefundID  string
+	RefundStatus      string
+	ProcessedAt       time.Time
+}
+
+type RefundRequest struct {
+	TransactionID string `json:"transaction_id" validate:"required,uuid"`
+	Amount        int64  `json:"amount" validate:"min=1"`
+	Reason        string `json:"reason" validate:"maxlen=500"`
+}
+
+type RefundResponse struct {
+	ID                string `json:"id"`
+	InternalReference string `json:"internal_reference"`
+	TransactionID     string `json:"transaction_id"`
+	Amount            int64  `json:"amount"`
+	AmountDisplay     string `json:"amount_display"`
+	Currency          string `json:"currency"`
+	Status            string `json:"status"`
+	ProviderRefundID  string `json:"provider_refund_id"`
+}
+
+// Request sent to a provider to refund (part of) a payment intent
+type ProviderRefundRequest struct {
+	PaymentIntentID string
+	Amount          int64
+	Reason          string
+}
+
+type ProviderRefundResponse struct {
+	ID     string
+	Amount int64
+	Status string
+}
+
+// Outcome of reconciling a transaction's status with its provider
+type ResyncResult struct {
+	PaymentIntentID string `json:"payment_intent_id"`
+	PreviousStatus  string `json:"previous_status"`
+	ProviderStatus  string `json:"provider_status"`
+	NewStatus       string `json:"new_status"`
+	Changed         bool   `json:"changed"`
+}
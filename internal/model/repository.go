@@ -14,12 +14,42 @@ type Transaction struct {
 	PaymentIntentID  	string
 	TxStatus	        string
 	CustomerID      	string
-	PaymentMethodID		string
-	PaymentProvider 	string
-	PaymentMethodType 	string
-	PaymentMethodStatus	string
+	// Our order this attempt pays towards, empty for standalone payments
+	OrderID				string
 	SavePaymentMethod	*bool
+	// What we receive for Amount/Currency. Equal to them, at rate 1, unless
+	// the customer paid in another currency through an FX quote
+	SettlementAmount	int64
+	SettlementCurrency	string
+	FxRate				string
+	FxQuoteID			string
+	// Provider the intent was created with, and the routing rule that picked
+	// it when the caller left the choice to us
+	Provider			string
+	RoutingRule			string
+	// Every provider tried for a direct charge, in order, when one failed over
+	ProviderAttempts	[]ProviderAttempt
 	CreatedAt			time.Time
 	UpdatedAt			time.Time
 	Metadata			map[string]string       
-}
\ No newline at end of file
+}
+
+// One provider tried for a direct charge. Outcome is charged, or the class of
+// the error that ended the try.
+type ProviderAttempt struct {
+	Provider	string	`json:"provider"`
+	AttemptID	string	`json:"attempt_id"`
+	Outcome		string	`json:"outcome"`
+	Error		string	`json:"error,omitempty"`
+}
+
+// A customer's saved payment method, keyed by (CustomerID, ID)
+type PaymentMethod struct {
+	ID					string
+	CustomerID			string
+	Provider			string
+	Type				string
+	Status				string
+	CreatedAt			time.Time
+	UpdatedAt			time.Time
+}
@@ -0,0 +1,38 @@
+package model
+
+// Pays one order with several payment methods, charged in order
+type SplitTenderRequest struct {
+	CustomerID                string            `json:"customer_id" validate:"required,maxlen=50"`
+	Currency                  string            `json:"currency" validate:"required,currency"`
+	// Existing order to pay, one is created for the sum of the legs otherwise
+	Order                     string            `json:"order" validate:"uuid"`
+	Legs                      []TenderLeg       `json:"legs" validate:"required,maxlen=5,dive"`
+	Metadata                  map[string]string `json:"metadata" validate:"metadata"`
+	OrderID                   string            `json:"order_id" validate:"maxlen=500"`
+	Description               string            `json:"description" validate:"maxlen=1000"`
+	ReceiptEmail              string            `json:"receipt_email" validate:"email"`
+	StatementDescriptorSuffix string            `json:"statement_descriptor_suffix" validate:"descriptor"`
+}
+
+type TenderLeg struct {
+	Provider PaymentProvider `json:"provider" validate:"required"`
+	Token    string          `json:"token" validate:"required"`
+	Amount   int64           `json:"amount" validate:"required,min=1"`
+}
+
+type SplitTenderResponse struct {
+	Order    string            `json:"order"`
+	Status   string            `json:"status"`
+	Amount   int64             `json:"amount"`
+	Currency string            `json:"currency"`
+	Legs     []TenderLegResult `json:"legs"`
+	Error    string            `json:"error,omitempty"`
+}
+
+type TenderLegResult struct {
+	Provider        PaymentProvider `json:"provider"`
+	Amount          int64           `json:"amount"`
+	Status          string          `json:"status"`
+	PaymentIntentID string          `json:"payment_intent_id,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
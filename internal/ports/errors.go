@@ -0,0 +1,71 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// Stable codes for errors that do not carry their own
+const (
+	CodeNotFound            = "not_found"
+	CodeConflict            = "conflict"
+	CodeCardDeclined        = "card_declined"
+	CodeProviderRejected    = "provider_rejected"
+	CodeProviderUnavailable = "provider_unavailable"
+	CodeProviderError       = "provider_error"
+	CodeTimeout             = "timeout"
+	CodeInternal            = "internal_error"
+)
+
+// AsError returns the API error err stands for. Context added by wrapping,
+// like "leg 2: ", stays in the message. Errors nobody classified are internal
+// and their text is not shown.
+func AsError(err error) *model.Error {
+	var apiErr *model.Error
+	if errors.As(err, &apiErr) {
+		return withContext(err, apiErr, *apiErr)
+	}
+
+	var perr *ProviderError
+	if errors.As(err, &perr) {
+		return withContext(err, perr, providerError(perr))
+	}
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return withContext(err, ErrNotFound, model.Error{Category: model.CategoryNotFound, Code: CodeNotFound, Message: ErrNotFound.Error()})
+	case errors.Is(err, ErrConflict):
+		// The wrapped database message names constraints, keep it out
+		return &model.Error{Category: model.CategoryConflict, Code: CodeConflict, Message: ErrConflict.Error(), Cause: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &model.Error{Category: model.CategoryProviderError, Code: CodeTimeout, Message: "request timed out, the outcome may be unknown", Cause: err}
+	}
+	return &model.Error{Category: model.CategoryInternal, Code: CodeInternal, Message: "internal error", Cause: err}
+}
+
+func providerError(perr *ProviderError) model.Error {
+	res := model.Error{Message: perr.Err.Error(), Cause: perr.Cause}
+	switch perr.Class {
+	case ErrorOutage:
+		res.Category, res.Code = model.CategoryProviderUnavailable, CodeProviderUnavailable
+	case ErrorDecline:
+		res.Category, res.Code, res.DeclineCode = model.CategoryDecline, CodeCardDeclined, perr.DeclineCode
+	case ErrorValidation:
+		res.Category, res.Code = model.CategoryValidation, CodeProviderRejected
+	default:
+		res.Category, res.Code = model.CategoryProviderError, CodeProviderError
+	}
+	return res
+}
+
+// Puts res's message in place of inner's text within err's
+func withContext(err error, inner error, res model.Error) *model.Error {
+	full, text := err.Error(), inner.Error()
+	if full != text && strings.Contains(full, text) {
+		res.Message = strings.Replace(full, text, res.Message, 1)
+	}
+	return &res
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// Where FX rates come from, polled by the rate refresher
+type IRateSource interface {
+	Name() string
+	FetchRates(ctx context.Context) ([]model.FxRate, error)
+}
@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+type IHealthChecker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// Optional capability for processors that can report provider reachability
+type IProviderPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Optional capability for processors that count their calls
+type IProviderStats interface {
+	Stats() model.ProviderStats
+}
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"errors"
 
 	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
 )
@@ -12,6 +13,101 @@ const (
 	PaymentFailed    = "payment_failed"
 	PaymentCancelled = "payment_intent.canceled"
 	Pending			 = "pending"
+	Refunded		 = "refunded"
+	PartiallyRefunded = "partially_refunded"
+	PaymentMethodDisabled = "disable"
+)
+
+// Charge attempt lifecycle: started -> charged -> recorded, or failed,
+// refunded and unresolved when the provider call or recording went wrong
+const (
+	AttemptStarted    = "started"
+	AttemptCharged    = "charged"
+	AttemptRecorded   = "recorded"
+	AttemptFailed     = "failed"
+	AttemptRefunded   = "refunded"
+	AttemptUnresolved = "unresolved"
+)
+
+// Order statuses, derived from the order's transactions except cancelled
+const (
+	OrderOpen          = "open"
+	OrderPaid          = "paid"
+	OrderPartiallyPaid = "partially_paid"
+	OrderRefunded      = "refunded"
+	OrderCancelled     = "cancelled"
+)
+
+// Split tender outcomes: every leg charged, every charged leg refunded after a
+// failure, or a leg whose money could not be accounted for
+const (
+	SplitSucceeded    = "succeeded"
+	SplitUnwound      = "unwound"
+	SplitUnwindFailed = "unwind_failed"
+)
+
+// Split tender leg statuses. A leg is unknown when its charge may have gone
+// through without being recorded, recovery settles it later.
+const (
+	LegCharged  = "charged"
+	LegFailed   = "failed"
+	LegRefunded = "refunded"
+	LegSkipped  = "skipped"
+	LegUnknown  = "unknown"
+)
+
+// FX quote statuses, an open quote past its expiry can no longer be used
+const (
+	FxQuoteOpen = "open"
+	FxQuoteUsed = "used"
+)
+
+// How a failed provider call is handled. Only outages fail over to another
+// provider: nothing was charged and the provider may be fine for others.
+type ErrorClass string
+
+const (
+	ErrorOutage     ErrorClass = "outage"
+	ErrorDecline    ErrorClass = "decline"
+	ErrorValidation ErrorClass = "validation"
+	// The call may have charged, e.g. it timed out or the connection broke
+	ErrorAmbiguous ErrorClass = "ambiguous"
+)
+
+// Adapters return ProviderError so the service knows what a failure means.
+// Err is safe to show to clients, Cause is the provider's own error.
+type ProviderError struct {
+	Class ErrorClass
+	Err   error
+	// Why a decline was declined, e.g. "insufficient_funds"
+	DeclineCode string
+	Cause       error
+}
+
+func (e *ProviderError) Error() string { return e.Err.Error() }
+
+func (e *ProviderError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Cause}
+}
+
+// ClassifyError returns the class an adapter gave err. Anything unclassified
+// is ambiguous, it is never safe to charge again elsewhere.
+func ClassifyError(err error) ErrorClass {
+	var perr *ProviderError
+	if errors.As(err, &perr) {
+		return perr.Class
+	}
+	return ErrorAmbiguous
+}
+
+// Metadata keys the service sets on provider objects, callers cannot use them
+const (
+	MetadataOrderID   = "order_id"
+	MetadataReference = "internal_reference"
+	MetadataAttemptID = "charge_attempt_id"
 )
 
 type IPaymentProcessor interface {
@@ -20,4 +116,30 @@ type IPaymentProcessor interface {
 	GetPaymentIntent(ctx context.Context, id string) (*model.PaymentProcessorResponse, error)
 	ChargeClient(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentProcessorResponse, error)
 	ParseWebhook(ctx context.Context, raw []byte, headers map[string][]string) (*model.PaymentEvent, error)
-}
\ No newline at end of file
+}
+
+// Optional capability: signs a payload the way the provider signs webhooks, so
+// an event saved from the provider can be replayed through ParseWebhook
+type IWebhookSigner interface {
+	SignWebhook(raw []byte) map[string][]string
+}
+
+// Optional capability for processors that can give money back. Refund returns
+// captured money, Cancel voids an intent nothing was captured on, e.g. one
+// waiting on 3-D Secure.
+type IRefunder interface {
+	Refund(ctx context.Context, req model.ProviderRefundRequest) (*model.ProviderRefundResponse, error)
+	Cancel(ctx context.Context, paymentIntentID string) (*model.PaymentProcessorResponse, error)
+}
+
+// Optional capability for processors with per-currency charge limits, checked
+// before the provider is called
+type IAmountLimiter interface {
+	AmountLimits(currency model.Currency) model.AmountLimits
+}
+
+// Optional capability for processors that can look up the charge created for
+// an attempt (PaymentIntentRequest.AttemptID). Returns ErrNotFound when none exists.
+type IChargeFinder interface {
+	FindCharge(ctx context.Context, attemptID string) (*model.PaymentProcessorResponse, error)
+}
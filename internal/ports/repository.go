@@ -2,16 +2,104 @@ package ports
 
 import (
 	"time"
+	"errors"
 	"context"
     
 	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
 )
 
+// Repositories wrap these so callers can use errors.Is regardless of the backend
+var (
+    ErrNotFound = errors.New("record not found")
+    ErrConflict = errors.New("record already exists")
+)
+
+// Create fills the generated ID, InternalReference and timestamps
 type ITransactionRepository interface {
     Create(ctx context.Context, tx *model.Transaction) error
     FindByID(ctx context.Context, id string) (*model.Transaction, error)
     FindByPaymentIntent(ctx context.Context, id string) (*model.Transaction, error)
+    FindByReference(ctx context.Context, reference string) (*model.Transaction, error)
     FindByStatus(ctx context.Context, status string, since time.Time) ([]model.Transaction, error)
     UpdateStatus(ctx context.Context, status string, filters map[string]interface{}) error
+    // Sets the intent of a transaction created before the provider call
+    AttachPaymentIntent(ctx context.Context, id string, paymentIntentID string) error
     FindByColumn(ctx context.Context, column string, value interface{}) ([]model.Transaction, error)
-}
\ No newline at end of file
+}
+
+type IPaymentMethodRepository interface {
+    Create(ctx context.Context, pm *model.PaymentMethod) error
+    FindByID(ctx context.Context, id string) (*model.PaymentMethod, error)
+    FindByCustomer(ctx context.Context, customerID string) ([]model.PaymentMethod, error)
+    FindByStatus(ctx context.Context, status string, since time.Time) ([]model.PaymentMethod, error)
+    UpdateStatus(ctx context.Context, customerID string, id string, status string) error
+}
+
+type IRefundRepository interface {
+    Create(ctx context.Context, refund *model.Refund) error
+    FindByTransaction(ctx context.Context, transactionID string) ([]model.Refund, error)
+}
+
+type IChargeAttemptRepository interface {
+    Create(ctx context.Context, attempt *model.ChargeAttempt) error
+    FindByID(ctx context.Context, id string) (*model.ChargeAttempt, error)
+    // Persists Status, PaymentIntentID, PaymentMethodID and LastError
+    Update(ctx context.Context, attempt *model.ChargeAttempt) error
+    // Attempts in one of statuses not updated since before, oldest first
+    FindStale(ctx context.Context, statuses []string, before time.Time, limit int) ([]model.ChargeAttempt, error)
+    // Attempts towards an order in one of statuses, oldest first
+    FindByOrder(ctx context.Context, orderID string, statuses []string) ([]model.ChargeAttempt, error)
+    // FindStale that also touches the attempts it returns, atomically, so a
+    // concurrent claim skips them until they go stale again
+    ClaimStale(ctx context.Context, statuses []string, before time.Time, limit int) ([]model.ChargeAttempt, error)
+}
+
+// Create fills the generated ID, InternalReference, Status and timestamps
+type IOrderRepository interface {
+    Create(ctx context.Context, order *model.Order) error
+    FindByID(ctx context.Context, id string) (*model.Order, error)
+    UpdateStatus(ctx context.Context, id string, status string) error
+}
+
+// Latest rate per currency pair
+type IFxRateRepository interface {
+    Upsert(ctx context.Context, rate *model.FxRate) error
+    Find(ctx context.Context, base string, quote string) (*model.FxRate, error)
+}
+
+// Create fills the generated ID, Status and CreatedAt
+type IFxQuoteRepository interface {
+    Create(ctx context.Context, quote *model.FxQuote) error
+    FindByID(ctx context.Context, id string) (*model.FxQuote, error)
+    // Marks an open quote used. ErrConflict when it is used or expired at at.
+    Use(ctx context.Context, id string, at time.Time) error
+}
+
+// Keys are unique. Reserve fills CreatedAt and fails with ErrConflict while
+// another record holds the key, unless that one has expired or is still
+// running since before staleBefore, which it then replaces.
+type IIdempotencyRepository interface {
+    Reserve(ctx context.Context, record *model.IdempotencyRecord, staleBefore time.Time) error
+    FindByKey(ctx context.Context, key string) (*model.IdempotencyRecord, error)
+    // Stores the response of a reserved key
+    Complete(ctx context.Context, key string, status int, body []byte) error
+    Delete(ctx context.Context, key string) error
+    // Deletes the records expired at the given time and returns how many
+    DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Repositories bound to a single unit of work
+type Repositories struct {
+    Transactions   ITransactionRepository
+    PaymentMethods IPaymentMethodRepository
+    Refunds        IRefundRepository
+    ChargeAttempts IChargeAttemptRepository
+    Orders         IOrderRepository
+    FxQuotes       IFxQuoteRepository
+}
+
+// Runs multi-table writes atomically. fn may be called more than once when the
+// database asks for a retry, so it must only touch the repositories it is given.
+type IUnitOfWork interface {
+    Do(ctx context.Context, fn func(repos Repositories) error) error
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IChargeAttemptRepository = (*ChargeAttemptRepository)(nil)
+
+const chargeAttemptColumns = `id, provider, customer_id, COALESCE(order_id::text, ''), amount, currency, COALESCE(payment_method_id, ''),
+               save_payment_method, COALESCE(payment_intent_id, ''), attempt_status,
+               COALESCE(last_error, ''), metadata, created_at, updated_at`
+
+type ChargeAttemptRepository struct {
+    db DB
+}
+
+func NewChargeAttemptRepository(pool *pgxpool.Pool) *ChargeAttemptRepository {
+	return &ChargeAttemptRepository{db: pool}
+}
+
+func (r *ChargeAttemptRepository) Create(ctx context.Context, attempt *model.ChargeAttempt) error {
+    const rawsql = `
+        INSERT INTO charge_attempts (provider, customer_id, amount, currency, payment_method_id, save_payment_method, attempt_status, metadata, order_id)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, COALESCE(NULLIF($7, ''), 'started'), $8, NULLIF($9, '')::uuid)
+        RETURNING id, attempt_status, created_at, updated_at`
+
+    err := r.db.QueryRow(ctx, rawsql,
+        attempt.Provider,
+        attempt.CustomerID,
+        attempt.Amount,
+        attempt.Currency,
+        attempt.PaymentMethodID,
+        attempt.SavePaymentMethod,
+        attempt.Status,
+        attempt.Metadata,
+        attempt.OrderID,
+    ).Scan(&attempt.ID, &attempt.Status, &attempt.CreatedAt, &attempt.UpdatedAt)
+    if err != nil {
+        return fmt.Errorf("error creating charge attempt: %w", dbError(err))
+    }
+    return nil
+}
+
+func (r *ChargeAttemptRepository) FindByID(ctx context.Context, id string) (*model.ChargeAttempt, error) {
+    rawsql := `SELECT ` + chargeAttemptColumns + ` FROM charge_attempts WHERE id = $1`
+
+    attempt, err := scanChargeAttempt(r.db.QueryRow(ctx, rawsql, id))
+    if err != nil {
+        return nil, fmt.Errorf("error getting charge attempt: %w", dbError(err))
+    }
+    return attempt, nil
+}
+
+func (r *ChargeAttemptRepository) Update(ctx context.Context, attempt *model.ChargeAttempt) error {
+    const rawsql = `
+        UPDATE charge_attempts
+        SET attempt_status = $2, payment_intent_id = NULLIF($3, ''), payment_method_id = NULLIF($4, ''),
+            last_error = NULLIF($5, ''), updated_at = NOW()
+        WHERE id = $1
+        RETURNING updated_at`
+
+    err := r.db.QueryRow(ctx, rawsql,
+        attempt.ID,
+        attempt.Status,
+        attempt.PaymentIntentID,
+        attempt.PaymentMethodID,
+        attempt.LastError,
+    ).Scan(&attempt.UpdatedAt)
+    if err != nil {
+        return fmt.Errorf("failed to update charge attempt: %w", dbError(err))
+    }
+    return nil
+}
+
+func (r *ChargeAttemptRepository) FindStale(ctx context.Context, statuses []string, before time.Time, limit int) ([]model.ChargeAttempt, error) {
+    rawsql := `SELECT ` + chargeAttemptColumns + `
+        FROM charge_attempts
+        WHERE attempt_status = ANY($1) AND updated_at < $2
+        ORDER BY updated_at ASC
+        LIMIT $3`
+
+    rows, err := r.db.Query(ctx, rawsql, statuses, before, limit)
+    if err != nil {
+        return nil, fmt.Errorf("error querying stale charge attempts: %w", err)
+    }
+    defer rows.Close()
+
+    var attempts []model.ChargeAttempt
+    for rows.Next() {
+        attempt, err := scanChargeAttempt(rows)
+        if err != nil {
+            return nil, fmt.Errorf("error scanning charge attempt: %w", err)
+        }
+        attempts = append(attempts, *attempt)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("rows iteration error: %w", err)
+    }
+
+    return attempts, nil
+}
+
+func (r *ChargeAttemptRepository) FindByOrder(ctx context.Context, orderID string, statuses []string) ([]model.ChargeAttempt, error) {
+    rawsql := `SELECT ` + chargeAttemptColumns + `
+        FROM charge_attempts
+        WHERE order_id = $1 AND attempt_status = ANY($2)
+        ORDER BY created_at ASC`
+
+    rows, err := r.db.Query(ctx, rawsql, orderID, statuses)
+    if err != nil {
+        return nil, fmt.Errorf("error querying order charge attempts: %w", err)
+    }
+    defer rows.Close()
+
+    var attempts []model.ChargeAttempt
+    for rows.Next() {
+        attempt, err := scanChargeAttempt(rows)
+        if err != nil {
+            return nil, fmt.Errorf("error scanning charge attempt: %w", err)
+        }
+        attempts = append(attempts, *attempt)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("rows iteration error: %w", err)
+    }
+
+    return attempts, nil
+}
+
+// Rows another claim holds are skipped rather than waited on. Claimed rows are
+// returned oldest first by the updated_at they had before the claim.
+func (r *ChargeAttemptRepository) ClaimStale(ctx context.Context, statuses []string, before time.Time, limit int) ([]model.ChargeAttempt, error) {
+    rawsql := `
+        WITH claimed AS (
+            UPDATE charge_attempts a
+            SET updated_at = NOW()
+            FROM (
+                SELECT id, updated_at
+                FROM charge_attempts
+                WHERE attempt_status = ANY($1) AND updated_at < $2
+                ORDER BY updated_at ASC
+                LIMIT $3
+                FOR UPDATE SKIP LOCKED
+            ) stale
+            WHERE a.id = stale.id
+            RETURNING a.*, stale.updated_at AS stale_since
+        )
+        SELECT ` + chargeAttemptColumns + `
+        FROM claimed
+        ORDER BY stale_since ASC`
+
+    rows, err := r.db.Query(ctx, rawsql, statuses, before, limit)
+    if err != nil {
+        return nil, fmt.Errorf("error claiming stale charge attempts: %w", err)
+    }
+    defer rows.Close()
+
+    var attempts []model.ChargeAttempt
+    for rows.Next() {
+        attempt, err := scanChargeAttempt(rows)
+        if err != nil {
+            return nil, fmt.Errorf("error scanning charge attempt: %w", err)
+        }
+        attempts = append(attempts, *attempt)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("rows iteration error: %w", err)
+    }
+
+    return attempts, nil
+}
+
+func scanChargeAttempt(row pgx.Row) (*model.ChargeAttempt, error) {
+    var a model.ChargeAttempt
+    err := row.Scan(
+        &a.ID,
+        &a.Provider,
+        &a.CustomerID,
+        &a.OrderID,
+        &a.Amount,
+        &a.Currency,
+        &a.PaymentMethodID,
+        &a.SavePaymentMethod,
+        &a.PaymentIntentID,
+        &a.Status,
+        &a.LastError,
+        &a.Metadata,
+        &a.CreatedAt,
+        &a.UpdatedAt,
+    )
+    if err != nil {
+        return nil, err
+    }
+    return &a, nil
+}
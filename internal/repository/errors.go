@@ -0,0 +1,25 @@
+package repository
+
+import (
+    "fmt"
+    "errors"
+
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgconn"
+    "github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+const uniqueViolation = "23505"
+
+// Maps driver errors onto the ports sentinels, anything else passes through
+func dbError(err error) error {
+    if errors.Is(err, pgx.ErrNoRows) {
+        return ports.ErrNotFound
+    }
+
+    var pgErr *pgconn.PgError
+    if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+        return fmt.Errorf("%w: %s", ports.ErrConflict, pgErr.Message)
+    }
+    return err
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var (
+	_ ports.IFxRateRepository  = (*FxRateRepository)(nil)
+	_ ports.IFxQuoteRepository = (*FxQuoteRepository)(nil)
+)
+
+type FxRateRepository struct {
+    db DB
+}
+
+func NewFxRateRepository(pool *pgxpool.Pool) *FxRateRepository {
+	return &FxRateRepository{db: pool}
+}
+
+func (r *FxRateRepository) Upsert(ctx context.Context, rate *model.FxRate) error {
+    const rawsql = `
+        INSERT INTO fx_rates (base_currency, quote_currency, rate, source, fetched_at)
+        VALUES ($1, $2, $3::text::numeric, $4, $5)
+        ON CONFLICT (base_currency, quote_currency)
+        DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source, fetched_at = EXCLUDED.fetched_at`
+
+    _, err := r.db.Exec(ctx, rawsql, rate.Base, rate.Quote, rate.Rate, rate.Source, rate.FetchedAt)
+    if err != nil {
+        return fmt.Errorf("error saving fx rate %s/%s: %w", rate.Base, rate.Quote, dbError(err))
+    }
+    return nil
+}
+
+func (r *FxRateRepository) Find(ctx context.Context, base string, quote string) (*model.FxRate, error) {
+    const rawsql = `
+        SELECT base_currency, quote_currency, rate::text, source, fetched_at
+        FROM fx_rates WHERE base_currency = $1 AND quote_currency = $2`
+
+    var rate model.FxRate
+    err := r.db.QueryRow(ctx, rawsql, base, quote).Scan(&rate.Base, &rate.Quote, &rate.Rate, &rate.Source, &rate.FetchedAt)
+    if err != nil {
+        return nil, fmt.Errorf("error getting fx rate %s/%s: %w", base, quote, dbError(err))
+    }
+    return &rate, nil
+}
+
+const fxQuoteColumns = `id, customer_id, settlement_amount, settlement_currency, presentment_amount,
+               presentment_currency, rate::text, quote_status, expires_at, used_at, created_at`
+
+type FxQuoteRepository struct {
+    db DB
+}
+
+func NewFxQuoteRepository(pool *pgxpool.Pool) *FxQuoteRepository {
+	return &FxQuoteRepository{db: pool}
+}
+
+func (r *FxQuoteRepository) Create(ctx context.Context, quote *model.FxQuote) error {
+    const rawsql = `
+        INSERT INTO fx_quotes (customer_id, settlement_amount, settlement_currency, presentment_amount,
+                               presentment_currency, rate, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6::text::numeric, $7)
+        RETURNING id, quote_status, created_at`
+
+    err := r.db.QueryRow(ctx, rawsql,
+        quote.CustomerID,
+        quote.SettlementAmount,
+        quote.SettlementCurrency,
+        quote.PresentmentAmount,
+        quote.PresentmentCurrency,
+        quote.Rate,
+        quote.ExpiresAt,
+    ).Scan(&quote.ID, &quote.Status, &quote.CreatedAt)
+    if err != nil {
+        return fmt.Errorf("error creating fx quote: %w", dbError(err))
+    }
+    return nil
+}
+
+func (r *FxQuoteRepository) FindByID(ctx context.Context, id string) (*model.FxQuote, error) {
+    rawsql := `SELECT ` + fxQuoteColumns + ` FROM fx_quotes WHERE id = $1`
+
+    quote, err := scanFxQuote(r.db.QueryRow(ctx, rawsql, id))
+    if err != nil {
+        return nil, fmt.Errorf("error getting fx quote: %w", dbError(err))
+    }
+    return quote, nil
+}
+
+func (r *FxQuoteRepository) Use(ctx context.Context, id string, at time.Time) error {
+    const rawsql = `
+        UPDATE fx_quotes SET quote_status = 'used', used_at = $2
+        WHERE id = $1 AND quote_status = 'open' AND expires_at > $2`
+
+    tag, err := r.db.Exec(ctx, rawsql, id, at)
+    if err != nil {
+        return fmt.Errorf("failed to use fx quote: %w", dbError(err))
+    }
+    if tag.RowsAffected() == 0 {
+        if _, err := r.FindByID(ctx, id); err != nil {
+            return fmt.Errorf("failed to use fx quote: %w", err)
+        }
+        return fmt.Errorf("failed to use fx quote: %w: quote %s is used or expired", ports.ErrConflict, id)
+    }
+    return nil
+}
+
+func scanFxQuote(row pgx.Row) (*model.FxQuote, error) {
+    var q model.FxQuote
+    err := row.Scan(
+        &q.ID,
+        &q.CustomerID,
+        &q.SettlementAmount,
+        &q.SettlementCurrency,
+        &q.PresentmentAmount,
+        &q.PresentmentCurrency,
+        &q.Rate,
+        &q.Status,
+        &q.ExpiresAt,
+        &q.UsedAt,
+        &q.CreatedAt,
+    )
+    if err != nil {
+        return nil, err
+    }
+    return &q, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DatabaseChecker struct {
+	pool *pgxpool.Pool
+}
+
+func NewDatabaseChecker(pool *pgxpool.Pool) *DatabaseChecker {
+	return &DatabaseChecker{pool: pool}
+}
+
+func (c *DatabaseChecker) Name() string {
+	return "postgres"
+}
+
+func (c *DatabaseChecker) Check(ctx context.Context) error {
+	if err := c.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("database ping failed: %w", err)
+	}
+	return nil
+}
+
+type MigrationChecker struct {
+	pool     *pgxpool.Pool
+	expected int64
+}
+
+func NewMigrationChecker(pool *pgxpool.Pool, expected int64) *MigrationChecker {
+	return &MigrationChecker{pool: pool, expected: expected}
+}
+
+func (c *MigrationChecker) Name() string {
+	return "migrations"
+}
+
+func (c *MigrationChecker) Check(ctx context.Context) error {
+	const rawsql = `
+        SELECT version_id FROM goose_db_version
+        WHERE is_applied = TRUE
+        ORDER BY id DESC LIMIT 1`
+
+	var version int64
+	if err := c.pool.QueryRow(ctx, rawsql).Scan(&version); err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+
+	if version < c.expected {
+		return fmt.Errorf("schema version %d is behind expected %d", version, c.expected)
+	}
+	return nil
+}
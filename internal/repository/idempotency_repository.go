@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"fmt"
+	"errors"
+	"time"
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IIdempotencyRepository = (*IdempotencyRepository)(nil)
+
+type IdempotencyRepository struct {
+    db DB
+}
+
+func NewIdempotencyRepository(pool *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{db: pool}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord, staleBefore time.Time) error {
+    // The conditional update takes over a dead key; a live one updates no row
+    const rawsql = `
+        INSERT INTO idempotency_keys (idempotency_key, fingerprint, expires_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (idempotency_key) DO UPDATE
+        SET fingerprint = EXCLUDED.fingerprint, response_status = NULL, response_body = NULL,
+            created_at = NOW(), expires_at = EXCLUDED.expires_at
+        WHERE idempotency_keys.expires_at <= NOW()
+           OR (idempotency_keys.response_status IS NULL AND idempotency_keys.created_at < $4)
+        RETURNING created_at`
+
+    err := r.db.QueryRow(ctx, rawsql, record.Key, record.Fingerprint, record.ExpiresAt, staleBefore).Scan(&record.CreatedAt)
+    if err != nil {
+        err = dbError(err)
+        if errors.Is(err, ports.ErrNotFound) {
+            return fmt.Errorf("error reserving idempotency key: %w: key %s is in use", ports.ErrConflict, record.Key)
+        }
+        return fmt.Errorf("error reserving idempotency key: %w", err)
+    }
+    record.ResponseStatus = 0
+    record.ResponseBody = nil
+    return nil
+}
+
+func (r *IdempotencyRepository) FindByKey(ctx context.Context, key string) (*model.IdempotencyRecord, error) {
+    const rawsql = `
+        SELECT idempotency_key, fingerprint, COALESCE(response_status, 0), response_body, created_at, expires_at
+        FROM idempotency_keys WHERE idempotency_key = $1`
+
+    var rec model.IdempotencyRecord
+    err := r.db.QueryRow(ctx, rawsql, key).Scan(
+        &rec.Key,
+        &rec.Fingerprint,
+        &rec.ResponseStatus,
+        &rec.ResponseBody,
+        &rec.CreatedAt,
+        &rec.ExpiresAt,
+    )
+    if err != nil {
+        return nil, fmt.Errorf("error getting idempotency key: %w", dbError(err))
+    }
+    return &rec, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, status int, body []byte) error {
+    const rawsql = `UPDATE idempotency_keys SET response_status = $2, response_body = $3 WHERE idempotency_key = $1`
+
+    tag, err := r.db.Exec(ctx, rawsql, key, status, body)
+    if err != nil {
+        return fmt.Errorf("failed to complete idempotency key: %w", dbError(err))
+    }
+    if tag.RowsAffected() == 0 {
+        return fmt.Errorf("no matching idempotency key found: %w", ports.ErrNotFound)
+    }
+    return nil
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, key string) error {
+    const rawsql = `DELETE FROM idempotency_keys WHERE idempotency_key = $1`
+
+    if _, err := r.db.Exec(ctx, rawsql, key); err != nil {
+        return fmt.Errorf("failed to delete idempotency key: %w", dbError(err))
+    }
+    return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+    const rawsql = `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+
+    tag, err := r.db.Exec(ctx, rawsql, before)
+    if err != nil {
+        return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", dbError(err))
+    }
+    return tag.RowsAffected(), nil
+}
@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IChargeAttemptRepository = (*ChargeAttemptRepository)(nil)
+
+// Values allowed by the charge_attempts CHECK constraint
+var validAttemptStatuses = map[string]bool{
+	ports.AttemptStarted: true, ports.AttemptCharged: true, ports.AttemptRecorded: true,
+	ports.AttemptFailed: true, ports.AttemptRefunded: true, ports.AttemptUnresolved: true,
+}
+
+type ChargeAttemptRepository struct {
+	mu    sync.RWMutex
+	rows  []model.ChargeAttempt
+	clock clock
+}
+
+func NewChargeAttemptRepository() *ChargeAttemptRepository {
+	return &ChargeAttemptRepository{}
+}
+
+func (r *ChargeAttemptRepository) Create(ctx context.Context, attempt *model.ChargeAttempt) error {
+	if attempt.Amount <= 0 {
+		return errors.New(`error creating charge attempt: new row for relation "charge_attempts" violates check constraint "charge_attempts_amount_check"`)
+	}
+	if len(attempt.Currency) > 3 {
+		return errors.New("error creating charge attempt: value too long for type character(3)")
+	}
+
+	row := *attempt
+	row.ID = uuid.NewString()
+	row.Metadata = cloneMetadata(attempt.Metadata)
+	row.Currency = fmt.Sprintf("%-3s", attempt.Currency)
+	if row.Status == "" {
+		row.Status = ports.AttemptStarted
+	}
+	if !validAttemptStatuses[row.Status] {
+		return errors.New(`error creating charge attempt: new row for relation "charge_attempts" violates check constraint "charge_attempts_attempt_status_check"`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row.CreatedAt = r.clock.now()
+	row.UpdatedAt = row.CreatedAt
+	r.rows = append(r.rows, row)
+
+	attempt.ID = row.ID
+	attempt.Status = row.Status
+	attempt.CreatedAt = row.CreatedAt
+	attempt.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (r *ChargeAttemptRepository) FindByID(ctx context.Context, id string) (*model.ChargeAttempt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, row := range r.rows {
+		if row.ID == id {
+			attempt := row
+			attempt.Metadata = cloneMetadata(row.Metadata)
+			return &attempt, nil
+		}
+	}
+	return nil, fmt.Errorf("error getting charge attempt: %w", ports.ErrNotFound)
+}
+
+func (r *ChargeAttemptRepository) Update(ctx context.Context, attempt *model.ChargeAttempt) error {
+	if !validAttemptStatuses[attempt.Status] {
+		return errors.New(`failed to update charge attempt: new row for relation "charge_attempts" violates check constraint "charge_attempts_attempt_status_check"`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rows {
+		if r.rows[i].ID == attempt.ID {
+			r.rows[i].Status = attempt.Status
+			r.rows[i].PaymentIntentID = attempt.PaymentIntentID
+			r.rows[i].PaymentMethodID = attempt.PaymentMethodID
+			r.rows[i].LastError = attempt.LastError
+			r.rows[i].UpdatedAt = r.clock.now()
+			attempt.UpdatedAt = r.rows[i].UpdatedAt
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to update charge attempt: %w", ports.ErrNotFound)
+}
+
+func (r *ChargeAttemptRepository) FindStale(ctx context.Context, statuses []string, before time.Time, limit int) ([]model.ChargeAttempt, error) {
+	wanted := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		wanted[status] = true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []model.ChargeAttempt
+	for _, row := range r.rows {
+		if wanted[row.Status] && row.UpdatedAt.Before(before) {
+			row.Metadata = cloneMetadata(row.Metadata)
+			out = append(out, row)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].UpdatedAt.Before(out[j].UpdatedAt)
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *ChargeAttemptRepository) FindByOrder(ctx context.Context, orderID string, statuses []string) ([]model.ChargeAttempt, error) {
+	wanted := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		wanted[status] = true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Rows are appended as they are created, so already oldest first
+	var out []model.ChargeAttempt
+	for _, row := range r.rows {
+		if row.OrderID == orderID && wanted[row.Status] {
+			row.Metadata = cloneMetadata(row.Metadata)
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+func (r *ChargeAttemptRepository) ClaimStale(ctx context.Context, statuses []string, before time.Time, limit int) ([]model.ChargeAttempt, error) {
+	wanted := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		wanted[status] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stale []int
+	for i, row := range r.rows {
+		if wanted[row.Status] && row.UpdatedAt.Before(before) {
+			stale = append(stale, i)
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		return r.rows[stale[i]].UpdatedAt.Before(r.rows[stale[j]].UpdatedAt)
+	})
+	if limit > 0 && len(stale) > limit {
+		stale = stale[:limit]
+	}
+
+	out := make([]model.ChargeAttempt, 0, len(stale))
+	for _, i := range stale {
+		r.rows[i].UpdatedAt = r.clock.now()
+		row := r.rows[i]
+		row.Metadata = cloneMetadata(row.Metadata)
+		out = append(out, row)
+	}
+	return out, nil
+}
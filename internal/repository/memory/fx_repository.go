@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var (
+	_ ports.IFxRateRepository  = (*FxRateRepository)(nil)
+	_ ports.IFxQuoteRepository = (*FxQuoteRepository)(nil)
+)
+
+type FxRateRepository struct {
+	mu    sync.RWMutex
+	rates map[[2]string]model.FxRate
+}
+
+func NewFxRateRepository() *FxRateRepository {
+	return &FxRateRepository{rates: make(map[[2]string]model.FxRate)}
+}
+
+func (r *FxRateRepository) Upsert(ctx context.Context, rate *model.FxRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rates[[2]string{rate.Base, rate.Quote}] = *rate
+	return nil
+}
+
+func (r *FxRateRepository) Find(ctx context.Context, base string, quote string) (*model.FxRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rate, ok := r.rates[[2]string{base, quote}]
+	if !ok {
+		return nil, fmt.Errorf("error getting fx rate %s/%s: %w", base, quote, ports.ErrNotFound)
+	}
+	return &rate, nil
+}
+
+type FxQuoteRepository struct {
+	mu    sync.RWMutex
+	rows  []model.FxQuote
+	clock clock
+}
+
+func NewFxQuoteRepository() *FxQuoteRepository {
+	return &FxQuoteRepository{}
+}
+
+func (r *FxQuoteRepository) Create(ctx context.Context, quote *model.FxQuote) error {
+	if quote.SettlementAmount <= 0 || quote.PresentmentAmount <= 0 {
+		return errors.New(`error creating fx quote: new row for relation "fx_quotes" violates check constraint`)
+	}
+
+	row := *quote
+	row.ID = uuid.NewString()
+	row.Status = ports.FxQuoteOpen
+	row.UsedAt = nil
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row.CreatedAt = r.clock.now()
+	r.rows = append(r.rows, row)
+
+	quote.ID = row.ID
+	quote.Status = row.Status
+	quote.CreatedAt = row.CreatedAt
+	return nil
+}
+
+func (r *FxQuoteRepository) FindByID(ctx context.Context, id string) (*model.FxQuote, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, row := range r.rows {
+		if row.ID == id {
+			return cloneFxQuote(row), nil
+		}
+	}
+	return nil, fmt.Errorf("error getting fx quote: %w", ports.ErrNotFound)
+}
+
+func (r *FxQuoteRepository) Use(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rows {
+		if r.rows[i].ID != id {
+			continue
+		}
+		if r.rows[i].Status != ports.FxQuoteOpen || !r.rows[i].ExpiresAt.After(at) {
+			return fmt.Errorf("failed to use fx quote: %w: quote %s is used or expired", ports.ErrConflict, id)
+		}
+		used := at
+		r.rows[i].Status = ports.FxQuoteUsed
+		r.rows[i].UsedAt = &used
+		return nil
+	}
+	return fmt.Errorf("failed to use fx quote: error getting fx quote: %w", ports.ErrNotFound)
+}
+
+func cloneFxQuote(q model.FxQuote) *model.FxQuote {
+	if q.UsedAt != nil {
+		used := *q.UsedAt
+		q.UsedAt = &used
+	}
+	return &q
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IIdempotencyRepository = (*IdempotencyRepository)(nil)
+
+type IdempotencyRepository struct {
+	mu    sync.RWMutex
+	rows  map[string]model.IdempotencyRecord
+	clock clock
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{rows: make(map[string]model.IdempotencyRecord)}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord, staleBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.now()
+	if existing, ok := r.rows[record.Key]; ok {
+		expired := !existing.ExpiresAt.After(now)
+		stale := existing.ResponseStatus == 0 && existing.CreatedAt.Before(staleBefore)
+		if !expired && !stale {
+			return fmt.Errorf("error reserving idempotency key: %w: key %s is in use", ports.ErrConflict, record.Key)
+		}
+	}
+
+	row := model.IdempotencyRecord{
+		Key:         record.Key,
+		Fingerprint: record.Fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   record.ExpiresAt,
+	}
+	r.rows[record.Key] = row
+
+	record.ResponseStatus = 0
+	record.ResponseBody = nil
+	record.CreatedAt = now
+	return nil
+}
+
+func (r *IdempotencyRepository) FindByKey(ctx context.Context, key string) (*model.IdempotencyRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	row, ok := r.rows[key]
+	if !ok {
+		return nil, fmt.Errorf("error getting idempotency key: %w", ports.ErrNotFound)
+	}
+	row.ResponseBody = cloneBytes(row.ResponseBody)
+	return &row, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, status int, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, ok := r.rows[key]
+	if !ok {
+		return fmt.Errorf("no matching idempotency key found: %w", ports.ErrNotFound)
+	}
+	row.ResponseStatus = status
+	row.ResponseBody = cloneBytes(body)
+	r.rows[key] = row
+	return nil
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.rows, key)
+	return nil
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, row := range r.rows {
+		if !row.ExpiresAt.After(before) {
+			delete(r.rows, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,60 @@
+// Package memory provides thread-safe in-memory repositories that mirror the
+// semantics of the Postgres ones (defaults, constraints, ordering and errors),
+// for fast tests and local development without a database.
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// clock hands out strictly increasing timestamps at Postgres (microsecond)
+// resolution so created_at ordering is deterministic
+type clock struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := time.Now().UTC().Truncate(time.Microsecond)
+	if !t.After(c.last) {
+		t = c.last.Add(time.Microsecond)
+	}
+	c.last = t
+	return t
+}
+
+// matches compares a column value the way an equality filter would in SQL
+func matches(column interface{}, value interface{}) bool {
+	if reflect.DeepEqual(column, value) {
+		return true
+	}
+	if column == nil || value == nil {
+		return false
+	}
+	return fmt.Sprint(column) == fmt.Sprint(value)
+}
+
+func cloneMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func cloneBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	v := *b
+	return &v
+}
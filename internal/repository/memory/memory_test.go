@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/repository/repotest"
+)
+
+func TestTransactionRepository(t *testing.T) {
+	repotest.TransactionRepository(t, func(t *testing.T) ports.ITransactionRepository {
+		return NewTransactionRepository()
+	})
+}
+
+func TestPaymentMethodRepository(t *testing.T) {
+	repotest.PaymentMethodRepository(t, func(t *testing.T) ports.IPaymentMethodRepository {
+		return NewPaymentMethodRepository()
+	})
+}
+
+func TestRefundRepository(t *testing.T) {
+	repotest.RefundRepository(t, func(t *testing.T) (ports.ITransactionRepository, ports.IRefundRepository) {
+		transactions := NewTransactionRepository()
+		return transactions, NewRefundRepository(transactions)
+	})
+}
+
+func TestChargeAttemptRepository(t *testing.T) {
+	repotest.ChargeAttemptRepository(t, func(t *testing.T) ports.IChargeAttemptRepository {
+		return NewChargeAttemptRepository()
+	})
+}
+
+func TestOrderRepository(t *testing.T) {
+	repotest.OrderRepository(t, func(t *testing.T) (ports.IOrderRepository, ports.ITransactionRepository) {
+		return NewOrderRepository(), NewTransactionRepository()
+	})
+}
+
+func TestFxRepositories(t *testing.T) {
+	repotest.FxRepositories(t, func(t *testing.T) (ports.IFxRateRepository, ports.IFxQuoteRepository, ports.ITransactionRepository) {
+		return NewFxRateRepository(), NewFxQuoteRepository(), NewTransactionRepository()
+	})
+}
+
+func TestIdempotencyRepository(t *testing.T) {
+	repotest.IdempotencyRepository(t, func(t *testing.T) ports.IIdempotencyRepository {
+		return NewIdempotencyRepository()
+	})
+}
+
+func TestUnitOfWork(t *testing.T) {
+	repotest.UnitOfWork(t, func(t *testing.T) (ports.IUnitOfWork, ports.Repositories) {
+		transactions := NewTransactionRepository()
+		paymentMethods := NewPaymentMethodRepository()
+		refunds := NewRefundRepository(transactions)
+		attempts := NewChargeAttemptRepository()
+		orders := NewOrderRepository()
+		quotes := NewFxQuoteRepository()
+		uow := NewUnitOfWork(transactions, paymentMethods, refunds, attempts, orders, quotes)
+		return uow, ports.Repositories{
+			Transactions:   transactions,
+			PaymentMethods: paymentMethods,
+			Refunds:        refunds,
+			ChargeAttempts: attempts,
+			Orders:         orders,
+			FxQuotes:       quotes,
+		}
+	})
+}
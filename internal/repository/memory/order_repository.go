@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IOrderRepository = (*OrderRepository)(nil)
+
+// Values allowed by the orders CHECK constraint
+var validOrderStatuses = map[string]bool{
+	ports.OrderOpen: true, ports.OrderPaid: true, ports.OrderPartiallyPaid: true,
+	ports.OrderRefunded: true, ports.OrderCancelled: true,
+}
+
+type OrderRepository struct {
+	mu    sync.RWMutex
+	rows  []model.Order
+	seq   int64
+	clock clock
+}
+
+func NewOrderRepository() *OrderRepository {
+	// Mirrors order_reference_seq START 1001
+	return &OrderRepository{seq: 1000}
+}
+
+func (r *OrderRepository) Create(ctx context.Context, order *model.Order) error {
+	if order.AmountDue <= 0 {
+		return errors.New(`error creating order: new row for relation "orders" violates check constraint "orders_amount_due_check"`)
+	}
+	if len(order.Currency) > 3 {
+		return errors.New("error creating order: value too long for type character(3)")
+	}
+
+	row := *order
+	row.ID = uuid.NewString()
+	row.Metadata = cloneMetadata(order.Metadata)
+	row.Currency = fmt.Sprintf("%-3s", order.Currency)
+	if row.Status == "" {
+		row.Status = ports.OrderOpen
+	}
+	if !validOrderStatuses[row.Status] {
+		return errors.New(`error creating order: new row for relation "orders" violates check constraint "orders_order_status_check"`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	row.InternalReference = fmt.Sprintf("ORD-%d", r.seq)
+	row.CreatedAt = r.clock.now()
+	row.UpdatedAt = row.CreatedAt
+	r.rows = append(r.rows, row)
+
+	order.ID = row.ID
+	order.InternalReference = row.InternalReference
+	order.Status = row.Status
+	order.CreatedAt = row.CreatedAt
+	order.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (r *OrderRepository) FindByID(ctx context.Context, id string) (*model.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, row := range r.rows {
+		if row.ID == id {
+			order := row
+			order.Metadata = cloneMetadata(row.Metadata)
+			return &order, nil
+		}
+	}
+	return nil, fmt.Errorf("error getting order: %w", ports.ErrNotFound)
+}
+
+func (r *OrderRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+	if !validOrderStatuses[status] {
+		return errors.New(`failed to update order status: new row for relation "orders" violates check constraint "orders_order_status_check"`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rows {
+		if r.rows[i].ID == id {
+			r.rows[i].Status = status
+			r.rows[i].UpdatedAt = r.clock.now()
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching order found: %w", ports.ErrNotFound)
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IPaymentMethodRepository = (*PaymentMethodRepository)(nil)
+
+// Values allowed by the payment_methods CHECK constraints
+var (
+	validPMProviders = map[string]bool{"stripe": true, "paypal": true, "needpam": true, "fake": true}
+	validPMStatuses  = map[string]bool{"active": true, "disable": true}
+)
+
+type PaymentMethodRepository struct {
+	mu    sync.RWMutex
+	rows  []model.PaymentMethod
+	clock clock
+}
+
+func NewPaymentMethodRepository() *PaymentMethodRepository {
+	return &PaymentMethodRepository{}
+}
+
+func (r *PaymentMethodRepository) Create(ctx context.Context, pm *model.PaymentMethod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row := *pm
+	if row.Type == "" {
+		row.Type = "none"
+	}
+	if row.Status == "" {
+		row.Status = ports.PaymentMethodStatus
+	}
+
+	if !validPMProviders[row.Provider] {
+		return errors.New(`error creating payment method: new row for relation "payment_methods" violates check constraint "payment_methods_pm_provider_check"`)
+	}
+	if !validPMStatuses[row.Status] {
+		return errors.New(`error creating payment method: new row for relation "payment_methods" violates check constraint "payment_methods_pm_status_check"`)
+	}
+
+	for _, existing := range r.rows {
+		if existing.CustomerID == row.CustomerID && existing.ID == row.ID {
+			return fmt.Errorf(`error creating payment method: %w: duplicate key value violates unique constraint "payment_methods_pkey"`, ports.ErrConflict)
+		}
+	}
+
+	row.CreatedAt = r.clock.now()
+	row.UpdatedAt = row.CreatedAt
+	r.rows = append(r.rows, row)
+	return nil
+}
+
+func (r *PaymentMethodRepository) FindByID(ctx context.Context, id string) (*model.PaymentMethod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, row := range r.rows {
+		if row.ID == id {
+			pm := row
+			return &pm, nil
+		}
+	}
+	return nil, fmt.Errorf("error getting payment Method: %w", ports.ErrNotFound)
+}
+
+func (r *PaymentMethodRepository) FindByCustomer(ctx context.Context, customerID string) ([]model.PaymentMethod, error) {
+	return r.findMany(func(pm *model.PaymentMethod) bool {
+		return pm.CustomerID == customerID
+	}), nil
+}
+
+func (r *PaymentMethodRepository) FindByStatus(ctx context.Context, status string, since time.Time) ([]model.PaymentMethod, error) {
+	return r.findMany(func(pm *model.PaymentMethod) bool {
+		return pm.Status == status && !pm.CreatedAt.Before(since)
+	}), nil
+}
+
+func (r *PaymentMethodRepository) UpdateStatus(ctx context.Context, customerID string, id string, status string) error {
+	if !validPMStatuses[status] {
+		return errors.New(`failed to update payment method status: new row for relation "payment_methods" violates check constraint "payment_methods_pm_status_check"`)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rows {
+		if r.rows[i].CustomerID == customerID && r.rows[i].ID == id {
+			r.rows[i].Status = status
+			r.rows[i].UpdatedAt = r.clock.now()
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching payment method found: %w", ports.ErrNotFound)
+}
+
+func (r *PaymentMethodRepository) findMany(pred func(*model.PaymentMethod) bool) []model.PaymentMethod {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []model.PaymentMethod
+	for i := range r.rows {
+		if pred(&r.rows[i]) {
+			out = append(out, r.rows[i])
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out
+}
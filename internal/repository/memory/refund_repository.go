@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IRefundRepository = (*RefundRepository)(nil)
+
+type RefundRepository struct {
+	mu           sync.RWMutex
+	rows         []model.Refund
+	seq          int64
+	clock        clock
+	transactions *TransactionRepository
+}
+
+// transactions enforces the refunds.transaction_id foreign key when not nil
+func NewRefundRepository(transactions *TransactionRepository) *RefundRepository {
+	// Mirrors refund_reference_seq START 1001
+	return &RefundRepository{seq: 1000, transactions: transactions}
+}
+
+func (r *RefundRepository) Create(ctx context.Context, refund *model.Refund) error {
+	if refund.Amount <= 0 {
+		return fmt.Errorf("error creating refund: %w",
+			errors.New(`new row for relation "refunds" violates check constraint "refunds_amount_check"`))
+	}
+
+	if r.transactions != nil {
+		if _, err := r.transactions.FindByID(ctx, refund.TransactionID); err != nil {
+			return fmt.Errorf("error creating refund: %w",
+				errors.New(`insert or update on table "refunds" violates foreign key constraint "refunds_transaction_id_fkey"`))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	refund.ID = uuid.NewString()
+	refund.InternalReference = fmt.Sprintf("REF-%d", r.seq)
+	refund.ProcessedAt = r.clock.now()
+	if refund.RefundStatus == "" {
+		refund.RefundStatus = "pending"
+	}
+
+	r.rows = append(r.rows, *refund)
+	return nil
+}
+
+func (r *RefundRepository) FindByTransaction(ctx context.Context, transactionID string) ([]model.Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []model.Refund
+	for _, rf := range r.rows {
+		if rf.TransactionID == transactionID {
+			out = append(out, rf)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].ProcessedAt.After(out[j].ProcessedAt)
+	})
+	return out, nil
+}
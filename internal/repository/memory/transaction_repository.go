@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.ITransactionRepository = (*TransactionRepository)(nil)
+
+type TransactionRepository struct {
+	mu    sync.RWMutex
+	rows  []model.Transaction
+	seq   int64
+	clock clock
+}
+
+func NewTransactionRepository() *TransactionRepository {
+	// Mirrors payment_reference_seq START 1001
+	return &TransactionRepository{seq: 1000}
+}
+
+func (r *TransactionRepository) Create(ctx context.Context, tx *model.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tx.Amount <= 0 {
+		return errors.New(`error creating transaction: new row for relation "transactions" violates check constraint "transactions_amount_check"`)
+	}
+	if len(tx.Currency) > 3 {
+		return errors.New("error creating transaction: value too long for type character(3)")
+	}
+
+	row := *tx
+	// CHAR(3) pads shorter values with spaces
+	row.Currency = fmt.Sprintf("%-3s", tx.Currency)
+	row.SavePaymentMethod = cloneBool(tx.SavePaymentMethod)
+	row.Metadata = cloneMetadata(tx.Metadata)
+	row.ProviderAttempts = slices.Clone(tx.ProviderAttempts)
+
+	if row.ID == "" {
+		row.ID = uuid.NewString()
+	}
+	if row.InternalReference == "" {
+		r.seq++
+		row.InternalReference = fmt.Sprintf("PAY-%d", r.seq)
+	}
+	if row.SavePaymentMethod == nil {
+		save := false
+		row.SavePaymentMethod = &save
+	}
+	// Rows written without a settlement side read back as settled in kind
+	if row.SettlementCurrency == "" {
+		row.SettlementAmount = row.Amount
+		row.SettlementCurrency = row.Currency
+	} else {
+		row.SettlementCurrency = fmt.Sprintf("%-3s", row.SettlementCurrency)
+	}
+	if row.FxRate == "" {
+		row.FxRate = "1"
+	}
+
+	for _, existing := range r.rows {
+		if row.FxQuoteID != "" && existing.FxQuoteID == row.FxQuoteID {
+			return fmt.Errorf(`error creating transaction: %w: duplicate key value violates unique constraint "transactions_fx_quote_id_key"`, ports.ErrConflict)
+		}
+		if existing.ID == row.ID {
+			return fmt.Errorf(`error creating transaction: %w: duplicate key value violates unique constraint "transactions_pkey"`, ports.ErrConflict)
+		}
+		if existing.InternalReference == row.InternalReference {
+			return fmt.Errorf(`error creating transaction: %w: duplicate key value violates unique constraint "transactions_internal_reference_key"`, ports.ErrConflict)
+		}
+	}
+
+	row.CreatedAt = r.clock.now()
+	row.UpdatedAt = row.CreatedAt
+	r.rows = append(r.rows, row)
+
+	tx.ID = row.ID
+	tx.InternalReference = row.InternalReference
+	tx.CreatedAt = row.CreatedAt
+	tx.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (r *TransactionRepository) FindByID(ctx context.Context, id string) (*model.Transaction, error) {
+	return r.findOne(func(tx *model.Transaction) bool { return tx.ID == id })
+}
+
+func (r *TransactionRepository) FindByPaymentIntent(ctx context.Context, id string) (*model.Transaction, error) {
+	return r.findOne(func(tx *model.Transaction) bool { return tx.PaymentIntentID != "" && tx.PaymentIntentID == id })
+}
+
+func (r *TransactionRepository) FindByReference(ctx context.Context, reference string) (*model.Transaction, error) {
+	return r.findOne(func(tx *model.Transaction) bool { return tx.InternalReference == reference })
+}
+
+func (r *TransactionRepository) FindByStatus(ctx context.Context, status string, since time.Time) ([]model.Transaction, error) {
+	return r.findMany(func(tx *model.Transaction) bool {
+		return tx.TxStatus == status && !tx.CreatedAt.Before(since)
+	}), nil
+}
+
+func (r *TransactionRepository) UpdateStatus(ctx context.Context, status string, filters map[string]interface{}) error {
+	if len(filters) == 0 {
+		return errors.New("at least one filter condition is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated := 0
+	now := r.clock.now()
+	for i := range r.rows {
+		ok, err := transactionMatches(&r.rows[i], filters)
+		if err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+		if ok {
+			r.rows[i].TxStatus = status
+			r.rows[i].UpdatedAt = now
+			updated++
+		}
+	}
+
+	if updated == 0 {
+		return fmt.Errorf("no matching transaction found: %w", ports.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *TransactionRepository) AttachPaymentIntent(ctx context.Context, id string, paymentIntentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rows {
+		if r.rows[i].ID != id {
+			continue
+		}
+		if r.rows[i].PaymentIntentID != "" && r.rows[i].PaymentIntentID != paymentIntentID {
+			return fmt.Errorf("failed to attach payment intent: %w: transaction %s already has another intent", ports.ErrConflict, id)
+		}
+		r.rows[i].PaymentIntentID = paymentIntentID
+		r.rows[i].UpdatedAt = r.clock.now()
+		return nil
+	}
+	return fmt.Errorf("failed to attach payment intent: error getting transaction: %w", ports.ErrNotFound)
+}
+
+func (r *TransactionRepository) FindByColumn(ctx context.Context, column string, value interface{}) ([]model.Transaction, error) {
+	if _, ok := transactionColumn(&model.Transaction{}, column); !ok {
+		return nil, fmt.Errorf("invalid column name: %s", column)
+	}
+
+	return r.findMany(func(tx *model.Transaction) bool {
+		v, _ := transactionColumn(tx, column)
+		return matches(v, value)
+	}), nil
+}
+
+func (r *TransactionRepository) findOne(pred func(*model.Transaction) bool) (*model.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := range r.rows {
+		if pred(&r.rows[i]) {
+			tx := cloneTransaction(r.rows[i])
+			return &tx, nil
+		}
+	}
+	return nil, fmt.Errorf("error getting transaction: %w", ports.ErrNotFound)
+}
+
+// Returns copies ordered by created_at DESC like the SQL queries
+func (r *TransactionRepository) findMany(pred func(*model.Transaction) bool) []model.Transaction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []model.Transaction
+	for i := range r.rows {
+		if pred(&r.rows[i]) {
+			out = append(out, cloneTransaction(r.rows[i]))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	return out
+}
+
+func transactionMatches(tx *model.Transaction, filters map[string]interface{}) (bool, error) {
+	for column, value := range filters {
+		v, ok := transactionColumn(tx, column)
+		if !ok {
+			return false, fmt.Errorf("column %q does not exist", column)
+		}
+		if !matches(v, value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Maps transactions table columns onto the model
+func transactionColumn(tx *model.Transaction, column string) (interface{}, bool) {
+	switch column {
+	case "id":
+		return tx.ID, true
+	case "internal_reference":
+		return tx.InternalReference, true
+	case "amount":
+		return tx.Amount, true
+	case "currency":
+		return tx.Currency, true
+	case "payment_intent_id":
+		// Write-ahead rows have a NULL intent until the provider answers
+		if tx.PaymentIntentID == "" {
+			return nil, true
+		}
+		return tx.PaymentIntentID, true
+	case "settlement_currency":
+		return tx.SettlementCurrency, true
+	case "fx_quote_id":
+		if tx.FxQuoteID == "" {
+			return nil, true
+		}
+		return tx.FxQuoteID, true
+	case "provider":
+		if tx.Provider == "" {
+			return nil, true
+		}
+		return tx.Provider, true
+	case "routing_rule":
+		if tx.RoutingRule == "" {
+			return nil, true
+		}
+		return tx.RoutingRule, true
+	case "tx_status":
+		return tx.TxStatus, true
+	case "customer_id":
+		return tx.CustomerID, true
+	case "order_id":
+		if tx.OrderID == "" {
+			return nil, true
+		}
+		return tx.OrderID, true
+	case "save_payment_method":
+		if tx.SavePaymentMethod == nil {
+			return nil, true
+		}
+		return *tx.SavePaymentMethod, true
+	case "created_at":
+		return tx.CreatedAt, true
+	case "updated_at":
+		return tx.UpdatedAt, true
+	case "metadata":
+		return tx.Metadata, true
+	}
+	return nil, false
+}
+
+func cloneTransaction(tx model.Transaction) model.Transaction {
+	tx.SavePaymentMethod = cloneBool(tx.SavePaymentMethod)
+	tx.Metadata = cloneMetadata(tx.Metadata)
+	tx.ProviderAttempts = slices.Clone(tx.ProviderAttempts)
+	return tx
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IUnitOfWork = (*UnitOfWork)(nil)
+
+// UnitOfWork serialises units and restores the repositories when fn fails.
+// Writes made outside Do while a unit is running are lost on rollback.
+type UnitOfWork struct {
+	mu             sync.Mutex
+	transactions   *TransactionRepository
+	paymentMethods *PaymentMethodRepository
+	refunds        *RefundRepository
+	attempts       *ChargeAttemptRepository
+	orders         *OrderRepository
+	quotes         *FxQuoteRepository
+}
+
+func NewUnitOfWork(transactions *TransactionRepository, paymentMethods *PaymentMethodRepository, refunds *RefundRepository, attempts *ChargeAttemptRepository, orders *OrderRepository, quotes *FxQuoteRepository) *UnitOfWork {
+	return &UnitOfWork{transactions: transactions, paymentMethods: paymentMethods, refunds: refunds, attempts: attempts, orders: orders, quotes: quotes}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(repos ports.Repositories) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	restore := u.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			restore()
+			panic(p)
+		}
+		if err != nil {
+			restore()
+		}
+	}()
+
+	return fn(ports.Repositories{
+		Transactions:   u.transactions,
+		PaymentMethods: u.paymentMethods,
+		Refunds:        u.refunds,
+		ChargeAttempts: u.attempts,
+		Orders:         u.orders,
+		FxQuotes:       u.quotes,
+	})
+}
+
+// Rows are stored by value, so copying the slices is enough to roll back
+func (u *UnitOfWork) snapshot() func() {
+	u.transactions.mu.RLock()
+	txRows := make([]model.Transaction, len(u.transactions.rows))
+	for i, tx := range u.transactions.rows {
+		txRows[i] = cloneTransaction(tx)
+	}
+	u.transactions.mu.RUnlock()
+
+	u.paymentMethods.mu.RLock()
+	pmRows := append([]model.PaymentMethod(nil), u.paymentMethods.rows...)
+	u.paymentMethods.mu.RUnlock()
+
+	u.refunds.mu.RLock()
+	rfRows := append([]model.Refund(nil), u.refunds.rows...)
+	u.refunds.mu.RUnlock()
+
+	u.attempts.mu.RLock()
+	attemptRows := append([]model.ChargeAttempt(nil), u.attempts.rows...)
+	u.attempts.mu.RUnlock()
+
+	u.orders.mu.RLock()
+	orderRows := make([]model.Order, len(u.orders.rows))
+	for i, order := range u.orders.rows {
+		order.Metadata = cloneMetadata(order.Metadata)
+		orderRows[i] = order
+	}
+	u.orders.mu.RUnlock()
+
+	u.quotes.mu.RLock()
+	quoteRows := make([]model.FxQuote, len(u.quotes.rows))
+	for i, quote := range u.quotes.rows {
+		quoteRows[i] = *cloneFxQuote(quote)
+	}
+	u.quotes.mu.RUnlock()
+
+	// Sequences are not rolled back, matching Postgres
+	return func() {
+		u.transactions.mu.Lock()
+		u.transactions.rows = txRows
+		u.transactions.mu.Unlock()
+
+		u.paymentMethods.mu.Lock()
+		u.paymentMethods.rows = pmRows
+		u.paymentMethods.mu.Unlock()
+
+		u.refunds.mu.Lock()
+		u.refunds.rows = rfRows
+		u.refunds.mu.Unlock()
+
+		u.attempts.mu.Lock()
+		u.attempts.rows = attemptRows
+		u.attempts.mu.Unlock()
+
+		u.orders.mu.Lock()
+		u.orders.rows = orderRows
+		u.orders.mu.Unlock()
+
+		u.quotes.mu.Lock()
+		u.quotes.rows = quoteRows
+		u.quotes.mu.Unlock()
+	}
+}
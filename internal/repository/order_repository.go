@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"fmt"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var _ ports.IOrderRepository = (*OrderRepository)(nil)
+
+const orderColumns = `id, internal_reference, customer_id, amount_due, currency, order_status, metadata, created_at, updated_at`
+
+type OrderRepository struct {
+    db DB
+}
+
+func NewOrderRepository(pool *pgxpool.Pool) *OrderRepository {
+	return &OrderRepository{db: pool}
+}
+
+func (r *OrderRepository) Create(ctx context.Context, order *model.Order) error {
+    const rawsql = `
+        INSERT INTO orders (customer_id, amount_due, currency, order_status, metadata)
+        VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'open'), $5)
+        RETURNING id, internal_reference, order_status, created_at, updated_at`
+
+    err := r.db.QueryRow(ctx, rawsql,
+        order.CustomerID,
+        order.AmountDue,
+        order.Currency,
+        order.Status,
+        order.Metadata,
+    ).Scan(&order.ID, &order.InternalReference, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+    if err != nil {
+        return fmt.Errorf("error creating order: %w", dbError(err))
+    }
+    return nil
+}
+
+func (r *OrderRepository) FindByID(ctx context.Context, id string) (*model.Order, error) {
+    rawsql := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+
+    order, err := scanOrder(r.db.QueryRow(ctx, rawsql, id))
+    if err != nil {
+        return nil, fmt.Errorf("error getting order: %w", dbError(err))
+    }
+    return order, nil
+}
+
+func (r *OrderRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+    const rawsql = `UPDATE orders SET order_status = $2, updated_at = NOW() WHERE id = $1`
+
+    tag, err := r.db.Exec(ctx, rawsql, id, status)
+    if err != nil {
+        return fmt.Errorf("failed to update order status: %w", dbError(err))
+    }
+    if tag.RowsAffected() == 0 {
+        return fmt.Errorf("no matching order found: %w", ports.ErrNotFound)
+    }
+    return nil
+}
+
+func scanOrder(row pgx.Row) (*model.Order, error) {
+    var o model.Order
+    err := row.Scan(
+        &o.ID,
+        &o.InternalReference,
+        &o.CustomerID,
+        &o.AmountDue,
+        &o.Currency,
+        &o.Status,
+        &o.Metadata,
+        &o.CreatedAt,
+        &o.UpdatedAt,
+    )
+    if err != nil {
+        return nil, err
+    }
+    return &o, nil
+}
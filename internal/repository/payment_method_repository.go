@@ -3,13 +3,13 @@ package repository
 import (
 	"fmt"
 	"time"
-    "errors"
     "strings"
 	"context"
-    "database/sql"
     
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
 )
 
 type PaymentMethodRepository struct {
@@ -20,7 +20,7 @@ func NewPaymentMethodRepository(pool *pgxpool.Pool) *PaymentMethodRepository {
 	return &PaymentMethodRepository{db: pool}
 }
 
-func (r *PaymentMethodRepository) Create(ctx context.Context, tx *model.Transaction) error {
+func (r *PaymentMethodRepository) Create(ctx context.Context, pm *model.PaymentMethod) error {
     // Initialize builder
     var (
         fields []string
@@ -31,31 +31,31 @@ func (r *PaymentMethodRepository) Create(ctx context.Context, tx *model.Transact
 
     // Required fields (assuming these can't be null)
     fields = append(fields, "customer_id")
-    values = append(values, tx.CustomerID)
+    values = append(values, pm.CustomerID)
     params = append(params, fmt.Sprintf("$%d", pos))
     pos++
 
     fields = append(fields, "payment_method_id")
-    values = append(values, tx.PaymentMethodID)
+    values = append(values, pm.ID)
     params = append(params, fmt.Sprintf("$%d", pos))
     pos++
 
     fields = append(fields, "pm_provider")
-    values = append(values, tx.PaymentProvider)
+    values = append(values, pm.Provider)
     params = append(params, fmt.Sprintf("$%d", pos))
     pos++
 
     // Conditionally add optional fields
-    if tx.PaymentMethodType != "" {
+    if pm.Type != "" {
         fields = append(fields, "method_type")
-        values = append(values, tx.PaymentMethodType)
+        values = append(values, pm.Type)
         params = append(params, fmt.Sprintf("$%d", pos))
         pos++
     }
 
-    if tx.PaymentMethodStatus != "" {
+    if pm.Status != "" {
         fields = append(fields, "pm_status")
-        values = append(values, tx.PaymentMethodStatus)
+        values = append(values, pm.Status)
         params = append(params, fmt.Sprintf("$%d", pos))
         pos++
     }
@@ -68,39 +68,41 @@ func (r *PaymentMethodRepository) Create(ctx context.Context, tx *model.Transact
         strings.Join(params, ", "),
     )
 
-    _, err := r.db.Exec(ctx, query, values...)
-    return err
+    if _, err := r.db.Exec(ctx, query, values...); err != nil {
+        return fmt.Errorf("error creating payment method: %w", dbError(err))
+    }
+    return nil
 }
 
-func (r *PaymentMethodRepository) FindByID(ctx context.Context, id string) (*model.Transaction, error) {
-	rawsql := `SELECT customer_id, payment_method_id, pm_provider, method_type, pm_status
+func (r *PaymentMethodRepository) FindByID(ctx context.Context, id string) (*model.PaymentMethod, error) {
+	rawsql := `SELECT customer_id, payment_method_id, pm_provider, COALESCE(method_type, ''), pm_status, created_at, updated_at
                FROM payment_methods WHERE payment_method_id = $1`
                
-	var tx model.Transaction
-	err := r.db.QueryRow(ctx, rawsql, id).Scan(
-		&tx.CustomerID,
-		&tx.PaymentMethodID,
-		&tx.PaymentProvider,
-		&tx.PaymentMethodType,
-		&tx.PaymentMethodStatus,
-	)
+	pm, err := scanPaymentMethod(r.db.QueryRow(ctx, rawsql, id))
 	if err != nil {
-        if errors.Is(err, sql.ErrNoRows) {
-            return nil, fmt.Errorf("Not found: %w", err)
-        }
-        return nil, fmt.Errorf("error getting payment Method: %w", err)
+        return nil, fmt.Errorf("error getting payment Method: %w", dbError(err))
 	}
-	return &tx, nil
+	return pm, nil
 }
 
-func (r *PaymentMethodRepository) FindByPaymentIntent(ctx context.Context, id string) (*model.Transaction, error) {
-	var tx model.Transaction
-	return &tx, nil
+func (r *PaymentMethodRepository) FindByCustomer(ctx context.Context, customerID string) ([]model.PaymentMethod, error) {
+    const rawsql = `
+        SELECT customer_id, payment_method_id, pm_provider, COALESCE(method_type, ''),
+               pm_status, created_at, updated_at
+        FROM payment_methods
+        WHERE customer_id = $1
+        ORDER BY created_at DESC`
+
+    rows, err := r.db.Query(ctx, rawsql, customerID)
+    if err != nil {
+        return nil, fmt.Errorf("error querying payment methods by customer: %w", err)
+    }
+    return collectPaymentMethods(rows)
 }
 
-func (r *PaymentMethodRepository) FindByStatus(ctx context.Context, status string, since time.Time) ([]model.Transaction, error) {
+func (r *PaymentMethodRepository) FindByStatus(ctx context.Context, status string, since time.Time) ([]model.PaymentMethod, error) {
 	    const rawsql = `
-        SELECT customer_id, payment_method_id, pm_provider, method_type, 
+        SELECT customer_id, payment_method_id, pm_provider, COALESCE(method_type, ''), 
         	   pm_status, created_at, updated_at
         FROM payment_methods
         WHERE pm_status = $1 AND created_at >= $2
@@ -111,143 +113,53 @@ func (r *PaymentMethodRepository) FindByStatus(ctx context.Context, status strin
     if err != nil {
         return nil, fmt.Errorf("error querying payment_methods by status: %w", err)
     }
-    defer rows.Close()
-
-    var payment_methods []model.Transaction
-    for rows.Next() {
-        var tx model.Transaction
-        if err := rows.Scan(
-			&tx.CustomerID,
-			&tx.PaymentMethodID,
-			&tx.PaymentProvider,
-			&tx.PaymentMethodType,
-			&tx.PaymentMethodStatus,
-        ); err != nil {
-            return nil, fmt.Errorf("error scanning payment method: %w", err)
-        }
-        payment_methods = append(payment_methods, tx)
-    }
-
-    if err := rows.Err(); err != nil {
-        return nil, fmt.Errorf("rows iteration error: %w", err)
-    }
-
-    return payment_methods, nil
+    return collectPaymentMethods(rows)
 }
 
-func (r *PaymentMethodRepository) UpdateStatus(ctx context.Context, status string, filters map[string]interface{}) error {
-    // Build dynamic SQL (pgx uses $1, $2... placeholders)
-    var (
-        query strings.Builder
-        args  []interface{}
-        pos   = 1 // Tracks placeholder position
-    )
+func (r *PaymentMethodRepository) UpdateStatus(ctx context.Context, customerID string, id string, status string) error {
+    const rawsql = `
+        UPDATE payment_methods SET pm_status = $1, updated_at = NOW()
+        WHERE customer_id = $2 AND payment_method_id = $3`
 
-    // Base UPDATE
-    query.WriteString(`UPDATE payment_methods SET pm_status = $1, updated_at = NOW()`)
-    args = append(args, status)
-    pos++
-
-    // Add WHERE conditions if filters exist
-    if len(filters) > 0 {
-        query.WriteString(" WHERE ")
-        conditions := []string{}
-        
-        for field, value := range filters {
-            conditions = append(conditions, fmt.Sprintf("%s = $%d", field, pos))
-            args = append(args, value)
-            pos++
-        }
-        query.WriteString(strings.Join(conditions, " AND "))
-    } else {
-        return errors.New("at least one filter condition is required")
-    }
-
-    // Execute with pgx
-    tag, err := r.db.Exec(ctx, query.String(), args...)
+    tag, err := r.db.Exec(ctx, rawsql, status, customerID, id)
     if err != nil {
         return fmt.Errorf("failed to update payment method status: %w", err)
     }
 
-    // Check if any rows were updated (pgx uses 'tag.RowsAffected()')
     if tag.RowsAffected() == 0 {
-        return fmt.Errorf("no matching payment method found")
+        return fmt.Errorf("no matching payment method found: %w", ports.ErrNotFound)
     }
 
     return nil
 }
 
-func (r *PaymentMethodRepository) WithTransaction(ctx context.Context, 
-    fn func(*PaymentMethodRepository) error,
-) error {
-    // Begin a transaction (default options)
-    tx, err := r.db.Begin(ctx)
+func scanPaymentMethod(row pgx.Row) (*model.PaymentMethod, error) {
+    var pm model.PaymentMethod
+    err := row.Scan(
+        &pm.CustomerID,
+        &pm.ID,
+        &pm.Provider,
+        &pm.Type,
+        &pm.Status,
+        &pm.CreatedAt,
+        &pm.UpdatedAt,
+    )
     if err != nil {
-        return fmt.Errorf("failed to begin transaction: %w", err)
-    }
-
-    // Create a transaction-scoped repository
-    txRepo := &PaymentMethodRepository{db: tx}
-
-    defer func() {
-        if p := recover(); p != nil {
-            tx.Rollback(ctx)
-            panic(p) // Re-throw panic after cleanup
-        }
-    }()
-
-    if err := fn(txRepo); err != nil {
-        if rbErr := tx.Rollback(ctx); rbErr != nil {
-            return fmt.Errorf("transaction error: %v, rollback failed: %w", err, rbErr)
-        }
-        return err
+        return nil, err
     }
-
-    return tx.Commit(ctx)
+    return &pm, nil
 }
 
-func (r *PaymentMethodRepository) FindByColumn(ctx context.Context, column string, value interface{}) ([]model.Transaction, error) {
-    // Validate the column name to prevent SQL injection
-    validColumns := map[string]bool{
-        "customer_id":          true,
-        "payment_method_id":    true,
-        "pm_provider":          true,
-        "method_type":          true,
-        "pm_status":            true,
-        "created_at":           true,
-        "updated_at":           true,
-    }
-
-    if !validColumns[column] {
-        return nil, fmt.Errorf("invalid column name: %s", column)
-    }
-
-    sql := fmt.Sprintf(`
-        SELECT customer_id, payment_method_id, pm_provider, method_type, 
-               pm_status
-        FROM payment_methods
-        WHERE %s = $1
-        ORDER BY created_at DESC`, column)
-
-    rows, err := r.db.Query(ctx, sql, value)
-    if err != nil {
-        return nil, fmt.Errorf("error querying payment method by %s: %w", column, err)
-    }
+func collectPaymentMethods(rows pgx.Rows) ([]model.PaymentMethod, error) {
     defer rows.Close()
 
-    var payment_methods []model.Transaction
+    var payment_methods []model.PaymentMethod
     for rows.Next() {
-        var tx model.Transaction
-        if err := rows.Scan(
-			&tx.CustomerID,
-			&tx.PaymentMethodID,
-			&tx.PaymentProvider,
-			&tx.PaymentMethodType,
-			&tx.PaymentMethodStatus,
-        ); err != nil {
+        pm, err := scanPaymentMethod(rows)
+        if err != nil {
             return nil, fmt.Errorf("error scanning payment method: %w", err)
         }
-        payment_methods = append(payment_methods, tx)
+        payment_methods = append(payment_methods, *pm)
     }
 
     if err := rows.Err(); err != nil {
@@ -255,4 +167,4 @@ func (r *PaymentMethodRepository) FindByColumn(ctx context.Context, column strin
     }
 
     return payment_methods, nil
-}
\ No newline at end of file
+}
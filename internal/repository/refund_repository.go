@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+type RefundRepository struct {
+    db DB
+}
+
+func NewRefundRepository(pool *pgxpool.Pool) *RefundRepository {
+	return &RefundRepository{db: pool}
+}
+
+func (r *RefundRepository) Create(ctx context.Context, refund *model.Refund) error {
+    const rawsql = `
+        INSERT INTO refunds (transaction_id, amount, reason, provider_refund_id, refund_status)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), COALESCE(NULLIF($5, ''), 'pending'))
+        RETURNING id, internal_reference, refund_status, processed_at`
+
+    err := r.db.QueryRow(ctx, rawsql,
+        refund.TransactionID,
+        refund.Amount,
+        refund.Reason,
+        refund.ProviderRefundID,
+        refund.RefundStatus,
+    ).Scan(&refund.ID, &refund.InternalReference, &refund.RefundStatus, &refund.ProcessedAt)
+    if err != nil {
+        return fmt.Errorf("error creating refund: %w", dbError(err))
+    }
+    return nil
+}
+
+func (r *RefundRepository) FindByTransaction(ctx context.Context, transactionID string) ([]model.Refund, error) {
+    const rawsql = `
+        SELECT id, internal_reference, transaction_id, amount, COALESCE(reason, ''),
+               COALESCE(provider_refund_id, ''), refund_status, processed_at
+        FROM refunds
+        WHERE transaction_id = $1
+        ORDER BY processed_at DESC`
+
+    rows, err := r.db.Query(ctx, rawsql, transactionID)
+    if err != nil {
+        return nil, fmt.Errorf("error querying refunds: %w", err)
+    }
+    defer rows.Close()
+
+    var refunds []model.Refund
+    for rows.Next() {
+        var rf model.Refund
+        if err := rows.Scan(
+            &rf.ID,
+            &rf.InternalReference,
+            &rf.TransactionID,
+            &rf.Amount,
+            &rf.Reason,
+            &rf.ProviderRefundID,
+            &rf.RefundStatus,
+            &rf.ProcessedAt,
+        ); err != nil {
+            return nil, fmt.Errorf("error scanning refund: %w", err)
+        }
+        refunds = append(refunds, rf)
+    }
+
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("rows iteration error: %w", err)
+    }
+
+    return refunds, nil
+}
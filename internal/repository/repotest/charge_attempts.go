@@ -0,0 +1,181 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// ChargeAttemptRepository checks the charge_attempts semantics recovery relies on
+func ChargeAttemptRepository(t *testing.T, newRepo func(t *testing.T) ports.IChargeAttemptRepository) {
+	ctx := context.Background()
+
+	t.Run("create assigns id and started status", func(t *testing.T) {
+		repo := newRepo(t)
+		attempt := newAttempt("cus_1")
+		if err := repo.Create(ctx, attempt); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if attempt.ID == "" || attempt.Status != ports.AttemptStarted || attempt.CreatedAt.IsZero() {
+			t.Fatalf("expected generated fields, got %+v", attempt)
+		}
+
+		got, err := repo.FindByID(ctx, attempt.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Amount != attempt.Amount || got.PaymentMethodID != attempt.PaymentMethodID || !got.SavePaymentMethod {
+			t.Fatalf("expected %+v, got %+v", attempt, got)
+		}
+		if got.Metadata["order_id"] != "ord_1" {
+			t.Fatalf("expected metadata to round-trip, got %v", got.Metadata)
+		}
+	})
+
+	t.Run("find missing returns not found", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.FindByID(ctx, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("update persists the outcome", func(t *testing.T) {
+		repo := newRepo(t)
+		attempt := mustCreateAttempt(t, repo, newAttempt("cus_1"))
+
+		attempt.Status = ports.AttemptCharged
+		attempt.PaymentIntentID = "pi_attempt"
+		attempt.LastError = "recording failed"
+		if err := repo.Update(ctx, attempt); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		got, err := repo.FindByID(ctx, attempt.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Status != ports.AttemptCharged || got.PaymentIntentID != "pi_attempt" || got.LastError != "recording failed" {
+			t.Fatalf("unexpected attempt %+v", got)
+		}
+		if !got.UpdatedAt.After(got.CreatedAt) {
+			t.Fatal("expected updated_at to move forward")
+		}
+	})
+
+	t.Run("update rejects unknown statuses and attempts", func(t *testing.T) {
+		repo := newRepo(t)
+		attempt := mustCreateAttempt(t, repo, newAttempt("cus_1"))
+
+		attempt.Status = "bogus"
+		if err := repo.Update(ctx, attempt); err == nil {
+			t.Fatal("expected attempt_status check violation")
+		}
+
+		missing := &model.ChargeAttempt{ID: "00000000-0000-0000-0000-000000000000", Status: ports.AttemptFailed}
+		if err := repo.Update(ctx, missing); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("find stale filters by status and age, oldest first", func(t *testing.T) {
+		repo := newRepo(t)
+		first := mustCreateAttempt(t, repo, newAttempt("cus_1"))
+		second := mustCreateAttempt(t, repo, newAttempt("cus_2"))
+		done := mustCreateAttempt(t, repo, newAttempt("cus_3"))
+
+		second.Status = ports.AttemptCharged
+		second.PaymentIntentID = "pi_second"
+		if err := repo.Update(ctx, second); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		done.Status = ports.AttemptRecorded
+		if err := repo.Update(ctx, done); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		statuses := []string{ports.AttemptStarted, ports.AttemptCharged}
+		stale, err := repo.FindStale(ctx, statuses, time.Now().Add(time.Minute), 10)
+		if err != nil {
+			t.Fatalf("FindStale: %v", err)
+		}
+		if len(stale) != 2 || stale[0].ID != first.ID || stale[1].ID != second.ID {
+			t.Fatalf("expected %s then %s, got %+v", first.ID, second.ID, stale)
+		}
+
+		stale, err = repo.FindStale(ctx, statuses, time.Now().Add(time.Minute), 1)
+		if err != nil {
+			t.Fatalf("FindStale: %v", err)
+		}
+		if len(stale) != 1 {
+			t.Fatalf("expected the limit to apply, got %d attempts", len(stale))
+		}
+
+		stale, err = repo.FindStale(ctx, statuses, time.Now().Add(-time.Hour), 10)
+		if err != nil {
+			t.Fatalf("FindStale: %v", err)
+		}
+		if len(stale) != 0 {
+			t.Fatalf("expected recent attempts to be skipped, got %+v", stale)
+		}
+	})
+
+	t.Run("claim stale hides claimed attempts from the next claim", func(t *testing.T) {
+		repo := newRepo(t)
+		first := mustCreateAttempt(t, repo, newAttempt("cus_1"))
+		second := mustCreateAttempt(t, repo, newAttempt("cus_2"))
+		time.Sleep(10 * time.Millisecond)
+		before := time.Now()
+
+		statuses := []string{ports.AttemptStarted, ports.AttemptCharged}
+		claimed, err := repo.ClaimStale(ctx, statuses, before, 1)
+		if err != nil {
+			t.Fatalf("ClaimStale: %v", err)
+		}
+		if len(claimed) != 1 || claimed[0].ID != first.ID {
+			t.Fatalf("expected %s to be claimed first, got %+v", first.ID, claimed)
+		}
+		if !claimed[0].UpdatedAt.After(first.UpdatedAt) {
+			t.Fatalf("expected the claim to touch updated_at, got %s", claimed[0].UpdatedAt)
+		}
+
+		claimed, err = repo.ClaimStale(ctx, statuses, before, 10)
+		if err != nil {
+			t.Fatalf("ClaimStale: %v", err)
+		}
+		if len(claimed) != 1 || claimed[0].ID != second.ID {
+			t.Fatalf("expected only %s to be left, got %+v", second.ID, claimed)
+		}
+
+		claimed, err = repo.ClaimStale(ctx, statuses, before, 10)
+		if err != nil {
+			t.Fatalf("ClaimStale: %v", err)
+		}
+		if len(claimed) != 0 {
+			t.Fatalf("expected nothing left to claim, got %+v", claimed)
+		}
+	})
+}
+
+func newAttempt(customer string) *model.ChargeAttempt {
+	return &model.ChargeAttempt{
+		Provider:          "stripe",
+		CustomerID:        customer,
+		Amount:            2500,
+		Currency:          "eur",
+		PaymentMethodID:   "pm_card_visa",
+		SavePaymentMethod: true,
+		Metadata:          map[string]string{"order_id": "ord_1"},
+	}
+}
+
+func mustCreateAttempt(t *testing.T, repo ports.IChargeAttemptRepository, attempt *model.ChargeAttempt) *model.ChargeAttempt {
+	t.Helper()
+	if err := repo.Create(context.Background(), attempt); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return attempt
+}
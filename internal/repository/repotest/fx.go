@@ -0,0 +1,121 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// FxRepositories checks rates, quotes and the settlement side of transactions
+func FxRepositories(t *testing.T, newRepos func(t *testing.T) (ports.IFxRateRepository, ports.IFxQuoteRepository, ports.ITransactionRepository)) {
+	ctx := context.Background()
+
+	t.Run("upsert replaces the rate for a pair", func(t *testing.T) {
+		rates, _, _ := newRepos(t)
+		fetched := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+		for _, rate := range []model.FxRate{
+			{Base: "eur", Quote: "usd", Rate: "1.0842", Source: "file", FetchedAt: fetched},
+			{Base: "eur", Quote: "usd", Rate: "1.0901", Source: "http", FetchedAt: fetched.Add(time.Minute)},
+		} {
+			if err := rates.Upsert(ctx, &rate); err != nil {
+				t.Fatalf("Upsert: %v", err)
+			}
+		}
+
+		got, err := rates.Find(ctx, "eur", "usd")
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+		if got.Rate != "1.0901" || got.Source != "http" || !got.FetchedAt.Equal(fetched.Add(time.Minute)) {
+			t.Fatalf("expected the second rate, got %+v", got)
+		}
+		if _, err := rates.Find(ctx, "usd", "eur"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound for the reverse pair, got %v", err)
+		}
+	})
+
+	t.Run("quote is used once", func(t *testing.T) {
+		_, quotes, _ := newRepos(t)
+		quote := mustCreateQuote(t, quotes, newQuote("cus_1", time.Now().Add(time.Hour)))
+		if quote.ID == "" || quote.Status != ports.FxQuoteOpen || quote.CreatedAt.IsZero() {
+			t.Fatalf("expected generated fields, got %+v", quote)
+		}
+
+		if err := quotes.Use(ctx, quote.ID, time.Now()); err != nil {
+			t.Fatalf("Use: %v", err)
+		}
+		if err := quotes.Use(ctx, quote.ID, time.Now()); !errors.Is(err, ports.ErrConflict) {
+			t.Fatalf("expected ports.ErrConflict on second use, got %v", err)
+		}
+
+		got, err := quotes.FindByID(ctx, quote.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Status != ports.FxQuoteUsed || got.UsedAt == nil || got.Rate != "1.0842" || got.PresentmentAmount != 1084 {
+			t.Fatalf("unexpected quote %+v", got)
+		}
+	})
+
+	t.Run("expired quote cannot be used", func(t *testing.T) {
+		_, quotes, _ := newRepos(t)
+		quote := mustCreateQuote(t, quotes, newQuote("cus_1", time.Now().Add(-time.Minute)))
+		if err := quotes.Use(ctx, quote.ID, time.Now()); !errors.Is(err, ports.ErrConflict) {
+			t.Fatalf("expected ports.ErrConflict, got %v", err)
+		}
+		if err := quotes.Use(ctx, "00000000-0000-0000-0000-000000000000", time.Now()); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("transactions keep both sides", func(t *testing.T) {
+		_, quotes, transactions := newRepos(t)
+		quote := mustCreateQuote(t, quotes, newQuote("cus_1", time.Now().Add(time.Hour)))
+
+		fx := newTx("pi_fx", "cus_1")
+		fx.Amount, fx.Currency = quote.PresentmentAmount, quote.PresentmentCurrency
+		fx.SettlementAmount, fx.SettlementCurrency = quote.SettlementAmount, quote.SettlementCurrency
+		fx.FxRate, fx.FxQuoteID = quote.Rate, quote.ID
+		mustCreateTx(t, transactions, fx)
+		mustCreateTx(t, transactions, newTx("pi_plain", "cus_1"))
+
+		got := mustFindByIntent(t, transactions, "pi_fx")
+		if got.Amount != 1084 || got.Currency != "usd" || got.SettlementAmount != 1000 || got.SettlementCurrency != "eur" || got.FxRate != "1.0842" || got.FxQuoteID != quote.ID {
+			t.Fatalf("unexpected fx transaction %+v", got)
+		}
+		got = mustFindByIntent(t, transactions, "pi_plain")
+		if got.SettlementAmount != got.Amount || got.SettlementCurrency != got.Currency || got.FxRate != "1" || got.FxQuoteID != "" {
+			t.Fatalf("expected settlement to mirror the charge, got %+v", got)
+		}
+
+		again := newTx("pi_fx_again", "cus_1")
+		again.FxQuoteID = quote.ID
+		if err := transactions.Create(ctx, again); !errors.Is(err, ports.ErrConflict) {
+			t.Fatalf("expected ports.ErrConflict for a reused quote, got %v", err)
+		}
+	})
+}
+
+func newQuote(customer string, expiresAt time.Time) *model.FxQuote {
+	return &model.FxQuote{
+		CustomerID:          customer,
+		SettlementAmount:    1000,
+		SettlementCurrency:  "eur",
+		PresentmentAmount:   1084,
+		PresentmentCurrency: "usd",
+		Rate:                "1.0842",
+		ExpiresAt:           expiresAt,
+	}
+}
+
+func mustCreateQuote(t *testing.T, repo ports.IFxQuoteRepository, quote *model.FxQuote) *model.FxQuote {
+	t.Helper()
+	if err := repo.Create(context.Background(), quote); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return quote
+}
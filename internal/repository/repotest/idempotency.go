@@ -0,0 +1,153 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// IdempotencyRepository checks that a key is held by one request at a time
+func IdempotencyRepository(t *testing.T, newRepo func(t *testing.T) ports.IIdempotencyRepository) {
+	ctx := context.Background()
+
+	t.Run("reserve, complete and find", func(t *testing.T) {
+		repo := newRepo(t)
+		record := newIdempotencyRecord("key_1", time.Now().Add(time.Hour))
+		if err := repo.Reserve(ctx, record, time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		if record.CreatedAt.IsZero() {
+			t.Fatalf("expected created_at to be set, got %+v", record)
+		}
+
+		got, err := repo.FindByKey(ctx, "key_1")
+		if err != nil {
+			t.Fatalf("FindByKey: %v", err)
+		}
+		if got.Fingerprint != record.Fingerprint || got.ResponseStatus != 0 || got.ResponseBody != nil {
+			t.Fatalf("expected a running record, got %+v", got)
+		}
+
+		if err := repo.Complete(ctx, "key_1", 201, []byte(`{"data":{}}`)); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		got, err = repo.FindByKey(ctx, "key_1")
+		if err != nil {
+			t.Fatalf("FindByKey: %v", err)
+		}
+		if got.ResponseStatus != 201 || string(got.ResponseBody) != `{"data":{}}` {
+			t.Fatalf("expected the stored response, got %+v", got)
+		}
+
+		if _, err := repo.FindByKey(ctx, "key_missing"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+		if err := repo.Complete(ctx, "key_missing", 201, nil); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound completing a missing key, got %v", err)
+		}
+	})
+
+	t.Run("live key cannot be reserved twice", func(t *testing.T) {
+		repo := newRepo(t)
+		staleBefore := time.Now().Add(-time.Minute)
+		if err := repo.Reserve(ctx, newIdempotencyRecord("key_1", time.Now().Add(time.Hour)), staleBefore); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		if err := repo.Reserve(ctx, newIdempotencyRecord("key_1", time.Now().Add(time.Hour)), staleBefore); !errors.Is(err, ports.ErrConflict) {
+			t.Fatalf("expected ports.ErrConflict while running, got %v", err)
+		}
+
+		if err := repo.Complete(ctx, "key_1", 201, []byte(`{}`)); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		// A completed key is never stale, only expiry releases it
+		if err := repo.Reserve(ctx, newIdempotencyRecord("key_1", time.Now().Add(time.Hour)), time.Now().Add(time.Minute)); !errors.Is(err, ports.ErrConflict) {
+			t.Fatalf("expected ports.ErrConflict once completed, got %v", err)
+		}
+	})
+
+	t.Run("stale or expired key is taken over", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.Reserve(ctx, newIdempotencyRecord("key_stale", time.Now().Add(time.Hour)), time.Now()); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		takeover := newIdempotencyRecord("key_stale", time.Now().Add(time.Hour))
+		takeover.Fingerprint = otherFingerprint
+		if err := repo.Reserve(ctx, takeover, time.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("expected a stale reservation to be taken over, got %v", err)
+		}
+		got, err := repo.FindByKey(ctx, "key_stale")
+		if err != nil {
+			t.Fatalf("FindByKey: %v", err)
+		}
+		if got.Fingerprint != otherFingerprint {
+			t.Fatalf("expected the new fingerprint, got %+v", got)
+		}
+
+		if err := repo.Reserve(ctx, newIdempotencyRecord("key_expired", time.Now().Add(-time.Second)), time.Now()); err != nil {
+			t.Fatalf("Reserve: %v", err)
+		}
+		if err := repo.Complete(ctx, "key_expired", 201, []byte(`{}`)); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		again := newIdempotencyRecord("key_expired", time.Now().Add(time.Hour))
+		if err := repo.Reserve(ctx, again, time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("expected an expired key to be reserved again, got %v", err)
+		}
+		got, err = repo.FindByKey(ctx, "key_expired")
+		if err != nil {
+			t.Fatalf("FindByKey: %v", err)
+		}
+		if got.ResponseStatus != 0 || got.ResponseBody != nil {
+			t.Fatalf("expected the old response to be cleared, got %+v", got)
+		}
+	})
+
+	t.Run("delete and delete expired", func(t *testing.T) {
+		repo := newRepo(t)
+		staleBefore := time.Now().Add(-time.Minute)
+		for _, record := range []*model.IdempotencyRecord{
+			newIdempotencyRecord("key_live", time.Now().Add(time.Hour)),
+			newIdempotencyRecord("key_old_1", time.Now().Add(-time.Minute)),
+			newIdempotencyRecord("key_old_2", time.Now().Add(-time.Hour)),
+			newIdempotencyRecord("key_gone", time.Now().Add(time.Hour)),
+		} {
+			if err := repo.Reserve(ctx, record, staleBefore); err != nil {
+				t.Fatalf("Reserve %s: %v", record.Key, err)
+			}
+		}
+
+		if err := repo.Delete(ctx, "key_gone"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.FindByKey(ctx, "key_gone"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound after delete, got %v", err)
+		}
+
+		n, err := repo.DeleteExpired(ctx, time.Now())
+		if err != nil {
+			t.Fatalf("DeleteExpired: %v", err)
+		}
+		if n != 2 {
+			t.Fatalf("expected 2 expired keys, got %d", n)
+		}
+		if _, err := repo.FindByKey(ctx, "key_live"); err != nil {
+			t.Fatalf("expected the live key to survive, got %v", err)
+		}
+	})
+}
+
+// Fingerprints are sha256 hex, fixed width in Postgres
+const otherFingerprint = "9b1e4c7a0d3f6b9e2c5a8d1f4b7e0a3c6f9d2b5e8a1c4f7b0e3d6a9c2f5b8e1d"
+
+func newIdempotencyRecord(key string, expiresAt time.Time) *model.IdempotencyRecord {
+	return &model.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: "3f2a9c0d6b8e4f1a7c5d2e9b0a6f3c8d1e4b7a0c9f2d5e8b1a4c7f0d3e6b9a2c",
+		ExpiresAt:   expiresAt,
+	}
+}
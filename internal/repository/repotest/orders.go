@@ -0,0 +1,111 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// OrderRepository checks orders and the transactions linked to them
+func OrderRepository(t *testing.T, newRepos func(t *testing.T) (ports.IOrderRepository, ports.ITransactionRepository)) {
+	ctx := context.Background()
+
+	t.Run("create assigns id, reference and open status", func(t *testing.T) {
+		orders, _ := newRepos(t)
+		order := newOrder("cus_1")
+		if err := orders.Create(ctx, order); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if order.ID == "" || !strings.HasPrefix(order.InternalReference, "ORD-") || order.CreatedAt.IsZero() {
+			t.Fatalf("expected generated fields, got %+v", order)
+		}
+		if order.Status != ports.OrderOpen {
+			t.Fatalf("expected default status open, got %s", order.Status)
+		}
+
+		got, err := orders.FindByID(ctx, order.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.AmountDue != order.AmountDue || got.CustomerID != "cus_1" || got.Metadata["cart"] != "c_1" {
+			t.Fatalf("expected %+v, got %+v", order, got)
+		}
+	})
+
+	t.Run("find missing returns not found", func(t *testing.T) {
+		orders, _ := newRepos(t)
+		if _, err := orders.FindByID(ctx, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("update status persists and checks the status", func(t *testing.T) {
+		orders, _ := newRepos(t)
+		order := mustCreateOrder(t, orders, newOrder("cus_1"))
+
+		if err := orders.UpdateStatus(ctx, order.ID, ports.OrderPartiallyPaid); err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+		got, err := orders.FindByID(ctx, order.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if got.Status != ports.OrderPartiallyPaid || !got.UpdatedAt.After(got.CreatedAt) {
+			t.Fatalf("unexpected order %+v", got)
+		}
+
+		if err := orders.UpdateStatus(ctx, order.ID, "bogus"); err == nil {
+			t.Fatal("expected order_status check violation")
+		}
+		if err := orders.UpdateStatus(ctx, "00000000-0000-0000-0000-000000000000", ports.OrderPaid); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("transactions are found by order", func(t *testing.T) {
+		orders, transactions := newRepos(t)
+		order := mustCreateOrder(t, orders, newOrder("cus_1"))
+
+		declined := newTx("pi_order_declined", "cus_1")
+		declined.OrderID = order.ID
+		mustCreateTx(t, transactions, declined)
+		paid := newTx("pi_order_paid", "cus_1")
+		paid.OrderID = order.ID
+		mustCreateTx(t, transactions, paid)
+		mustCreateTx(t, transactions, newTx("pi_standalone", "cus_1"))
+
+		if got := mustFindByIntent(t, transactions, "pi_order_paid"); got.OrderID != order.ID {
+			t.Fatalf("expected order %s, got %q", order.ID, got.OrderID)
+		}
+		if got := mustFindByIntent(t, transactions, "pi_standalone"); got.OrderID != "" {
+			t.Fatalf("expected no order, got %q", got.OrderID)
+		}
+
+		txs, err := transactions.FindByColumn(ctx, "order_id", order.ID)
+		if err != nil {
+			t.Fatalf("FindByColumn: %v", err)
+		}
+		assertIntents(t, txs, "pi_order_paid", "pi_order_declined")
+	})
+}
+
+func newOrder(customer string) *model.Order {
+	return &model.Order{
+		CustomerID: customer,
+		AmountDue:  5000,
+		Currency:   "eur",
+		Metadata:   map[string]string{"cart": "c_1"},
+	}
+}
+
+func mustCreateOrder(t *testing.T, repo ports.IOrderRepository, order *model.Order) *model.Order {
+	t.Helper()
+	if err := repo.Create(context.Background(), order); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return order
+}
@@ -0,0 +1,139 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// PaymentMethodRepository checks the payment_methods semantics
+func PaymentMethodRepository(t *testing.T, newRepo func(t *testing.T) ports.IPaymentMethodRepository) {
+	ctx := context.Background()
+
+	t.Run("create applies column defaults", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreatePM(t, repo, newPM("cus_1", "pm_default"))
+
+		pm, err := repo.FindByID(ctx, "pm_default")
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if pm.Status != ports.PaymentMethodStatus || pm.Type != "none" {
+			t.Fatalf("expected defaults active/none, got %s/%s", pm.Status, pm.Type)
+		}
+		if pm.CustomerID != "cus_1" || pm.Provider != "stripe" || pm.CreatedAt.IsZero() {
+			t.Fatalf("unexpected payment method %+v", pm)
+		}
+	})
+
+	t.Run("create rejects duplicate customer method", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreatePM(t, repo, newPM("cus_1", "pm_dup"))
+		if err := repo.Create(ctx, newPM("cus_1", "pm_dup")); !errors.Is(err, ports.ErrConflict) {
+			t.Fatalf("expected ports.ErrConflict, got %v", err)
+		}
+	})
+
+	t.Run("create rejects unknown providers", func(t *testing.T) {
+		repo := newRepo(t)
+		pm := newPM("cus_1", "pm_bad")
+		pm.Provider = "unknown"
+		if err := repo.Create(ctx, pm); err == nil {
+			t.Fatal("expected pm_provider check violation")
+		}
+	})
+
+	t.Run("find missing returns not found", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.FindByID(ctx, "pm_missing"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("update status reports no matching payment method", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreatePM(t, repo, newPM("cus_1", "pm_other"))
+
+		err := repo.UpdateStatus(ctx, "cus_2", "pm_other", ports.PaymentMethodDisabled)
+		if !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("update status disables one method", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreatePM(t, repo, newPM("cus_1", "pm_a"))
+		mustCreatePM(t, repo, newPM("cus_1", "pm_b"))
+
+		if err := repo.UpdateStatus(ctx, "cus_1", "pm_a", ports.PaymentMethodDisabled); err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+
+		methods, err := repo.FindByCustomer(ctx, "cus_1")
+		if err != nil {
+			t.Fatalf("FindByCustomer: %v", err)
+		}
+		statuses := map[string]string{}
+		for _, pm := range methods {
+			statuses[pm.ID] = pm.Status
+		}
+		if statuses["pm_a"] != ports.PaymentMethodDisabled || statuses["pm_b"] != ports.PaymentMethodStatus {
+			t.Fatalf("unexpected statuses %v", statuses)
+		}
+	})
+
+	t.Run("find by customer orders newest first", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreatePM(t, repo, newPM("cus_ord", "pm_1"))
+		mustCreatePM(t, repo, newPM("cus_ord", "pm_2"))
+		mustCreatePM(t, repo, newPM("cus_other", "pm_3"))
+
+		methods, err := repo.FindByCustomer(ctx, "cus_ord")
+		if err != nil {
+			t.Fatalf("FindByCustomer: %v", err)
+		}
+		if len(methods) != 2 || methods[0].ID != "pm_2" || methods[1].ID != "pm_1" {
+			t.Fatalf("expected pm_2, pm_1 (created_at DESC), got %+v", methods)
+		}
+	})
+
+	t.Run("find by status honours since", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreatePM(t, repo, newPM("cus_1", "pm_active"))
+
+		methods, err := repo.FindByStatus(ctx, ports.PaymentMethodStatus, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("FindByStatus: %v", err)
+		}
+		if len(methods) != 1 || methods[0].ID != "pm_active" {
+			t.Fatalf("expected pm_active, got %+v", methods)
+		}
+
+		methods, err = repo.FindByStatus(ctx, ports.PaymentMethodStatus, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("FindByStatus: %v", err)
+		}
+		if len(methods) != 0 {
+			t.Fatalf("expected no methods, got %+v", methods)
+		}
+	})
+}
+
+func newPM(customer string, id string) *model.PaymentMethod {
+	return &model.PaymentMethod{
+		ID:         id,
+		CustomerID: customer,
+		Provider:   "stripe",
+	}
+}
+
+func mustCreatePM(t *testing.T, repo ports.IPaymentMethodRepository, pm *model.PaymentMethod) {
+	t.Helper()
+	if err := repo.Create(context.Background(), pm); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package repotest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// RefundRepository checks refunds against their parent transactions
+func RefundRepository(t *testing.T, newRepos func(t *testing.T) (ports.ITransactionRepository, ports.IRefundRepository)) {
+	ctx := context.Background()
+
+	t.Run("create assigns id, reference and pending status", func(t *testing.T) {
+		transactions, refunds := newRepos(t)
+		mustCreateTx(t, transactions, newTx("pi_refund", "cus_1"))
+		tx := mustFindByIntent(t, transactions, "pi_refund")
+
+		rf := &model.Refund{TransactionID: tx.ID, Amount: 500, Reason: "duplicate"}
+		if err := refunds.Create(ctx, rf); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if rf.ID == "" || !strings.HasPrefix(rf.InternalReference, "REF-") || rf.ProcessedAt.IsZero() {
+			t.Fatalf("expected generated fields, got %+v", rf)
+		}
+		if rf.RefundStatus != "pending" {
+			t.Fatalf("expected default status pending, got %s", rf.RefundStatus)
+		}
+	})
+
+	t.Run("create requires an existing transaction", func(t *testing.T) {
+		_, refunds := newRepos(t)
+		rf := &model.Refund{TransactionID: "00000000-0000-0000-0000-000000000000", Amount: 500}
+		if err := refunds.Create(ctx, rf); err == nil {
+			t.Fatal("expected foreign key violation")
+		}
+	})
+
+	t.Run("find by transaction orders newest first", func(t *testing.T) {
+		transactions, refunds := newRepos(t)
+		mustCreateTx(t, transactions, newTx("pi_refunds", "cus_1"))
+		tx := mustFindByIntent(t, transactions, "pi_refunds")
+
+		for _, amount := range []int64{100, 200} {
+			if err := refunds.Create(ctx, &model.Refund{TransactionID: tx.ID, Amount: amount, RefundStatus: "succeeded"}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		got, err := refunds.FindByTransaction(ctx, tx.ID)
+		if err != nil {
+			t.Fatalf("FindByTransaction: %v", err)
+		}
+		if len(got) != 2 || got[0].Amount != 200 || got[1].Amount != 100 {
+			t.Fatalf("expected refunds 200, 100, got %+v", got)
+		}
+	})
+}
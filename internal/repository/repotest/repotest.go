@@ -0,0 +1,57 @@
+// Package repotest is a conformance suite shared by every repository
+// implementation. Run it from a test with the memory repositories, and with
+// the Postgres ones using PostgresPool:
+//
+//	repotest.TransactionRepository(t, func(t *testing.T) ports.ITransactionRepository {
+//		return memory.NewTransactionRepository()
+//	})
+package repotest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/migrate"
+)
+
+// PostgresPool connects to TEST_DATABASE_URL, migrates it and empties the
+// payment tables. The test is skipped when the variable is not set.
+func PostgresPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping Postgres conformance")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := migrate.New(pool)
+	if err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	defer migrator.Close()
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	Truncate(t, pool)
+	return pool
+}
+
+// Truncate empties the payment tables between subtests
+func Truncate(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+
+	if _, err := pool.Exec(context.Background(), `TRUNCATE refunds, transactions, payment_methods, charge_attempts, orders, fx_quotes, fx_rates, idempotency_keys CASCADE`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
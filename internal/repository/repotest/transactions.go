@@ -0,0 +1,311 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+var paymentReference = regexp.MustCompile(`^PAY-(\d+)$`)
+
+// TransactionRepository checks the semantics PaymentService relies on.
+// newRepo must return an empty repository for every call.
+func TransactionRepository(t *testing.T, newRepo func(t *testing.T) ports.ITransactionRepository) {
+	ctx := context.Background()
+
+	t.Run("create generates sequential internal references", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateTx(t, repo, newTx("pi_ref_1", "cus_1"))
+		mustCreateTx(t, repo, newTx("pi_ref_2", "cus_1"))
+
+		first := mustFindByIntent(t, repo, "pi_ref_1")
+		second := mustFindByIntent(t, repo, "pi_ref_2")
+
+		a, b := referenceNumber(t, first.InternalReference), referenceNumber(t, second.InternalReference)
+		if a < 1001 || b <= a {
+			t.Fatalf("expected increasing references from PAY-1001, got %s then %s", first.InternalReference, second.InternalReference)
+		}
+		if first.ID == "" || first.ID == second.ID {
+			t.Fatalf("expected distinct generated ids, got %q and %q", first.ID, second.ID)
+		}
+	})
+
+	t.Run("create rejects duplicate internal reference", func(t *testing.T) {
+		repo := newRepo(t)
+		tx := newTx("pi_dup_1", "cus_1")
+		tx.InternalReference = "PAY-TEST-1"
+		mustCreateTx(t, repo, tx)
+
+		dup := newTx("pi_dup_2", "cus_1")
+		dup.InternalReference = "PAY-TEST-1"
+		if err := repo.Create(ctx, dup); !errors.Is(err, ports.ErrConflict) {
+			t.Fatalf("expected ports.ErrConflict on internal_reference, got %v", err)
+		}
+	})
+
+	t.Run("create rejects non-positive amounts", func(t *testing.T) {
+		repo := newRepo(t)
+		tx := newTx("pi_zero", "cus_1")
+		tx.Amount = 0
+		if err := repo.Create(ctx, tx); err == nil {
+			t.Fatal("expected amount check violation")
+		}
+	})
+
+	t.Run("find round-trips stored fields", func(t *testing.T) {
+		repo := newRepo(t)
+		save := true
+		tx := newTx("pi_round", "cus_round")
+		tx.SavePaymentMethod = &save
+		tx.Metadata = map[string]string{"order_id": "ord_1"}
+		mustCreateTx(t, repo, tx)
+
+		got := mustFindByIntent(t, repo, "pi_round")
+		byID, err := repo.FindByID(ctx, got.ID)
+		if err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+
+		for _, found := range []*model.Transaction{got, byID} {
+			if found.Amount != tx.Amount || found.Currency != tx.Currency || found.CustomerID != tx.CustomerID || found.TxStatus != tx.TxStatus {
+				t.Fatalf("unexpected transaction %+v", found)
+			}
+			if found.SavePaymentMethod == nil || !*found.SavePaymentMethod {
+				t.Fatalf("expected save_payment_method true, got %v", found.SavePaymentMethod)
+			}
+			if found.Metadata["order_id"] != "ord_1" {
+				t.Fatalf("expected metadata to round-trip, got %v", found.Metadata)
+			}
+			if found.CreatedAt.IsZero() || found.UpdatedAt.IsZero() {
+				t.Fatal("expected timestamps to be set")
+			}
+		}
+	})
+
+	t.Run("find missing returns no rows", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.FindByPaymentIntent(ctx, "pi_missing"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+		if _, err := repo.FindByID(ctx, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("update status requires a filter", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.UpdateStatus(ctx, ports.PaymentSucceeded, nil); err == nil {
+			t.Fatal("expected error without filters")
+		}
+	})
+
+	t.Run("update status reports no matching transaction", func(t *testing.T) {
+		repo := newRepo(t)
+		err := repo.UpdateStatus(ctx, ports.PaymentSucceeded, map[string]interface{}{"payment_intent_id": "pi_none"})
+		if !errors.Is(err, ports.ErrNotFound) || !strings.Contains(err.Error(), "no matching transaction found") {
+			t.Fatalf("expected no matching transaction error, got %v", err)
+		}
+	})
+
+	t.Run("update status changes only matching rows", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateTx(t, repo, newTx("pi_upd_1", "cus_1"))
+		mustCreateTx(t, repo, newTx("pi_upd_2", "cus_1"))
+
+		err := repo.UpdateStatus(ctx, ports.PaymentSucceeded, map[string]interface{}{"payment_intent_id": "pi_upd_1"})
+		if err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+
+		if got := mustFindByIntent(t, repo, "pi_upd_1"); got.TxStatus != ports.PaymentSucceeded {
+			t.Fatalf("expected %s, got %s", ports.PaymentSucceeded, got.TxStatus)
+		}
+		if got := mustFindByIntent(t, repo, "pi_upd_2"); got.TxStatus != ports.Pending {
+			t.Fatalf("expected untouched row to stay %s, got %s", ports.Pending, got.TxStatus)
+		}
+	})
+
+	t.Run("create fills generated fields", func(t *testing.T) {
+		repo := newRepo(t)
+		tx := newTx("pi_generated", "cus_1")
+		mustCreateTx(t, repo, tx)
+
+		if tx.ID == "" || paymentReference.FindString(tx.InternalReference) == "" || tx.CreatedAt.IsZero() {
+			t.Fatalf("expected id, reference and timestamps on the model, got %+v", tx)
+		}
+		if got := mustFindByIntent(t, repo, "pi_generated"); got.ID != tx.ID || got.InternalReference != tx.InternalReference {
+			t.Fatalf("expected %s/%s, got %s/%s", tx.ID, tx.InternalReference, got.ID, got.InternalReference)
+		}
+	})
+
+	t.Run("write-ahead transaction gets its intent attached once", func(t *testing.T) {
+		repo := newRepo(t)
+		tx := newTx("", "cus_1")
+		mustCreateTx(t, repo, tx)
+
+		got, err := repo.FindByReference(ctx, tx.InternalReference)
+		if err != nil {
+			t.Fatalf("FindByReference: %v", err)
+		}
+		if got.ID != tx.ID || got.PaymentIntentID != "" {
+			t.Fatalf("expected %s without intent, got %+v", tx.ID, got)
+		}
+		if _, err := repo.FindByPaymentIntent(ctx, ""); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected pending rows not to match an empty intent, got %v", err)
+		}
+
+		if err := repo.AttachPaymentIntent(ctx, tx.ID, "pi_attached"); err != nil {
+			t.Fatalf("AttachPaymentIntent: %v", err)
+		}
+		if err := repo.AttachPaymentIntent(ctx, tx.ID, "pi_attached"); err != nil {
+			t.Fatalf("expected attaching the same intent again to succeed, got %v", err)
+		}
+		if err := repo.AttachPaymentIntent(ctx, tx.ID, "pi_other"); !errors.Is(err, ports.ErrConflict) {
+			t.Fatalf("expected ports.ErrConflict for another intent, got %v", err)
+		}
+		if got := mustFindByIntent(t, repo, "pi_attached"); got.ID != tx.ID {
+			t.Fatalf("expected %s, got %s", tx.ID, got.ID)
+		}
+	})
+
+	t.Run("attach and find by reference report missing rows", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.FindByReference(ctx, "PAY-0"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+		if err := repo.AttachPaymentIntent(ctx, "00000000-0000-0000-0000-000000000000", "pi_x"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected ports.ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("find by column validates column names", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.FindByColumn(ctx, "customer_id; DROP TABLE transactions", "x"); err == nil {
+			t.Fatal("expected invalid column error")
+		}
+	})
+
+	t.Run("find by column orders by created_at desc", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, intent := range []string{"pi_ord_1", "pi_ord_2", "pi_ord_3"} {
+			mustCreateTx(t, repo, newTx(intent, "cus_order"))
+		}
+		mustCreateTx(t, repo, newTx("pi_other", "cus_other"))
+
+		txs, err := repo.FindByColumn(ctx, "customer_id", "cus_order")
+		if err != nil {
+			t.Fatalf("FindByColumn: %v", err)
+		}
+		assertIntents(t, txs, "pi_ord_3", "pi_ord_2", "pi_ord_1")
+	})
+
+	t.Run("find by status honours since", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreateTx(t, repo, newTx("pi_status_1", "cus_1"))
+
+		txs, err := repo.FindByStatus(ctx, ports.Pending, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("FindByStatus: %v", err)
+		}
+		assertIntents(t, txs, "pi_status_1")
+
+		txs, err = repo.FindByStatus(ctx, ports.Pending, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("FindByStatus: %v", err)
+		}
+		assertIntents(t, txs)
+	})
+	t.Run("provider and routing rule are stored", func(t *testing.T) {
+		repo := newRepo(t)
+		routed := newTx("pi_routed", "cus_1")
+		routed.Provider, routed.RoutingRule = "stripe", "large-eur-cards"
+		mustCreateTx(t, repo, routed)
+		mustCreateTx(t, repo, newTx("pi_unrouted", "cus_1"))
+
+		if got := mustFindByIntent(t, repo, "pi_routed"); got.Provider != "stripe" || got.RoutingRule != "large-eur-cards" {
+			t.Fatalf("expected stripe by large-eur-cards, got %q by %q", got.Provider, got.RoutingRule)
+		}
+		if got := mustFindByIntent(t, repo, "pi_unrouted"); got.Provider != "" || got.RoutingRule != "" {
+			t.Fatalf("expected no provider or rule, got %q by %q", got.Provider, got.RoutingRule)
+		}
+
+		txs, err := repo.FindByColumn(ctx, "routing_rule", "large-eur-cards")
+		if err != nil {
+			t.Fatalf("FindByColumn: %v", err)
+		}
+		assertIntents(t, txs, "pi_routed")
+	})
+
+	t.Run("provider attempts round-trip in order", func(t *testing.T) {
+		repo := newRepo(t)
+		charged := newTx("pi_failed_over", "cus_1")
+		charged.ProviderAttempts = []model.ProviderAttempt{
+			{Provider: "stripe", AttemptID: "att_1", Outcome: "outage", Error: "Direct charge failed #acc3"},
+			{Provider: "fake", AttemptID: "att_2", Outcome: "charged"},
+		}
+		mustCreateTx(t, repo, charged)
+		mustCreateTx(t, repo, newTx("pi_single", "cus_1"))
+
+		got := mustFindByIntent(t, repo, "pi_failed_over")
+		if len(got.ProviderAttempts) != 2 || got.ProviderAttempts[0] != charged.ProviderAttempts[0] || got.ProviderAttempts[1] != charged.ProviderAttempts[1] {
+			t.Fatalf("expected %+v, got %+v", charged.ProviderAttempts, got.ProviderAttempts)
+		}
+		if got := mustFindByIntent(t, repo, "pi_single"); len(got.ProviderAttempts) != 0 {
+			t.Fatalf("expected no provider attempts, got %+v", got.ProviderAttempts)
+		}
+	})
+}
+
+func newTx(intent string, customer string) *model.Transaction {
+	return &model.Transaction{
+		Amount:          1500,
+		Currency:        "eur",
+		PaymentIntentID: intent,
+		TxStatus:        ports.Pending,
+		CustomerID:      customer,
+	}
+}
+
+func mustCreateTx(t *testing.T, repo ports.ITransactionRepository, tx *model.Transaction) {
+	t.Helper()
+	if err := repo.Create(context.Background(), tx); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+}
+
+func mustFindByIntent(t *testing.T, repo ports.ITransactionRepository, intent string) *model.Transaction {
+	t.Helper()
+	tx, err := repo.FindByPaymentIntent(context.Background(), intent)
+	if err != nil {
+		t.Fatalf("FindByPaymentIntent(%s): %v", intent, err)
+	}
+	return tx
+}
+
+func referenceNumber(t *testing.T, ref string) int {
+	t.Helper()
+	m := paymentReference.FindStringSubmatch(ref)
+	if m == nil {
+		t.Fatalf("internal reference %q does not match PAY-<n>", ref)
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+func assertIntents(t *testing.T, txs []model.Transaction, want ...string) {
+	t.Helper()
+	var got []string
+	for _, tx := range txs {
+		got = append(got, tx.PaymentIntentID)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected intents %v, got %v", want, got)
+	}
+}
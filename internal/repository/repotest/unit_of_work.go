@@ -0,0 +1,88 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// UnitOfWork checks that writes across repositories commit or roll back
+// together. repos must read outside of any unit.
+func UnitOfWork(t *testing.T, newUoW func(t *testing.T) (ports.IUnitOfWork, ports.Repositories)) {
+	ctx := context.Background()
+
+	t.Run("commits every write", func(t *testing.T) {
+		uow, repos := newUoW(t)
+		err := uow.Do(ctx, func(tx ports.Repositories) error {
+			if err := tx.Transactions.Create(ctx, newTx("pi_uow_ok", "cus_uow")); err != nil {
+				return err
+			}
+			return tx.PaymentMethods.Create(ctx, newPM("cus_uow", "pm_uow_ok"))
+		})
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+
+		mustFindByIntent(t, repos.Transactions, "pi_uow_ok")
+		if _, err := repos.PaymentMethods.FindByID(ctx, "pm_uow_ok"); err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+	})
+
+	t.Run("rolls back every write on error", func(t *testing.T) {
+		uow, repos := newUoW(t)
+		mustCreateTx(t, repos.Transactions, newTx("pi_uow_existing", "cus_uow"))
+		existing := mustFindByIntent(t, repos.Transactions, "pi_uow_existing")
+
+		boom := errors.New("boom")
+		err := uow.Do(ctx, func(tx ports.Repositories) error {
+			if err := tx.Transactions.Create(ctx, newTx("pi_uow_rollback", "cus_uow")); err != nil {
+				return err
+			}
+			if err := tx.Transactions.UpdateStatus(ctx, ports.PaymentSucceeded, map[string]interface{}{"id": existing.ID}); err != nil {
+				return err
+			}
+			if err := tx.PaymentMethods.Create(ctx, newPM("cus_uow", "pm_uow_rollback")); err != nil {
+				return err
+			}
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("expected the unit's error, got %v", err)
+		}
+
+		if _, err := repos.Transactions.FindByPaymentIntent(ctx, "pi_uow_rollback"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected the transaction to be rolled back, got %v", err)
+		}
+		if _, err := repos.PaymentMethods.FindByID(ctx, "pm_uow_rollback"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected the payment method to be rolled back, got %v", err)
+		}
+		if got := mustFindByIntent(t, repos.Transactions, "pi_uow_existing"); got.TxStatus != ports.Pending {
+			t.Fatalf("expected the status update to be rolled back, got %s", got.TxStatus)
+		}
+	})
+
+	t.Run("rolls back and re-panics", func(t *testing.T) {
+		uow, repos := newUoW(t)
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected the panic to propagate")
+				}
+			}()
+			uow.Do(ctx, func(tx ports.Repositories) error {
+				if err := tx.Transactions.Create(ctx, newTx("pi_uow_panic", "cus_uow")); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		}()
+
+		if _, err := repos.Transactions.FindByPaymentIntent(ctx, "pi_uow_panic"); !errors.Is(err, ports.ErrNotFound) {
+			t.Fatalf("expected the transaction to be rolled back, got %v", err)
+		}
+	})
+}
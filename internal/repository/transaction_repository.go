@@ -1,7 +1,6 @@
 package repository
 
 import (
-    "log"
 	"fmt"
 	"time"
     "errors"
@@ -12,6 +11,7 @@ import (
     "github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
 )
 
 // Combines all needed interfaces
@@ -87,6 +87,55 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *model.Transactio
         pos++
     }
 
+    if tx.OrderID != "" {
+        fields = append(fields, "order_id")
+        values = append(values, tx.OrderID)
+        params = append(params, fmt.Sprintf("$%d", pos))
+        pos++
+    }
+
+    if tx.SettlementCurrency != "" {
+        fields = append(fields, "settlement_amount", "settlement_currency")
+        values = append(values, tx.SettlementAmount, tx.SettlementCurrency)
+        params = append(params, fmt.Sprintf("$%d", pos), fmt.Sprintf("$%d", pos+1))
+        pos += 2
+    }
+
+    if tx.FxRate != "" {
+        fields = append(fields, "fx_rate")
+        values = append(values, tx.FxRate)
+        params = append(params, fmt.Sprintf("$%d::text::numeric", pos))
+        pos++
+    }
+
+    if tx.FxQuoteID != "" {
+        fields = append(fields, "fx_quote_id")
+        values = append(values, tx.FxQuoteID)
+        params = append(params, fmt.Sprintf("$%d", pos))
+        pos++
+    }
+
+    if tx.Provider != "" {
+        fields = append(fields, "provider")
+        values = append(values, tx.Provider)
+        params = append(params, fmt.Sprintf("$%d", pos))
+        pos++
+    }
+
+    if tx.RoutingRule != "" {
+        fields = append(fields, "routing_rule")
+        values = append(values, tx.RoutingRule)
+        params = append(params, fmt.Sprintf("$%d", pos))
+        pos++
+    }
+
+    if len(tx.ProviderAttempts) > 0 {
+        fields = append(fields, "provider_attempts")
+        values = append(values, tx.ProviderAttempts)
+        params = append(params, fmt.Sprintf("$%d", pos))
+        pos++
+    }
+
     if tx.SavePaymentMethod != nil {
         fields = append(fields, "save_payment_method")
         values = append(values, *tx.SavePaymentMethod)
@@ -104,17 +153,23 @@ func (r *TransactionRepository) Create(ctx context.Context, tx *model.Transactio
     // Build the query
     query := fmt.Sprintf(`
         INSERT INTO transactions (%s)
-        VALUES (%s)`,
+        VALUES (%s)
+        RETURNING id, internal_reference, created_at, updated_at`,
         strings.Join(fields, ", "),
         strings.Join(params, ", "),
     )
 
-    _, err := r.db.Exec(ctx, query, values...)
-    return err
+    err := r.db.QueryRow(ctx, query, values...).Scan(&tx.ID, &tx.InternalReference, &tx.CreatedAt, &tx.UpdatedAt)
+    if err != nil {
+        return fmt.Errorf("error creating transaction: %w", dbError(err))
+    }
+    return nil
 }
 
 func (r *TransactionRepository) FindByID(ctx context.Context, id string) (*model.Transaction, error) {
-	sql := `SELECT * FROM transactions WHERE id = $1`
+	sql := `SELECT id, internal_reference, amount, currency, COALESCE(payment_intent_id, ''), tx_status, customer_id, COALESCE(order_id::text, ''),
+               COALESCE(settlement_amount, amount), COALESCE(settlement_currency, currency), COALESCE(fx_rate::text, '1'), COALESCE(fx_quote_id::text, ''), COALESCE(provider, ''), COALESCE(routing_rule, ''), COALESCE(provider_attempts, '[]'), save_payment_method, created_at, updated_at, metadata
+        FROM transactions WHERE id = $1`
 	var tx model.Transaction
 	err := r.db.QueryRow(ctx, sql, id).Scan(
 		&tx.ID,
@@ -124,18 +179,28 @@ func (r *TransactionRepository) FindByID(ctx context.Context, id string) (*model
 		&tx.PaymentIntentID,
 		&tx.TxStatus,
 		&tx.CustomerID,
+		&tx.OrderID,
+		&tx.SettlementAmount,
+		&tx.SettlementCurrency,
+		&tx.FxRate,
+		&tx.FxQuoteID,
+		&tx.Provider,
+		&tx.RoutingRule,
+		&tx.ProviderAttempts,
+		&tx.SavePaymentMethod,
 		&tx.CreatedAt,
 		&tx.UpdatedAt,
 		&tx.Metadata,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting transaction: %w", err)
+		return nil, fmt.Errorf("error getting transaction: %w", dbError(err))
 	}
 	return &tx, nil
 }
 
 func (r *TransactionRepository) FindByPaymentIntent(ctx context.Context, id string) (*model.Transaction, error) {
-	sql := `SELECT id, internal_reference, amount, currency, payment_intent_id, tx_status, customer_id, save_payment_method, created_at, updated_at, metadata 
+	sql := `SELECT id, internal_reference, amount, currency, COALESCE(payment_intent_id, ''), tx_status, customer_id, COALESCE(order_id::text, ''),
+               COALESCE(settlement_amount, amount), COALESCE(settlement_currency, currency), COALESCE(fx_rate::text, '1'), COALESCE(fx_quote_id::text, ''), COALESCE(provider, ''), COALESCE(routing_rule, ''), COALESCE(provider_attempts, '[]'), save_payment_method, created_at, updated_at, metadata 
         FROM transactions WHERE payment_intent_id = $1`
 	var tx model.Transaction
 	err := r.db.QueryRow(ctx, sql, id).Scan(
@@ -146,22 +211,62 @@ func (r *TransactionRepository) FindByPaymentIntent(ctx context.Context, id stri
 		&tx.PaymentIntentID,
 		&tx.TxStatus,
 		&tx.CustomerID,
+		&tx.OrderID,
+		&tx.SettlementAmount,
+		&tx.SettlementCurrency,
+		&tx.FxRate,
+		&tx.FxQuoteID,
+		&tx.Provider,
+		&tx.RoutingRule,
+		&tx.ProviderAttempts,
         &tx.SavePaymentMethod,
 		&tx.CreatedAt,
 		&tx.UpdatedAt,
 		&tx.Metadata,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("error getting transaction: %w", err)
+		return nil, fmt.Errorf("error getting transaction: %w", dbError(err))
+	}
+	return &tx, nil
+}
+
+func (r *TransactionRepository) FindByReference(ctx context.Context, reference string) (*model.Transaction, error) {
+	sql := `SELECT id, internal_reference, amount, currency, COALESCE(payment_intent_id, ''), tx_status, customer_id, COALESCE(order_id::text, ''),
+               COALESCE(settlement_amount, amount), COALESCE(settlement_currency, currency), COALESCE(fx_rate::text, '1'), COALESCE(fx_quote_id::text, ''), COALESCE(provider, ''), COALESCE(routing_rule, ''), COALESCE(provider_attempts, '[]'), save_payment_method, created_at, updated_at, metadata
+        FROM transactions WHERE internal_reference = $1`
+	var tx model.Transaction
+	err := r.db.QueryRow(ctx, sql, reference).Scan(
+		&tx.ID,
+		&tx.InternalReference,
+		&tx.Amount,
+		&tx.Currency,
+		&tx.PaymentIntentID,
+		&tx.TxStatus,
+		&tx.CustomerID,
+		&tx.OrderID,
+		&tx.SettlementAmount,
+		&tx.SettlementCurrency,
+		&tx.FxRate,
+		&tx.FxQuoteID,
+		&tx.Provider,
+		&tx.RoutingRule,
+		&tx.ProviderAttempts,
+		&tx.SavePaymentMethod,
+		&tx.CreatedAt,
+		&tx.UpdatedAt,
+		&tx.Metadata,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting transaction: %w", dbError(err))
 	}
-    log.Printf("Transaction-info-inside: %+v", &tx)
 	return &tx, nil
 }
 
 func (r *TransactionRepository) FindByStatus(ctx context.Context, status string, since time.Time) ([]model.Transaction, error) {
 	    const sql = `
         SELECT id, internal_reference, amount, currency, 
-               payment_intent_id, status, customer_id,
+               COALESCE(payment_intent_id, ''), tx_status, customer_id, COALESCE(order_id::text, ''),
+               COALESCE(settlement_amount, amount), COALESCE(settlement_currency, currency), COALESCE(fx_rate::text, '1'), COALESCE(fx_quote_id::text, ''), COALESCE(provider, ''), COALESCE(routing_rule, ''), COALESCE(provider_attempts, '[]'),
                created_at, updated_at, metadata
         FROM transactions
         WHERE tx_status = $1 AND created_at >= $2
@@ -185,6 +290,14 @@ func (r *TransactionRepository) FindByStatus(ctx context.Context, status string,
             &tx.PaymentIntentID,
             &tx.TxStatus,
             &tx.CustomerID,
+            &tx.OrderID,
+            &tx.SettlementAmount,
+            &tx.SettlementCurrency,
+            &tx.FxRate,
+            &tx.FxQuoteID,
+            &tx.Provider,
+            &tx.RoutingRule,
+            &tx.ProviderAttempts,
             &tx.CreatedAt,
             &tx.UpdatedAt,
             &tx.Metadata,
@@ -237,39 +350,30 @@ func (r *TransactionRepository) UpdateStatus(ctx context.Context, status string,
 
     // Check if any rows were updated (pgx uses 'tag.RowsAffected()')
     if tag.RowsAffected() == 0 {
-        return fmt.Errorf("no matching transaction found")
+        return fmt.Errorf("no matching transaction found: %w", ports.ErrNotFound)
     }
 
     return nil
 }
 
-func (r *TransactionRepository) WithTransaction(ctx context.Context, 
-    fn func(*TransactionRepository) error,
-) error {
-    // Begin a transaction (default options)
-    tx, err := r.db.Begin(ctx)
+// Sets the provider intent on a write-ahead transaction. Attaching the same
+// intent twice is a no-op, a different one is a conflict.
+func (r *TransactionRepository) AttachPaymentIntent(ctx context.Context, id string, paymentIntentID string) error {
+    const sql = `
+        UPDATE transactions SET payment_intent_id = $2, updated_at = NOW()
+        WHERE id = $1 AND (payment_intent_id IS NULL OR payment_intent_id = $2)`
+
+    tag, err := r.db.Exec(ctx, sql, id, paymentIntentID)
     if err != nil {
-        return fmt.Errorf("failed to begin transaction: %w", err)
+        return fmt.Errorf("failed to attach payment intent: %w", dbError(err))
     }
-
-    // Create a transaction-scoped repository
-    txRepo := &TransactionRepository{db: tx}
-
-    defer func() {
-        if p := recover(); p != nil {
-            tx.Rollback(ctx)
-            panic(p) // Re-throw panic after cleanup
-        }
-    }()
-
-    if err := fn(txRepo); err != nil {
-        if rbErr := tx.Rollback(ctx); rbErr != nil {
-            return fmt.Errorf("transaction error: %v, rollback failed: %w", err, rbErr)
+    if tag.RowsAffected() == 0 {
+        if _, err := r.FindByID(ctx, id); err != nil {
+            return fmt.Errorf("failed to attach payment intent: %w", err)
         }
-        return err
+        return fmt.Errorf("failed to attach payment intent: %w: transaction %s already has another intent", ports.ErrConflict, id)
     }
-
-    return tx.Commit(ctx)
+    return nil
 }
 
 func (r *TransactionRepository) FindByColumn(ctx context.Context, column string, value interface{}) ([]model.Transaction, error) {
@@ -282,6 +386,11 @@ func (r *TransactionRepository) FindByColumn(ctx context.Context, column string,
         "payment_intent_id":  true,
         "tx_status":           true,
         "customer_id":         true,
+        "order_id":            true,
+        "settlement_currency": true,
+        "fx_quote_id":         true,
+        "provider":            true,
+        "routing_rule":        true,
         "save_payment_method": true,
         "created_at":          true,
         "updated_at":          true,
@@ -294,7 +403,8 @@ func (r *TransactionRepository) FindByColumn(ctx context.Context, column string,
 
     sql := fmt.Sprintf(`
         SELECT id, internal_reference, amount, currency, 
-               payment_intent_id, tx_status, customer_id,
+               COALESCE(payment_intent_id, ''), tx_status, customer_id, COALESCE(order_id::text, ''),
+               COALESCE(settlement_amount, amount), COALESCE(settlement_currency, currency), COALESCE(fx_rate::text, '1'), COALESCE(fx_quote_id::text, ''), COALESCE(provider, ''), COALESCE(routing_rule, ''), COALESCE(provider_attempts, '[]'),
                save_payment_method, created_at, updated_at, metadata
         FROM transactions
         WHERE %s = $1
@@ -317,6 +427,14 @@ func (r *TransactionRepository) FindByColumn(ctx context.Context, column string,
             &tx.PaymentIntentID,
             &tx.TxStatus,
             &tx.CustomerID,
+            &tx.OrderID,
+            &tx.SettlementAmount,
+            &tx.SettlementCurrency,
+            &tx.FxRate,
+            &tx.FxQuoteID,
+            &tx.Provider,
+            &tx.RoutingRule,
+            &tx.ProviderAttempts,
             &tx.SavePaymentMethod,
             &tx.CreatedAt,
             &tx.UpdatedAt,
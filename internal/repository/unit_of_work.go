@@ -0,0 +1,92 @@
+package repository
+
+import (
+    "fmt"
+    "time"
+    "errors"
+    "context"
+    "math/rand"
+
+    "github.com/jackc/pgx/v5"
+    "github.com/jackc/pgx/v5/pgconn"
+    "github.com/jackc/pgx/v5/pgxpool"
+    "github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+const (
+    serializationFailure = "40001"
+    deadlockDetected     = "40P01"
+
+    uowMaxAttempts = 4
+    uowBaseBackoff = 20 * time.Millisecond
+)
+
+// UnitOfWork runs a function against repositories sharing one SERIALIZABLE
+// transaction, retrying the whole function when Postgres asks for it
+type UnitOfWork struct {
+    pool *pgxpool.Pool
+}
+
+func NewUnitOfWork(pool *pgxpool.Pool) *UnitOfWork {
+    return &UnitOfWork{pool: pool}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(repos ports.Repositories) error) error {
+    var err error
+    for attempt := 1; attempt <= uowMaxAttempts; attempt++ {
+        if err = u.attempt(ctx, fn); err == nil || !retryable(err) {
+            return err
+        }
+
+        // Jittered exponential backoff so competing writers spread out
+        backoff := uowBaseBackoff << (attempt - 1)
+        backoff += time.Duration(rand.Int63n(int64(backoff)))
+        select {
+        case <-ctx.Done():
+            return fmt.Errorf("unit of work aborted after %d attempts: %w", attempt, err)
+        case <-time.After(backoff):
+        }
+    }
+    return fmt.Errorf("unit of work failed after %d attempts: %w", uowMaxAttempts, err)
+}
+
+func (u *UnitOfWork) attempt(ctx context.Context, fn func(repos ports.Repositories) error) (err error) {
+    tx, err := u.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+    if err != nil {
+        return fmt.Errorf("failed to begin transaction: %w", err)
+    }
+
+    defer func() {
+        if p := recover(); p != nil {
+            tx.Rollback(ctx)
+            panic(p) // Re-throw panic after cleanup
+        }
+    }()
+
+    repos := ports.Repositories{
+        Transactions:   &TransactionRepository{db: tx},
+        PaymentMethods: &PaymentMethodRepository{db: tx},
+        Refunds:        &RefundRepository{db: tx},
+        ChargeAttempts: &ChargeAttemptRepository{db: tx},
+        Orders:         &OrderRepository{db: tx},
+        FxQuotes:       &FxQuoteRepository{db: tx},
+    }
+
+    if err := fn(repos); err != nil {
+        if rbErr := tx.Rollback(ctx); rbErr != nil {
+            return fmt.Errorf("transaction error: %w, rollback failed: %v", err, rbErr)
+        }
+        return err
+    }
+
+    return tx.Commit(ctx)
+}
+
+// Serialization failures and deadlocks are safe to retry from the start
+func retryable(err error) bool {
+    var pgErr *pgconn.PgError
+    if !errors.As(err, &pgErr) {
+        return false
+    }
+    return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
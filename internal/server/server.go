@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	MaxBodyBytes      int64
+	// Time between failing readiness and closing the listener, so the ALB deregisters the task
+	DrainDelay time.Duration
+	// Hard deadline for in-flight requests and workers once draining is over.
+	// Requests may use half of it, workers whatever is left.
+	ShutdownTimeout time.Duration
+}
+
+// Server owns the HTTP listener and the background workers sharing its lifecycle
+type Server struct {
+	opts    Options
+	http    *http.Server
+	onDrain []func()
+	onClose []func()
+
+	workersCtx  context.Context
+	stopWorkers context.CancelFunc
+	workers     sync.WaitGroup
+}
+
+func New(opts Options, handler http.Handler) *Server {
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+
+	return &Server{
+		opts: opts,
+		http: &http.Server{
+			Addr:              opts.Addr,
+			Handler:           LimitBody(opts.MaxBodyBytes, handler),
+			ReadTimeout:       opts.ReadTimeout,
+			ReadHeaderTimeout: opts.ReadHeaderTimeout,
+			WriteTimeout:      opts.WriteTimeout,
+			IdleTimeout:       opts.IdleTimeout,
+			MaxHeaderBytes:    opts.MaxHeaderBytes,
+		},
+		workersCtx:  workersCtx,
+		stopWorkers: stopWorkers,
+	}
+}
+
+// Go starts a background worker. Its context is cancelled once in-flight
+// requests have drained, and Run waits for it before running close hooks.
+func (s *Server) Go(name string, fn func(ctx context.Context)) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		fn(s.workersCtx)
+		log.Printf("Worker %s stopped", name)
+	}()
+}
+
+// OnDrain registers a hook run as soon as shutdown starts (e.g. fail readiness)
+func (s *Server) OnDrain(fn func()) {
+	s.onDrain = append(s.onDrain, fn)
+}
+
+// OnClose registers a hook run after requests and workers have drained (e.g. close the pool)
+func (s *Server) OnClose(fn func()) {
+	s.onClose = append(s.onClose, fn)
+}
+
+// Run serves until ctx is cancelled, then drains and shuts down
+func (s *Server) Run(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server running on %s", s.opts.Addr)
+		if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			s.stopWorkers()
+			s.workers.Wait()
+			s.runHooks(s.onClose)
+			return fmt.Errorf("http server failed: %w", err)
+		}
+	case <-ctx.Done():
+	}
+
+	return s.shutdown()
+}
+
+func (s *Server) shutdown() error {
+	log.Printf("Shutdown started, draining for %s", s.opts.DrainDelay)
+	s.runHooks(s.onDrain)
+
+	// Keep serving while the load balancer notices we are not ready
+	time.Sleep(s.opts.DrainDelay)
+
+	// One deadline covers requests and workers. Requests get the first half
+	// of it so workers always keep some of the budget to finish cleanly.
+	deadline, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
+	defer cancel()
+	requestsDeadline, cancelRequests := context.WithTimeout(deadline, s.opts.ShutdownTimeout/2)
+	defer cancelRequests()
+
+	var shutdownErr error
+	if err := s.http.Shutdown(requestsDeadline); err != nil {
+		// Requests still in flight when their share of the deadline ran out
+		shutdownErr = fmt.Errorf("graceful shutdown incomplete: %w", err)
+		s.http.Close()
+	}
+
+	s.stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-deadline.Done():
+		shutdownErr = errors.Join(shutdownErr, errors.New("background workers did not stop before deadline"))
+	}
+
+	s.runHooks(s.onClose)
+	log.Printf("Shutdown complete")
+	return shutdownErr
+}
+
+func (s *Server) runHooks(hooks []func()) {
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// LimitBody caps request bodies so a single client cannot exhaust memory
+func LimitBody(max int64, next http.Handler) http.Handler {
+	if max <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+		next.ServeHTTP(w, r)
+	})
+}
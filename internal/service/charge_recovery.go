@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// What recovery does with a charge that succeeded but was never recorded
+const (
+	RecoveryRecord = "record"
+	RecoveryRefund = "refund"
+)
+
+const orphanRefundReason = "orphaned charge"
+
+// Moves an attempt to status, failures are logged since recovery catches up later
+func (s *PaymentService) settleAttempt(ctx context.Context, attempt *model.ChargeAttempt, status string, cause error) {
+	attempt.Status = status
+	attempt.LastError = ""
+	if cause != nil {
+		attempt.LastError = cause.Error()
+	}
+	if err := s.attempts.Update(ctx, attempt); err != nil {
+		log.Printf("Error updating charge attempt %s to %s: %v", attempt.ID, status, err)
+	}
+}
+
+// Writes the transaction with txStatus, the payment method and the recorded
+// attempt in one unit. A transaction that already exists for the intent is
+// left as is. chain lists the providers tried before, when known.
+func (s *PaymentService) recordCharge(ctx context.Context, attempt *model.ChargeAttempt, chain []model.ProviderAttempt, txStatus string) error {
+	recorded := *attempt
+	recorded.Status = ports.AttemptRecorded
+	recorded.LastError = ""
+
+	err := s.uow.Do(ctx, func(repos ports.Repositories) error {
+		_, err := repos.Transactions.FindByPaymentIntent(ctx, attempt.PaymentIntentID)
+		if err != nil && !errors.Is(err, ports.ErrNotFound) {
+			return err
+		}
+
+		if err != nil {
+			newTx := attemptTransaction(attempt, chain, txStatus)
+			if err := repos.Transactions.Create(ctx, &newTx); err != nil {
+				return err
+			}
+
+			// A pending charge saves its method once the success webhook arrives
+			if attempt.SavePaymentMethod && txStatus == ports.PaymentSucceeded {
+				err := savePaymentMethod(ctx, repos.PaymentMethods, model.PaymentProvider(attempt.Provider), attempt.CustomerID, attempt.PaymentMethodID)
+				if err != nil {
+					return err
+				}
+			}
+
+			if attempt.OrderID != "" {
+				if err := refreshOrderStatus(ctx, repos, attempt.OrderID); err != nil {
+					return err
+				}
+			}
+		}
+
+		return repos.ChargeAttempts.Update(ctx, &recorded)
+	})
+	if err != nil {
+		return err
+	}
+
+	*attempt = recorded
+	return nil
+}
+
+func attemptTransaction(attempt *model.ChargeAttempt, chain []model.ProviderAttempt, txStatus string) model.Transaction {
+	save := attempt.SavePaymentMethod
+	return model.Transaction{Amount: attempt.Amount,
+		Currency: attempt.Currency,
+		PaymentIntentID: attempt.PaymentIntentID,
+		TxStatus: txStatus,
+		CustomerID: attempt.CustomerID,
+		OrderID: attempt.OrderID,
+		Provider: attempt.Provider,
+		ProviderAttempts: chain,
+		SavePaymentMethod: &save,
+		Metadata: attempt.Metadata,
+	}
+}
+
+// RecoverChargeAttempts settles attempts left started or charged since before.
+// Started attempts are looked up at the provider, charged ones are recorded or
+// refunded according to policy. Attempts are claimed first, so workers running
+// side by side never settle the same one.
+func (s *PaymentService) RecoverChargeAttempts(ctx context.Context, before time.Time, limit int, policy string) ([]model.RecoveryResult, error) {
+	if policy != RecoveryRecord && policy != RecoveryRefund {
+		return nil, fmt.Errorf("unknown recovery policy %q", policy)
+	}
+
+	attempts, err := s.attempts.ClaimStale(ctx, []string{ports.AttemptStarted, ports.AttemptCharged}, before, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.RecoveryResult, 0, len(attempts))
+	for i := range attempts {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		results = append(results, s.recoverAttempt(ctx, &attempts[i], policy))
+	}
+	return results, nil
+}
+
+func (s *PaymentService) recoverAttempt(ctx context.Context, attempt *model.ChargeAttempt, policy string) model.RecoveryResult {
+	result := model.RecoveryResult{
+		AttemptID:      attempt.ID,
+		PreviousStatus: attempt.Status,
+	}
+	fail := func(err error) model.RecoveryResult {
+		result.PaymentIntentID = attempt.PaymentIntentID
+		result.NewStatus = attempt.Status
+		result.Error = err.Error()
+		return result
+	}
+
+	processor, err := s.providerRegistry.Get(model.PaymentProvider(attempt.Provider))
+	if err != nil {
+		return fail(err)
+	}
+
+	if attempt.Status == ports.AttemptStarted {
+		finder, ok := processor.(ports.IChargeFinder)
+		if !ok {
+			s.settleAttempt(ctx, attempt, ports.AttemptUnresolved, fmt.Errorf("provider %s cannot look up charges", attempt.Provider))
+			return fail(errors.New(attempt.LastError))
+		}
+
+		pctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+		res, err := finder.FindCharge(pctx, attempt.ID)
+		cancel()
+
+		switch {
+		case errors.Is(err, ports.ErrNotFound):
+			s.settleAttempt(ctx, attempt, ports.AttemptFailed, errors.New("no charge found at provider"))
+		case err != nil:
+			return fail(err)
+		case res.Status != "succeeded":
+			attempt.PaymentIntentID = res.ID
+			s.settleAttempt(ctx, attempt, ports.AttemptFailed, fmt.Errorf("provider charge is %s", res.Status))
+		default:
+			attempt.PaymentIntentID = res.ID
+			if res.PaymentMethodID != "" {
+				attempt.PaymentMethodID = res.PaymentMethodID
+			}
+			s.settleAttempt(ctx, attempt, ports.AttemptCharged, nil)
+		}
+	}
+
+	if attempt.Status == ports.AttemptCharged {
+		if policy == RecoveryRefund {
+			err = s.refundOrphan(ctx, processor, attempt)
+		} else {
+			err = s.recordCharge(ctx, attempt, nil, ports.PaymentSucceeded)
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	result.PaymentIntentID = attempt.PaymentIntentID
+	result.NewStatus = attempt.Status
+	return result
+}
+
+// Refunds a charge nothing was recorded for, then records it as a refunded
+// transaction with its refund so the money can be traced
+func (s *PaymentService) refundOrphan(ctx context.Context, processor ports.IPaymentProcessor, attempt *model.ChargeAttempt) error {
+	// Recorded after all, e.g. by an earlier run that could not settle the attempt
+	_, err := s.transactions.FindByPaymentIntent(ctx, attempt.PaymentIntentID)
+	if err == nil {
+		return s.recordCharge(ctx, attempt, nil, ports.PaymentSucceeded)
+	}
+	if !errors.Is(err, ports.ErrNotFound) {
+		return err
+	}
+
+	refunder, ok := processor.(ports.IRefunder)
+	if !ok {
+		err := fmt.Errorf("provider %s does not support refunds", attempt.Provider)
+		s.settleAttempt(ctx, attempt, ports.AttemptUnresolved, err)
+		return err
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+	defer cancel()
+
+	res, err := refunder.Refund(pctx, model.ProviderRefundRequest{
+		PaymentIntentID: attempt.PaymentIntentID,
+		Reason:          orphanRefundReason,
+	})
+	if err != nil {
+		return err
+	}
+
+	refunded := *attempt
+	refunded.Status = ports.AttemptRefunded
+	refunded.LastError = ""
+
+	err = s.uow.Do(ctx, func(repos ports.Repositories) error {
+		newTx := attemptTransaction(attempt, nil, ports.Refunded)
+		if err := repos.Transactions.Create(ctx, &newTx); err != nil {
+			return err
+		}
+
+		newRefund := model.Refund{
+			TransactionID:    newTx.ID,
+			Amount:           res.Amount,
+			Reason:           orphanRefundReason,
+			ProviderRefundID: res.ID,
+			RefundStatus:     res.Status,
+		}
+		if err := repos.Refunds.Create(ctx, &newRefund); err != nil {
+			return err
+		}
+
+		if attempt.OrderID != "" {
+			if err := refreshOrderStatus(ctx, repos, attempt.OrderID); err != nil {
+				return err
+			}
+		}
+
+		return repos.ChargeAttempts.Update(ctx, &refunded)
+	})
+	if err != nil {
+		// The money is back, only the records are missing
+		s.settleAttempt(ctx, attempt, ports.AttemptUnresolved, fmt.Errorf("refunded %s as %s but not recorded: %v", attempt.PaymentIntentID, res.ID, err))
+		return err
+	}
+
+	*attempt = refunded
+	return nil
+}
+
+// RecoveryWorker periodically settles charge attempts older than grace
+type RecoveryWorker struct {
+	service  *PaymentService
+	interval time.Duration
+	grace    time.Duration
+	policy   string
+	batch    int
+}
+
+func NewRecoveryWorker(service *PaymentService, interval time.Duration, grace time.Duration, policy string) *RecoveryWorker {
+	return &RecoveryWorker{
+		service:  service,
+		interval: interval,
+		grace:    grace,
+		policy:   policy,
+		batch:    100,
+	}
+}
+
+// Run blocks until ctx is cancelled
+func (w *RecoveryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.tick(ctx)
+		}
+	}
+}
+
+func (w *RecoveryWorker) tick(ctx context.Context) {
+	results, err := w.service.RecoverChargeAttempts(ctx, time.Now().Add(-w.grace), w.batch, w.policy)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Charge recovery failed: %v", err)
+	}
+
+	for _, res := range results {
+		if res.Error != "" {
+			log.Printf("Charge attempt %s (%s) not recovered: %s", res.AttemptID, res.PreviousStatus, res.Error)
+			continue
+		}
+		log.Printf("Charge attempt %s recovered: %s -> %s (intent %s)", res.AttemptID, res.PreviousStatus, res.NewStatus, res.PaymentIntentID)
+	}
+}
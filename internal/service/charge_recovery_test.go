@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// A charge that succeeded at the provider with nothing recorded for it
+func orphanAttempt(t *testing.T, svc *testService) *model.ChargeAttempt {
+	t.Helper()
+	ctx := context.Background()
+
+	attempt := &model.ChargeAttempt{Provider: string(adapters.FakeProvider), CustomerID: "cus_test", Amount: 1250, Currency: "usd"}
+	if err := svc.attempts.Create(ctx, attempt); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	req := testCharge(1250, adapters.FakeTokenSuccess)
+	req.AttemptID = attempt.ID
+	charge, err := svc.fake.ChargeClient(ctx, req)
+	if err != nil {
+		t.Fatalf("ChargeClient: %v", err)
+	}
+
+	attempt.Status = ports.AttemptCharged
+	attempt.PaymentIntentID = charge.ID
+	if err := svc.attempts.Update(ctx, attempt); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	return attempt
+}
+
+func TestRecoveryRecordsOrphanRefunds(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	attempt := orphanAttempt(t, svc)
+
+	results, err := svc.RecoverChargeAttempts(ctx, time.Now().Add(time.Minute), 10, RecoveryRefund)
+	if err != nil {
+		t.Fatalf("RecoverChargeAttempts: %v", err)
+	}
+	if len(results) != 1 || results[0].NewStatus != ports.AttemptRefunded {
+		t.Fatalf("expected the attempt to be refunded, got %+v", results)
+	}
+
+	tx, err := svc.transactions.FindByPaymentIntent(ctx, attempt.PaymentIntentID)
+	if err != nil {
+		t.Fatalf("FindByPaymentIntent: %v", err)
+	}
+	if tx.TxStatus != ports.Refunded {
+		t.Fatalf("expected a refunded transaction, got %s", tx.TxStatus)
+	}
+	refunds, err := svc.refunds.FindByTransaction(ctx, tx.ID)
+	if err != nil {
+		t.Fatalf("FindByTransaction: %v", err)
+	}
+	if len(refunds) != 1 || refunds[0].Amount != 1250 || refunds[0].Reason != orphanRefundReason {
+		t.Fatalf("expected the orphan refund to be recorded, got %+v", refunds)
+	}
+}
+
+func TestRecoveryClaimsEachAttemptOnce(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	orphanAttempt(t, svc)
+	before := time.Now().Add(time.Minute)
+
+	first, err := svc.attempts.ClaimStale(ctx, []string{ports.AttemptCharged}, time.Now(), 10)
+	if err != nil {
+		t.Fatalf("ClaimStale: %v", err)
+	}
+	if len(first) != 1 {
+		t.Fatalf("expected the attempt to be claimed, got %+v", first)
+	}
+
+	// Another worker whose grace has not passed since the claim finds nothing
+	results, err := svc.RecoverChargeAttempts(ctx, first[0].UpdatedAt, 10, RecoveryRefund)
+	if err != nil {
+		t.Fatalf("RecoverChargeAttempts: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected the claimed attempt to be skipped, got %+v", results)
+	}
+
+	results, err = svc.RecoverChargeAttempts(ctx, before, 10, RecoveryRefund)
+	if err != nil {
+		t.Fatalf("RecoverChargeAttempts: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected the attempt to be recovered once its claim went stale, got %+v", results)
+	}
+}
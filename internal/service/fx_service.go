@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// Digits kept when a rate is inverted
+const fxRatePrecision = 10
+
+type FxService struct {
+	rates      ports.IFxRateRepository
+	quotes     ports.IFxQuoteRepository
+	source     ports.IRateSource
+	settlement string
+	quoteTTL   time.Duration
+	maxRateAge time.Duration
+}
+
+func NewFxService(rates ports.IFxRateRepository, quotes ports.IFxQuoteRepository, source ports.IRateSource, settlementCurrency string, quoteTTL time.Duration, maxRateAge time.Duration) *FxService {
+	return &FxService{
+		rates:      rates,
+		quotes:     quotes,
+		source:     source,
+		settlement: strings.ToLower(settlementCurrency),
+		quoteTTL:   quoteTTL,
+		maxRateAge: maxRateAge,
+	}
+}
+
+// RefreshRates stores the source's current rates and returns how many
+func (s *FxService) RefreshRates(ctx context.Context) (int, error) {
+	rates, err := s.source.FetchRates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range rates {
+		if err := s.rates.Upsert(ctx, &rates[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(rates), nil
+}
+
+// CreateQuote prices a settlement amount in the customer's currency and locks
+// the rate until the quote expires
+func (s *FxService) CreateQuote(ctx context.Context, req model.FxQuoteRequest) (*model.FxQuoteResponse, error) {
+	if req.CustomerID == "" {
+		return nil, model.Invalid("customer_id_required", "customer_id is required")
+	}
+	if req.Amount <= 0 {
+		return nil, model.Invalid("amount_invalid", "amount must be positive")
+	}
+	if req.Currency == "" {
+		req.Currency = s.settlement
+	}
+
+	settlement, err := model.LookupCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	presentment, err := model.LookupCurrency(req.PresentmentCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("presentment_currency: %w", err)
+	}
+	if settlement.Code == presentment.Code {
+		return nil, model.Invalid("fx_same_currency", "presentment_currency is the settlement currency, no quote is needed")
+	}
+
+	rate, err := s.rate(ctx, settlement, presentment)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := convertAmount(req.Amount, settlement, presentment, rate)
+	if err != nil {
+		return nil, err
+	}
+	if amount <= 0 {
+		return nil, model.Invalid("amount_too_small", "amount %s is too small to convert to %s", model.Money{Amount: req.Amount, Currency: settlement}, strings.ToUpper(presentment.Code))
+	}
+
+	quote := model.FxQuote{
+		CustomerID:          req.CustomerID,
+		SettlementAmount:    req.Amount,
+		SettlementCurrency:  settlement.Code,
+		PresentmentAmount:   amount,
+		PresentmentCurrency: presentment.Code,
+		Rate:                rate,
+		ExpiresAt:           time.Now().Add(s.quoteTTL),
+	}
+	if err := s.quotes.Create(ctx, &quote); err != nil {
+		return nil, err
+	}
+	return fxQuoteResponse(&quote), nil
+}
+
+func (s *FxService) GetQuote(ctx context.Context, id string) (*model.FxQuoteResponse, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("error getting fx quote: %w", ports.ErrNotFound)
+	}
+
+	quote, err := s.quotes.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return fxQuoteResponse(quote), nil
+}
+
+// Finds a fresh rate from one currency to another, inverting the reverse pair
+// when only that one is published
+func (s *FxService) rate(ctx context.Context, from model.Currency, to model.Currency) (string, error) {
+	rate, err := s.rates.Find(ctx, from.Code, to.Code)
+	inverted := false
+	if errors.Is(err, ports.ErrNotFound) {
+		rate, err = s.rates.Find(ctx, to.Code, from.Code)
+		inverted = true
+	}
+	if errors.Is(err, ports.ErrNotFound) {
+		return "", model.Invalid("fx_rate_unavailable", "no fx rate from %s to %s", strings.ToUpper(from.Code), strings.ToUpper(to.Code))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if age := time.Since(rate.FetchedAt); age > s.maxRateAge {
+		return "", model.NewError(model.CategoryProviderUnavailable, "fx_rate_stale", "fx rate %s/%s is stale, fetched %s ago", strings.ToUpper(rate.Base), strings.ToUpper(rate.Quote), age.Round(time.Minute))
+	}
+	if !inverted {
+		return rate.Rate, nil
+	}
+
+	r, ok := new(big.Rat).SetString(rate.Rate)
+	if !ok || r.Sign() <= 0 {
+		return "", fmt.Errorf("fx rate %s/%s %q is invalid", rate.Base, rate.Quote, rate.Rate)
+	}
+	inverse := strings.TrimRight(new(big.Rat).Inv(r).FloatString(fxRatePrecision), "0")
+	return strings.TrimSuffix(inverse, "."), nil
+}
+
+// Converts minor units at rate, rounding half up
+func convertAmount(amount int64, from model.Currency, to model.Currency, rate string) (int64, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return 0, fmt.Errorf("fx rate %q is invalid", rate)
+	}
+
+	v := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), r)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(to.Exponent-from.Exponent))), nil))
+	if to.Exponent > from.Exponent {
+		v.Mul(v, scale)
+	} else {
+		v.Quo(v, scale)
+	}
+
+	q, m := new(big.Int).QuoRem(v.Num(), v.Denom(), new(big.Int))
+	if m.Mul(m, big.NewInt(2)).Cmp(v.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("converted amount is out of range")
+	}
+	return q.Int64(), nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func fxQuoteResponse(quote *model.FxQuote) *model.FxQuoteResponse {
+	status := quote.Status
+	if status == ports.FxQuoteOpen && !quote.ExpiresAt.After(time.Now()) {
+		status = "expired"
+	}
+	return &model.FxQuoteResponse{
+		ID:                  quote.ID,
+		CustomerID:          quote.CustomerID,
+		Amount:              quote.SettlementAmount,
+		Currency:            strings.TrimSpace(quote.SettlementCurrency),
+		PresentmentAmount:   quote.PresentmentAmount,
+		PresentmentCurrency: strings.TrimSpace(quote.PresentmentCurrency),
+		PresentmentDisplay:  model.FormatAmount(quote.PresentmentAmount, quote.PresentmentCurrency),
+		Rate:                quote.Rate,
+		Status:              status,
+		ExpiresAt:           quote.ExpiresAt,
+	}
+}
+
+// RateRefresher keeps the rates table current
+type RateRefresher struct {
+	service  *FxService
+	interval time.Duration
+}
+
+func NewRateRefresher(service *FxService, interval time.Duration) *RateRefresher {
+	return &RateRefresher{service: service, interval: interval}
+}
+
+// Run refreshes right away so quotes work after a restart, then every
+// interval until ctx is cancelled
+func (w *RateRefresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *RateRefresher) tick(ctx context.Context) {
+	n, err := w.service.RefreshRates(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("FX rate refresh from %s failed after %d rates: %v", w.service.source.Name(), n, err)
+		}
+		return
+	}
+	log.Printf("FX rates refreshed from %s: %d rates", w.service.source.Name(), n)
+}
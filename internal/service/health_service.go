@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+type HealthService struct {
+	checkers []ports.IHealthChecker
+	draining atomic.Bool
+}
+
+func NewHealthService(checkers ...ports.IHealthChecker) *HealthService {
+	return &HealthService{checkers: checkers}
+}
+
+// Marks the process as not ready so the load balancer stops routing to it
+func (s *HealthService) SetDraining() {
+	s.draining.Store(true)
+}
+
+func (s *HealthService) IsDraining() bool {
+	return s.draining.Load()
+}
+
+func (s *HealthService) Liveness() *model.HealthReport {
+	return &model.HealthReport{Status: model.HealthUp}
+}
+
+// Runs every check concurrently, reporting per-check status and latency
+func (s *HealthService) Readiness(ctx context.Context) *model.HealthReport {
+	results := make([]model.HealthCheckResult, len(s.checkers))
+
+	var wg sync.WaitGroup
+	for i, checker := range s.checkers {
+		wg.Add(1)
+		go func(i int, checker ports.IHealthChecker) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	report := &model.HealthReport{Status: model.HealthUp, Checks: results}
+	for _, res := range results {
+		if res.Status != model.HealthUp {
+			report.Status = model.HealthDown
+		}
+	}
+
+	if s.IsDraining() {
+		report.Status = model.HealthDraining
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, checker ports.IHealthChecker) model.HealthCheckResult {
+	res := model.HealthCheckResult{Name: checker.Name(), Status: model.HealthUp}
+
+	var (
+		err     error
+		latency time.Duration
+	)
+	if cc, ok := checker.(*CachedChecker); ok {
+		latency, res.Cached, err = cc.check(ctx)
+	} else {
+		start := time.Now()
+		err = checker.Check(ctx)
+		latency = time.Since(start)
+	}
+
+	res.LatencyMs = float64(latency.Microseconds()) / 1000
+	if err != nil {
+		res.Status = model.HealthDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Checks a provider's reachability through its optional Ping capability
+type ProviderChecker struct {
+	provider model.PaymentProvider
+	pinger   ports.IProviderPinger
+}
+
+func NewProviderChecker(processor ports.IPaymentProcessor) (*ProviderChecker, bool) {
+	pinger, ok := processor.(ports.IProviderPinger)
+	if !ok {
+		return nil, false
+	}
+	return &ProviderChecker{provider: processor.Name(), pinger: pinger}, true
+}
+
+func (c *ProviderChecker) Name() string {
+	return fmt.Sprintf("provider:%s", c.provider)
+}
+
+func (c *ProviderChecker) Check(ctx context.Context) error {
+	return c.pinger.Ping(ctx)
+}
+
+// Caches the result of a slow or rate-limited check for ttl
+type CachedChecker struct {
+	checker ports.IHealthChecker
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	latency   time.Duration
+	lastErr   error
+}
+
+func NewCachedChecker(checker ports.IHealthChecker, ttl time.Duration) *CachedChecker {
+	return &CachedChecker{checker: checker, ttl: ttl}
+}
+
+func (c *CachedChecker) Name() string {
+	return c.checker.Name()
+}
+
+func (c *CachedChecker) Check(ctx context.Context) error {
+	_, _, err := c.check(ctx)
+	return err
+}
+
+// Returns the (possibly cached) result with the latency of the call that produced it
+func (c *CachedChecker) check(ctx context.Context) (time.Duration, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.ttl {
+		return c.latency, true, c.lastErr
+	}
+
+	start := time.Now()
+	c.lastErr = c.checker.Check(ctx)
+	c.latency = time.Since(start)
+	c.checkedAt = time.Now()
+	return c.latency, false, c.lastErr
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// How long a request may hold its key before a retry takes it over, in case
+// the process holding it died. Longer than any request timeout.
+const idempotencyStaleAfter = 5 * time.Minute
+
+// IdempotencyService remembers the responses of requests sent with an
+// Idempotency-Key so a retried request gets the same response instead of
+// running twice
+type IdempotencyService struct {
+	keys ports.IIdempotencyRepository
+	ttl  time.Duration
+}
+
+func NewIdempotencyService(keys ports.IIdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{keys: keys, ttl: ttl}
+}
+
+// Begin claims key for a request identified by fingerprint. It returns nil
+// when the request should run, or the stored response of its first run.
+func (s *IdempotencyService) Begin(ctx context.Context, key string, fingerprint string) (*model.IdempotencyRecord, error) {
+	now := time.Now()
+	record := &model.IdempotencyRecord{Key: key, Fingerprint: fingerprint, ExpiresAt: now.Add(s.ttl)}
+
+	err := s.keys.Reserve(ctx, record, now.Add(-idempotencyStaleAfter))
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, ports.ErrConflict) {
+		return nil, err
+	}
+
+	existing, err := s.keys.FindByKey(ctx, key)
+	if errors.Is(err, ports.ErrNotFound) {
+		// Pruned in between, the client's retry will reserve it
+		return nil, model.Conflict("idempotency_key_in_use", "a request with this Idempotency-Key is in progress, retry later")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, model.Invalid("idempotency_key_reused", "this Idempotency-Key was used with a different request")
+	}
+	if existing.ResponseStatus == 0 {
+		return nil, model.Conflict("idempotency_key_in_use", "a request with this Idempotency-Key is in progress, retry later")
+	}
+	return existing, nil
+}
+
+// Finish stores the response of a request Begin let run. Requests that never
+// reached a provider are forgotten so the retry runs them again.
+func (s *IdempotencyService) Finish(ctx context.Context, key string, status int, body []byte) error {
+	if status == http.StatusServiceUnavailable {
+		if err := s.keys.Delete(ctx, key); err != nil {
+			return fmt.Errorf("error releasing idempotency key: %w", err)
+		}
+		return nil
+	}
+	return s.keys.Complete(ctx, key, status, body)
+}
+
+// Prune deletes the keys past their TTL
+func (s *IdempotencyService) Prune(ctx context.Context) (int64, error) {
+	return s.keys.DeleteExpired(ctx, time.Now())
+}
+
+// IdempotencyPruner keeps the idempotency keys table small
+type IdempotencyPruner struct {
+	service  *IdempotencyService
+	interval time.Duration
+}
+
+func NewIdempotencyPruner(service *IdempotencyService, interval time.Duration) *IdempotencyPruner {
+	return &IdempotencyPruner{service: service, interval: interval}
+}
+
+// Run prunes every interval until ctx is cancelled
+func (w *IdempotencyPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.tick(ctx)
+		}
+	}
+}
+
+func (w *IdempotencyPruner) tick(ctx context.Context) {
+	n, err := w.service.Prune(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Idempotency key pruning failed: %v", err)
+		}
+		return
+	}
+	if n > 0 {
+		log.Printf("Pruned %d expired idempotency keys", n)
+	}
+}
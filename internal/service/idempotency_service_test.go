@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/repository/memory"
+)
+
+func TestIdempotencyPruneDeletesExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	keys := memory.NewIdempotencyRepository()
+
+	expired := NewIdempotencyService(keys, -time.Second)
+	if _, err := expired.Begin(ctx, "key_old", "fp"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	live := NewIdempotencyService(keys, time.Hour)
+	if _, err := live.Begin(ctx, "key_live", "fp"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	n, err := live.Prune(ctx)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 expired key, got %d", n)
+	}
+	if _, err := keys.FindByKey(ctx, "key_live"); err != nil {
+		t.Fatalf("expected the live key to survive, got %v", err)
+	}
+}
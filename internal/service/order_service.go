@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/validation"
+)
+
+type OrderService struct {
+	orders       ports.IOrderRepository
+	transactions ports.ITransactionRepository
+	refunds      ports.IRefundRepository
+	uow          ports.IUnitOfWork
+}
+
+func NewOrderService(orders ports.IOrderRepository, transactions ports.ITransactionRepository, refunds ports.IRefundRepository, uow ports.IUnitOfWork) *OrderService {
+	return &OrderService{
+		orders:       orders,
+		transactions: transactions,
+		refunds:      refunds,
+		uow:          uow,
+	}
+}
+
+func (s *OrderService) CreateOrder(ctx context.Context, req model.OrderRequest) (*model.OrderResponse, error) {
+	if req.CustomerID == "" {
+		return nil, model.Invalid("customer_id_required", "customer_id is required")
+	}
+	if req.AmountDue <= 0 {
+		return nil, model.Invalid("amount_invalid", "amount_due must be positive")
+	}
+	currency, err := model.LookupCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := validation.CheckMetadata(req.Metadata); err != nil {
+		return nil, err
+	}
+
+	order := model.Order{
+		CustomerID: req.CustomerID,
+		AmountDue:  req.AmountDue,
+		Currency:   currency.Code,
+		Metadata:   req.Metadata,
+	}
+	if err := s.orders.Create(ctx, &order); err != nil {
+		return nil, err
+	}
+
+	return orderResponse(&order, nil, 0), nil
+}
+
+// GetOrder returns the order with every transaction made towards it
+func (s *OrderService) GetOrder(ctx context.Context, id string) (*model.OrderResponse, error) {
+	order, attempts, err := loadOrder(ctx, s.orders, s.transactions, s.refunds, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Derived rather than stored, so the answer holds even mid-update
+	status, paid := orderStatus(order, attempts)
+	order.Status = status
+	return orderResponse(order, attempts, paid), nil
+}
+
+// CancelOrder stops an order from taking payments. Orders with captured
+// payments must be refunded instead.
+func (s *OrderService) CancelOrder(ctx context.Context, id string) (*model.OrderResponse, error) {
+	var res *model.OrderResponse
+	err := s.uow.Do(ctx, func(repos ports.Repositories) error {
+		order, attempts, err := loadOrder(ctx, repos.Orders, repos.Transactions, repos.Refunds, id)
+		if err != nil {
+			return err
+		}
+
+		status, paid := orderStatus(order, attempts)
+		if status != ports.OrderOpen && status != ports.OrderCancelled {
+			return model.Conflict("order_not_cancellable", "order %s is %s and cannot be cancelled", order.ID, status)
+		}
+		if status == ports.OrderOpen {
+			if err := repos.Orders.UpdateStatus(ctx, order.ID, ports.OrderCancelled); err != nil {
+				return err
+			}
+			order.Status = ports.OrderCancelled
+		}
+
+		res = orderResponse(order, attempts, paid)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Loads an order with its transactions and what was refunded on each
+func loadOrder(ctx context.Context, orders ports.IOrderRepository, transactions ports.ITransactionRepository, refunds ports.IRefundRepository, id string) (*model.Order, []model.OrderAttempt, error) {
+	// Postgres rejects malformed UUIDs outright, to callers it is just unknown
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, nil, fmt.Errorf("error getting order: %w", ports.ErrNotFound)
+	}
+
+	order, err := orders.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	order.Currency = strings.TrimSpace(order.Currency)
+
+	txs, err := transactions.FindByColumn(ctx, "order_id", order.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempts := make([]model.OrderAttempt, 0, len(txs))
+	for _, tx := range txs {
+		previous, err := refunds.FindByTransaction(ctx, tx.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Orders are kept in the settlement currency, refunds are made in
+		// the presentment one and scaled across
+		refunded := refundedAmount(previous)
+		if tx.Amount > 0 && tx.SettlementAmount != tx.Amount {
+			refunded = refunded * tx.SettlementAmount / tx.Amount
+		}
+		attempts = append(attempts, model.OrderAttempt{
+			TransactionID:     tx.ID,
+			InternalReference: tx.InternalReference,
+			PaymentIntentID:   tx.PaymentIntentID,
+			Amount:            tx.SettlementAmount,
+			Refunded:          refunded,
+			Status:            tx.TxStatus,
+			CreatedAt:         tx.CreatedAt,
+		})
+	}
+	return order, attempts, nil
+}
+
+// Derives the order status and the amount paid net of refunds. Cancelled only
+// holds while nothing is captured, a payment that lands anyway still counts.
+func orderStatus(order *model.Order, attempts []model.OrderAttempt) (string, int64) {
+	var captured, paid int64
+	for _, a := range attempts {
+		switch a.Status {
+		case ports.PaymentSucceeded, ports.PartiallyRefunded, ports.Refunded:
+			captured += a.Amount
+			paid += a.Amount - a.Refunded
+		}
+	}
+
+	switch {
+	case paid >= order.AmountDue:
+		return ports.OrderPaid, paid
+	case paid > 0:
+		return ports.OrderPartiallyPaid, paid
+	case captured > 0:
+		return ports.OrderRefunded, paid
+	case order.Status == ports.OrderCancelled:
+		return ports.OrderCancelled, paid
+	}
+	return ports.OrderOpen, paid
+}
+
+// Stores the derived status, called in the unit that changed one of the
+// order's transactions
+func refreshOrderStatus(ctx context.Context, repos ports.Repositories, orderID string) error {
+	order, attempts, err := loadOrder(ctx, repos.Orders, repos.Transactions, repos.Refunds, orderID)
+	if err != nil {
+		return err
+	}
+
+	status, _ := orderStatus(order, attempts)
+	if status == order.Status {
+		return nil
+	}
+	return repos.Orders.UpdateStatus(ctx, order.ID, status)
+}
+
+func orderResponse(order *model.Order, attempts []model.OrderAttempt, paid int64) *model.OrderResponse {
+	if attempts == nil {
+		attempts = []model.OrderAttempt{}
+	}
+	return &model.OrderResponse{
+		ID:                order.ID,
+		InternalReference: order.InternalReference,
+		CustomerID:        order.CustomerID,
+		AmountDue:         order.AmountDue,
+		AmountPaid:        paid,
+		Currency:          strings.TrimSpace(order.Currency),
+		Status:            order.Status,
+		Metadata:          order.Metadata,
+		Attempts:          attempts,
+		CreatedAt:         order.CreatedAt,
+		UpdatedAt:         order.UpdatedAt,
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+func newTestOrder(t *testing.T, svc *testService, amountDue int64) string {
+	t.Helper()
+	order := model.Order{CustomerID: "cus_test", AmountDue: amountDue, Currency: "usd"}
+	if err := svc.orders.Create(context.Background(), &order); err != nil {
+		t.Fatalf("Create order: %v", err)
+	}
+	return order.ID
+}
+
+func assertExceedsBalance(t *testing.T, err error) {
+	t.Helper()
+	var merr *model.Error
+	if !errors.As(err, &merr) || merr.Code != "amount_exceeds_order_balance" {
+		t.Fatalf("expected amount_exceeds_order_balance, got %v", err)
+	}
+}
+
+func TestPendingPaymentsHoldTheOrderBalance(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	order := newTestOrder(t, svc, 1000)
+
+	req := testCharge(600, "")
+	req.PaymentMethod = "card"
+	req.Order = order
+	if _, err := svc.CreatePaymentIntent(ctx, adapters.FakeProvider, req); err != nil {
+		t.Fatalf("CreatePaymentIntent: %v", err)
+	}
+
+	req = testCharge(600, adapters.FakeTokenSuccess)
+	req.Order = order
+	_, err := svc.ChargeClient(ctx, adapters.FakeProvider, req)
+	assertExceedsBalance(t, err)
+
+	req.Amount = 400
+	if _, err := svc.ChargeClient(ctx, adapters.FakeProvider, req); err != nil {
+		t.Fatalf("ChargeClient for the rest: %v", err)
+	}
+}
+
+func TestUnrecordedChargesHoldTheOrderBalance(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	order := newTestOrder(t, svc, 1000)
+
+	// A charge whose outcome recovery has yet to settle
+	attempt := &model.ChargeAttempt{Provider: string(adapters.FakeProvider), CustomerID: "cus_test", OrderID: order, Amount: 1000, Currency: "usd"}
+	if err := svc.attempts.Create(ctx, attempt); err != nil {
+		t.Fatalf("Create attempt: %v", err)
+	}
+
+	req := testCharge(1000, adapters.FakeTokenSuccess)
+	req.Order = order
+	_, err := svc.ChargeClient(ctx, adapters.FakeProvider, req)
+	assertExceedsBalance(t, err)
+
+	attempt.Status = ports.AttemptFailed
+	if err := svc.attempts.Update(ctx, attempt); err != nil {
+		t.Fatalf("Update attempt: %v", err)
+	}
+	if _, err := svc.ChargeClient(ctx, adapters.FakeProvider, req); err != nil {
+		t.Fatalf("ChargeClient once the attempt failed: %v", err)
+	}
+}
+
+func TestConcurrentChargesCannotOverpayAnOrder(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+	order := newTestOrder(t, svc, 1000)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	charged := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := testCharge(1000, adapters.FakeTokenSuccess)
+			req.Order = order
+			if _, err := svc.ChargeClient(ctx, adapters.FakeProvider, req); err == nil {
+				mu.Lock()
+				charged++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if charged != 1 {
+		t.Fatalf("expected exactly one charge to go through, got %d", charged)
+	}
+}
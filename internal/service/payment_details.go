@@ -0,0 +1,74 @@
+package service
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/validation"
+)
+
+// Validates the details passed through to the provider and returns the
+// metadata to store and send, with the order ID folded in
+func paymentMetadata(req model.PaymentIntentRequest) (map[string]string, error) {
+	count := len(req.Metadata)
+	if req.OrderID != "" {
+		count++
+	}
+	if count > validation.MaxMetadataKeys {
+		return nil, model.Invalid("metadata_invalid", "metadata has %d keys, at most %d are allowed", count, validation.MaxMetadataKeys)
+	}
+
+	if err := validation.CheckMetadata(req.Metadata); err != nil {
+		return nil, err
+	}
+
+	if utf8.RuneCountInString(req.OrderID) > validation.MaxMetadataValueLength {
+		return nil, model.Invalid("order_id_invalid", "order_id exceeds %d characters", validation.MaxMetadataValueLength)
+	}
+	if utf8.RuneCountInString(req.Description) > validation.MaxDescriptionLength {
+		return nil, model.Invalid("description_invalid", "description exceeds %d characters", validation.MaxDescriptionLength)
+	}
+	if req.ReceiptEmail != "" {
+		if err := validation.CheckEmail("receipt_email", req.ReceiptEmail); err != nil {
+			return nil, err
+		}
+	}
+	if err := validation.CheckDescriptorSuffix(req.StatementDescriptorSuffix); err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return nil, nil
+	}
+	metadata := make(map[string]string, count)
+	for key, value := range req.Metadata {
+		metadata[key] = value
+	}
+	if req.OrderID != "" {
+		metadata[ports.MetadataOrderID] = req.OrderID
+	}
+	return metadata, nil
+}
+
+// Checks the amount against its currency and the provider's limits before
+// anything is written or sent
+func checkAmount(processor ports.IPaymentProcessor, amount int64, currency string) (model.Money, error) {
+	money, err := model.NewMoney(amount, currency)
+	if err != nil {
+		return model.Money{}, err
+	}
+	if amount <= 0 {
+		return model.Money{}, model.Invalid("amount_invalid", "amount must be positive")
+	}
+
+	var limits model.AmountLimits
+	if limiter, ok := processor.(ports.IAmountLimiter); ok {
+		limits = limiter.AmountLimits(money.Currency)
+	}
+	if err := money.Check(limits); err != nil {
+		return model.Money{}, fmt.Errorf("%w for %s", err, processor.Name())
+	}
+	return money, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// FindPaymentIntent is GetPaymentIntent for callers that only know the
+// intent, the provider is the one stored with its transaction
+func (s *PaymentService) FindPaymentIntent(ctx context.Context, id string) (*model.PaymentIntentResponse, error) {
+	txdata, err := s.transactionForIntent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.GetPaymentIntent(ctx, model.PaymentProvider(txdata.Provider), id)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Provider = txdata.Provider
+	response.RoutingRule = txdata.RoutingRule
+	response.Attempts = txdata.ProviderAttempts
+	if txdata.FxQuoteID != "" {
+		response.SettlementAmount = txdata.SettlementAmount
+		response.SettlementCurrency = txdata.SettlementCurrency
+		response.FxRate = txdata.FxRate
+	}
+	return response, nil
+}
+
+// RefundPaymentIntent is RefundPayment addressed by payment intent
+func (s *PaymentService) RefundPaymentIntent(ctx context.Context, id string, req model.CreateRefundRequest) (*model.RefundResponse, error) {
+	txdata, err := s.transactionForIntent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.RefundPayment(ctx, model.PaymentProvider(txdata.Provider), model.RefundRequest{
+		TransactionID: txdata.ID,
+		Amount:        req.Amount,
+		Reason:        req.Reason,
+	})
+}
+
+// ListRefunds returns the refunds of a payment intent, newest first
+func (s *PaymentService) ListRefunds(ctx context.Context, id string, page model.Page) (*model.List[model.RefundResponse], error) {
+	txdata, err := s.transactionForIntent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	refunds, err := s.refunds.FindByTransaction(ctx, txdata.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.RefundResponse, len(refunds))
+	for i, rf := range refunds {
+		responses[i] = model.RefundResponse{
+			ID:                rf.ID,
+			InternalReference: rf.InternalReference,
+			TransactionID:     txdata.ID,
+			Amount:            rf.Amount,
+			AmountDisplay:     model.FormatAmount(rf.Amount, txdata.Currency),
+			Currency:          txdata.Currency,
+			Status:            rf.RefundStatus,
+			ProviderRefundID:  rf.ProviderRefundID,
+		}
+	}
+	return paginate(responses, page, func(rf model.RefundResponse) string { return rf.ID })
+}
+
+// ListPaymentMethods returns a customer's saved payment methods, newest first
+func (s *PaymentService) ListPaymentMethods(ctx context.Context, customerID string, page model.Page) (*model.List[model.PaymentMethodResponse], error) {
+	methods, err := s.GetUserPMethods(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(methods, page, func(pm model.PaymentMethodResponse) string { return pm.PaymentMethodID })
+}
+
+// Transactions created before the provider was stored cannot be addressed by
+// intent alone, /payments/{provider}/intent still reads them
+func (s *PaymentService) transactionForIntent(ctx context.Context, id string) (*model.Transaction, error) {
+	txdata, err := s.transactions.FindByPaymentIntent(ctx, id)
+	if errors.Is(err, ports.ErrNotFound) {
+		return nil, model.NotFound("payment_intent_not_found", "payment intent %s does not exist", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if txdata.Provider == "" {
+		return nil, model.Conflict("provider_unknown", "payment intent %s has no recorded provider, read it through /payments/{provider}/intent", id)
+	}
+	return txdata, nil
+}
+
+// Cuts the page after page.Cursor out of items. The lists are small enough,
+// a customer's payment methods or an intent's refunds, to page in memory.
+func paginate[T any](items []T, page model.Page, id func(T) string) (*model.List[T], error) {
+	limit := page.Limit
+	if limit <= 0 {
+		limit = model.DefaultPageLimit
+	}
+
+	start := 0
+	if page.Cursor != "" {
+		i := slices.IndexFunc(items, func(item T) bool { return id(item) == page.Cursor })
+		if i < 0 {
+			return nil, model.Invalid("cursor_invalid", "cursor %q is not an item of this list", page.Cursor)
+		}
+		start = i + 1
+	}
+
+	end := min(start+limit, len(items))
+	list := &model.List[T]{Data: append([]T{}, items[start:end]...), HasMore: end < len(items)}
+	if list.HasMore {
+		list.NextCursor = id(items[end-1])
+	}
+	return list, nil
+}
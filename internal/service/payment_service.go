@@ -3,72 +3,197 @@ package service
 import (
 	"fmt"
 	"log"
+	"errors"
+	"strings"
 	"time"
 	"context"
 
+	"github.com/google/uuid"
+
 	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/core"
 	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
 )
 
+// Marks ChargeClient errors after which the charge may still exist at the
+// provider, recovery settles the attempt later
+type unsettledChargeError struct {
+	err error
+}
+
+func (e *unsettledChargeError) Error() string { return e.err.Error() }
+
+func (e *unsettledChargeError) Unwrap() error { return e.err }
+
 type PaymentService struct {
 	providerRegistry *core.ProviderRegistry
-	repositoryRegistry *core.RepositoryRegistry
+	router *core.Router
+	transactions ports.ITransactionRepository
+	paymentMethods ports.IPaymentMethodRepository
+	refunds ports.IRefundRepository
+	attempts ports.IChargeAttemptRepository
+	orders ports.IOrderRepository
+	quotes ports.IFxQuoteRepository
+	uow ports.IUnitOfWork
+	providerTimeout time.Duration
 }
 
-func NewPaymentService(providerRegistry *core.ProviderRegistry, repositoryRegistry *core.RepositoryRegistry) *PaymentService {
-	return &PaymentService{providerRegistry: providerRegistry, repositoryRegistry: repositoryRegistry}
+// router may be nil when callers always name the provider
+func NewPaymentService(providerRegistry *core.ProviderRegistry, router *core.Router, transactions ports.ITransactionRepository, paymentMethods ports.IPaymentMethodRepository, refunds ports.IRefundRepository, attempts ports.IChargeAttemptRepository, orders ports.IOrderRepository, quotes ports.IFxQuoteRepository, uow ports.IUnitOfWork, providerTimeout time.Duration) *PaymentService {
+	return &PaymentService{
+		providerRegistry: providerRegistry,
+		router: router,
+		transactions: transactions,
+		paymentMethods: paymentMethods,
+		refunds: refunds,
+		attempts: attempts,
+		orders: orders,
+		quotes: quotes,
+		uow: uow,
+		providerTimeout: providerTimeout,
+	}
 }
 
 func (s *PaymentService) CreatePaymentIntent(ctx context.Context, provider model.PaymentProvider, req model.PaymentIntentRequest) (*model.PaymentIntentResponse, error) {
-	processor, err := s.providerRegistry.Get(provider)
-	if err != nil {
+	return s.createPaymentIntent(ctx, provider, "", req)
+}
+
+// RoutePaymentIntent creates the intent with the provider picked by the
+// routing rules, and records which rule picked it
+func (s *PaymentService) RoutePaymentIntent(ctx context.Context, req model.PaymentIntentRequest) (*model.PaymentIntentResponse, error) {
+	if s.router == nil {
+		return nil, model.NewError(model.CategoryInternal, "routing_not_configured", "payment routing is not configured")
+	}
+
+	// Route on what the customer pays, which a quote decides
+	if _, err := s.lockedQuote(ctx, &req); err != nil {
 		return nil, err
 	}
 
-	// Select the repo to interact with table Transactions in DB
-	transaction, err := s.repositoryRegistry.Get(core.TransactionRepo)
+	provider, rule, err := s.router.Route(req)
 	if err != nil {
 		return nil, err
 	}
+	log.Printf("Routing payment for customer %s to %s by rule %s", req.CustomerID, provider, rule)
 
-	// Add payment-processor-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-    defer cancel()
+	return s.createPaymentIntent(ctx, provider, rule, req)
+}
+
+func (s *PaymentService) createPaymentIntent(ctx context.Context, provider model.PaymentProvider, rule string, req model.PaymentIntentRequest) (*model.PaymentIntentResponse, error) {
+	processor, err := s.providerRegistry.Get(provider)
+	if err != nil {
+		return nil, err
+	}
 
 	// Log the payment method being used
 	log.Printf("Creating payment intent using method: %s", req.PaymentMethod)
 
 	// Validate the payment method
 	if req.PaymentMethod == "" {
-		return nil, fmt.Errorf("payment method is required")
+		return nil, model.Invalid("payment_method_required", "payment method is required")
 	}
 
-	pi_response, err := processor.CreatePaymentIntent(ctx, req)
+	quote, err := s.lockedQuote(ctx, &req)
 	if err != nil {
 		return nil, err
 	}
-    
-	//Save transaction to repo - table transactions
-	newPi := model.Transaction{Amount: pi_response.Amount,
-		Currency: pi_response.Currency,
-		PaymentIntentID: pi_response.ID,
+
+	money, err := checkAmount(processor, req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	req.Currency = money.Currency.Code
+
+	metadata, err := paymentMetadata(req)
+	if err != nil {
+		return nil, err
+	}
+	req.Metadata = metadata
+
+	// Orders are priced in what we settle in
+	settled := req
+	if quote != nil {
+		settled.Amount = quote.SettlementAmount
+		settled.Currency = quote.SettlementCurrency
+	}
+
+	// Write the pending transaction first, a webhook racing the response then
+	// still finds it through the internal reference sent to the provider
+	newPi := model.Transaction{Amount: req.Amount,
+		Currency: req.Currency,
 		TxStatus: ports.Pending,
 		CustomerID: req.CustomerID,
+		Provider: string(provider),
+		RoutingRule: rule,
 		SavePaymentMethod: req.RememberMe,
+		Metadata: metadata,
+		SettlementAmount: req.Amount,
+		SettlementCurrency: req.Currency,
+		FxRate: "1",
+	}
+
+	if quote != nil {
+		newPi.SettlementAmount = quote.SettlementAmount
+		newPi.SettlementCurrency = strings.TrimSpace(quote.SettlementCurrency)
+		newPi.FxRate = quote.Rate
+		newPi.FxQuoteID = quote.ID
+	}
+
+	// The order balance is checked in the unit that takes from it
+	err = s.uow.Do(ctx, func(repos ports.Repositories) error {
+		orderID, err := s.orderFor(ctx, repos, settled)
+		if err != nil {
+			return err
+		}
+		newPi.OrderID = orderID
+
+		// The quote pays for this transaction only
+		if quote != nil {
+			if err := repos.FxQuotes.Use(ctx, quote.ID, time.Now()); err != nil {
+				return err
+			}
+		}
+		return repos.Transactions.Create(ctx, &newPi)
+	})
+	if err != nil {
+		return nil, err
 	}
+	req.Reference = newPi.InternalReference
+
+	// Add payment-processor-specific timeout
+	pctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+	defer cancel()
 
-	err = transaction.Create(ctx, &newPi)
+	pi_response, err := processor.CreatePaymentIntent(pctx, req)
+	if err != nil {
+		// The client never gets a secret for this intent, so it cannot be paid
+		uerr := s.transactions.UpdateStatus(ctx, ports.PaymentFailed, map[string]interface{}{
+			"id": newPi.ID,
+		})
+		if uerr != nil {
+			log.Printf("Error failing transaction %s: %v", newPi.InternalReference, uerr)
+		}
+		return nil, err
+	}
+
+	err = s.transactions.AttachPaymentIntent(ctx, newPi.ID, pi_response.ID)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	return &model.PaymentIntentResponse{
 		ID: pi_response.ID,
 		Amount: pi_response.Amount,
 		Currency: pi_response.Currency,
+		AmountDisplay: model.FormatAmount(pi_response.Amount, pi_response.Currency),
 		Status: pi_response.Status,
 		ClientSecret: pi_response.ClientSecret,
+		Metadata: metadata,
+		SettlementAmount: newPi.SettlementAmount,
+		SettlementCurrency: newPi.SettlementCurrency,
+		FxRate: newPi.FxRate,
+		Provider: newPi.Provider,
+		RoutingRule: rule,
 	}, nil
 }
 
@@ -78,69 +203,162 @@ func (s *PaymentService) ChargeClient(ctx context.Context, provider model.Paymen
 		return nil, err
 	}
 
-	// Select the repo to interact with table Transactions in DB
-	transaction, err := s.repositoryRegistry.Get(core.TransactionRepo)
-	if err != nil {
-		return nil, err
+	if req.FxQuote != "" {
+		return nil, model.Invalid("fx_quote_not_allowed", "fx quotes can only be used with payment intents")
 	}
 
-	// Select the repo to interact with table payment_method
-	payment_method, err := s.repositoryRegistry.Get(core.PaymentRepo)
+	money, err := checkAmount(processor, req.Amount, req.Currency)
 	if err != nil {
 		return nil, err
 	}
+	req.Currency = money.Currency.Code
 
-	// Add payment-processor-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-    defer cancel()
-
-	res, err := processor.ChargeClient(ctx, req)
+	metadata, err := paymentMetadata(req)
 	if err != nil {
 		return nil, err
 	}
+	req.Metadata = metadata
 
-	//Save transaction to repo - table transactions
-	newPi := model.Transaction{Amount: res.Amount,
-		Currency: res.Currency,
-		PaymentIntentID: res.ID,
-		TxStatus: ports.PaymentSucceeded,
-		CustomerID: req.CustomerID,
-		SavePaymentMethod: req.RememberMe,
+	// Providers to cascade to when one has an outage
+	providers := []model.PaymentProvider{provider}
+	if s.router != nil {
+		providers = append(providers, s.router.Failover(provider)...)
 	}
 
-	err = transaction.Create(ctx, &newPi)
-	if err != nil {
-		return nil, err
+	var chain []model.ProviderAttempt
+	var lastErr error
+	for i, candidate := range providers {
+		if i > 0 {
+			next, err := s.providerRegistry.Get(candidate)
+			if err == nil {
+				_, err = checkAmount(next, req.Amount, req.Currency)
+			}
+			if err != nil {
+				log.Printf("Not failing over to %s: %v", candidate, err)
+				continue
+			}
+			log.Printf("Provider %s unavailable, failing over charge for customer %s to %s", chain[len(chain)-1].Provider, req.CustomerID, candidate)
+			processor = next
+		}
+
+		attempt, res, err := s.chargeWith(ctx, candidate, processor, req)
+		if attempt == nil {
+			return nil, err
+		}
+
+		hop := model.ProviderAttempt{Provider: string(candidate), AttemptID: attempt.ID, Outcome: ports.AttemptCharged}
+		if err != nil {
+			class := ports.ClassifyError(err)
+			// A timeout may have charged whatever the provider said
+			var unsettled *unsettledChargeError
+			if errors.As(err, &unsettled) {
+				class = ports.ErrorAmbiguous
+			}
+			hop.Outcome, hop.Error = string(class), err.Error()
+		}
+		chain = append(chain, hop)
+
+		if err == nil {
+			return s.completeCharge(ctx, attempt, res, chain)
+		}
+		if hop.Outcome != string(ports.ErrorOutage) {
+			return nil, err
+		}
+		lastErr = err
 	}
 
-	// Save payment method to repo - table payment_methods
-	// Save payment method for this customer if payment is new
-	pm_trueVal := true
-	if req.RememberMe != nil && *req.RememberMe == pm_trueVal {
-		pm, err := payment_method.FindByID(ctx, string(res.PaymentMethodID))
+	if len(chain) == 1 {
+		return nil, lastErr
+	}
+	tried := make([]string, len(chain))
+	for i, hop := range chain {
+		tried[i] = hop.Provider
+	}
+	return nil, &model.Error{
+		Category: model.CategoryProviderUnavailable,
+		Code:     ports.CodeProviderUnavailable,
+		Message:  fmt.Sprintf("providers %s are unavailable", strings.Join(tried, ", ")),
+		Cause:    lastErr,
+	}
+}
+
+// Charges through one provider as its own attempt. The attempt is nil when
+// it could not be persisted and nothing was sent.
+func (s *PaymentService) chargeWith(ctx context.Context, provider model.PaymentProvider, processor ports.IPaymentProcessor, req model.PaymentIntentRequest) (*model.ChargeAttempt, *model.PaymentProcessorResponse, error) {
+	// Persist the attempt first, a charge we fail to record can then be recovered
+	attempt := model.ChargeAttempt{
+		Provider: string(provider),
+		CustomerID: req.CustomerID,
+		Amount: req.Amount,
+		Currency: req.Currency,
+		PaymentMethodID: req.Token,
+		SavePaymentMethod: req.RememberMe != nil && *req.RememberMe,
+		Metadata: req.Metadata,
+	}
+	// The order balance is checked in the unit that takes from it
+	err := s.uow.Do(ctx, func(repos ports.Repositories) error {
+		orderID, err := s.orderFor(ctx, repos, req)
 		if err != nil {
-			log.Printf("error: %+v", err)
+			return err
 		}
+		attempt.OrderID = orderID
+		return repos.ChargeAttempts.Create(ctx, &attempt)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	req.AttemptID = attempt.ID
 
-		if pm == nil {
-			newPm := model.Transaction{CustomerID: req.CustomerID,
-				PaymentMethodID: res.PaymentMethodID,
-				PaymentMethodStatus: ports.PaymentMethodStatus,
-				PaymentProvider: string(provider),
-			}
-			err := payment_method.Create(ctx, &newPm)
-			if err != nil {
-				log.Printf("Saving payment method--Event: %+v", err)
-			}
+	// Add payment-processor-specific timeout
+	pctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+	defer cancel()
+
+	res, err := processor.ChargeClient(pctx, req)
+	if err != nil {
+		// A call that timed out may still have charged, leave it to recovery
+		if pctx.Err() != nil || ports.ClassifyError(err) == ports.ErrorAmbiguous {
+			return &attempt, nil, &unsettledChargeError{err: err}
 		}
+		s.settleAttempt(ctx, &attempt, ports.AttemptFailed, err)
+		return &attempt, nil, err
+	}
+
+	attempt.PaymentIntentID = res.ID
+	if res.PaymentMethodID != "" {
+		attempt.PaymentMethodID = res.PaymentMethodID
+	}
+	// Only a succeeded charge took money. One waiting on the customer or the
+	// provider stays started, recovery must not refund or record it as paid.
+	status := ports.AttemptCharged
+	if res.Status != "succeeded" {
+		status = ports.AttemptStarted
+	}
+	s.settleAttempt(ctx, &attempt, status, nil)
+	return &attempt, res, nil
+}
+
+func (s *PaymentService) completeCharge(ctx context.Context, attempt *model.ChargeAttempt, res *model.PaymentProcessorResponse, chain []model.ProviderAttempt) (*model.PaymentIntentResponse, error) {
+	// Record the charge and the payment method together, with the provider's status
+	if err := s.recordCharge(ctx, attempt, chain, chargeTxStatus(res.Status)); err != nil {
+		log.Printf("Charge %s (%s) for attempt %s was not recorded: %v", res.ID, res.Status, attempt.ID, err)
+		return nil, &unsettledChargeError{err: &model.Error{
+			Category: model.CategoryInternal,
+			Code:     "charge_unrecorded",
+			Message:  fmt.Sprintf("charge %s could not be recorded, it will be reconciled", res.ID),
+			Cause:    err,
+		}}
 	}
 
 	return &model.PaymentIntentResponse{
 		ID: res.ID,
 		Amount: res.Amount,
 		Currency: res.Currency,
+		AmountDisplay: model.FormatAmount(res.Amount, res.Currency),
 		Status: res.Status,
 		ClientSecret: res.ClientSecret,
+		Metadata: attempt.Metadata,
+		Provider: attempt.Provider,
+		Attempts: chain,
 	}, nil
 }
 
@@ -150,8 +368,8 @@ func (s *PaymentService) GetPaymentIntent(ctx context.Context, provider model.Pa
 		return nil, err
 	}
 
-	// Add payment-processor-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// Add payment-processor-specific timeout
+    ctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
     defer cancel()
 
 	pi_response, err := processor.GetPaymentIntent(ctx, id)
@@ -163,8 +381,10 @@ func (s *PaymentService) GetPaymentIntent(ctx context.Context, provider model.Pa
 		ID: pi_response.ID,
 		Amount: pi_response.Amount,
 		Currency: pi_response.Currency,
+		AmountDisplay: model.FormatAmount(pi_response.Amount, pi_response.Currency),
 		Status: pi_response.Status,
 		ClientSecret: pi_response.ClientSecret,
+		Metadata: pi_response.Metadata,
 	}, nil
 }
 
@@ -174,76 +394,71 @@ func (s *PaymentService) ParseWebhook(ctx context.Context, provider model.Paymen
 		return nil, err
 	}
 
-	//Select the repo to interact with table Transactions in DB
-	transaction, err := s.repositoryRegistry.Get(core.TransactionRepo)
-	if err != nil {
-		return nil, err
-	}
-
-	payment_method, err := s.repositoryRegistry.Get(core.PaymentRepo)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add payment-processor-specific timeout (5 seconds)
-    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	// Add payment-processor-specific timeout
+    ctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
     defer cancel()
 
 	event, err := processor.ParseWebhook(ctx, raw, headers)
 	if err != nil {
-		return nil, err
+		// Bad signatures and payloads, adapters keep these messages safe
+		return nil, &model.Error{Category: model.CategoryInvalidRequest, Code: "webhook_invalid", Message: err.Error()}
 	}
 
 	switch event.Type {
 	case ports.PaymentSucceeded:
-		// Handle successful payment
-		err := transaction.UpdateStatus(ctx, ports.PaymentSucceeded, map[string]interface{}{
-    		"payment_intent_id": event.PaymentIntent,
-		})
-
-		if err != nil {
-			return nil, err
-		}
+		// Handle successful payment, status and payment method move together
+		err := s.uow.Do(ctx, func(repos ports.Repositories) error {
+			txdata, err := matchTransaction(ctx, repos.Transactions, event)
+			if err != nil {
+				return fmt.Errorf("Error while finding payment intent: %w", err)
+			}
 
-		// Save payment method if option true
-		pm_saveTrue := true
-		txdata, err := transaction.FindByPaymentIntent(ctx, string(event.PaymentIntent))
+			err = repos.Transactions.UpdateStatus(ctx, ports.PaymentSucceeded, map[string]interface{}{
+    			"id": txdata.ID,
+			})
 
-		if err != nil {
-			return nil, fmt.Errorf("Error while finding payment intent: %w", err)
-		}
+			if err != nil {
+				return err
+			}
 
-		if txdata != nil {
+			// Save payment method if option true
+			pm_saveTrue := true
 			if txdata.SavePaymentMethod !=nil && *txdata.SavePaymentMethod == pm_saveTrue {
 				// Payment method is requested to be saved
-				pm, _ := payment_method.FindByID(ctx, string(event.PaymentMethod))
-				if pm == nil {
-					// Add new payment method to DB
-					newPm := model.Transaction{CustomerID: txdata.CustomerID,
-						PaymentMethodID: event.PaymentMethod,
-						PaymentMethodStatus: ports.PaymentMethodStatus,
-						PaymentProvider: string(provider),
-					}
-					err := payment_method.Create(ctx, &newPm)
-					if err != nil {
-						log.Printf("Saving payment method--Event: %+v", err)
-					}
+				err = savePaymentMethod(ctx, repos.PaymentMethods, provider, txdata.CustomerID, event.PaymentMethod)
+				if err != nil {
+					return err
 				}
 			}
-		}
-	case ports.PaymentFailed:
-		// Handle failed payment
-			err := transaction.UpdateStatus(ctx, ports.PaymentFailed, map[string]interface{}{
-    		"payment_intent_id": event.PaymentIntent,
+
+			if txdata.OrderID != "" {
+				return refreshOrderStatus(ctx, repos, txdata.OrderID)
+			}
+			return nil
 		})
 
 		if err != nil {
 			return nil, err
 		}
-	case ports.PaymentCancelled:
-		// Handle cancelled payment
-			err := transaction.UpdateStatus(ctx, ports.PaymentCancelled, map[string]interface{}{
-    		"payment_intent_id": event.PaymentIntent,
+	case ports.PaymentFailed, ports.PaymentCancelled:
+		// Handle failed or cancelled payment
+		err := s.uow.Do(ctx, func(repos ports.Repositories) error {
+			txdata, err := matchTransaction(ctx, repos.Transactions, event)
+			if err != nil {
+				return fmt.Errorf("Error while finding payment intent: %w", err)
+			}
+
+			err = repos.Transactions.UpdateStatus(ctx, event.Type, map[string]interface{}{
+				"id": txdata.ID,
+			})
+			if err != nil {
+				return err
+			}
+
+			if txdata.OrderID != "" {
+				return refreshOrderStatus(ctx, repos, txdata.OrderID)
+			}
+			return nil
 		})
 
 		if err != nil {
@@ -255,14 +470,8 @@ func (s *PaymentService) ParseWebhook(ctx context.Context, provider model.Paymen
 }
 
 func (s *PaymentService) GetUserPMethods(ctx context.Context, userId string) ([]model.PaymentMethodResponse, error) {
-    // Select the repo to interact with table payment_method
-    payment_method, err := s.repositoryRegistry.Get(core.PaymentRepo)
-    if err != nil {
-        return nil, err
-    }
-
     // Get payment methods for the user
-    paymentMethods, err := payment_method.FindByColumn(ctx, "customer_id", userId)
+    paymentMethods, err := s.paymentMethods.FindByCustomer(ctx, userId)
     if err != nil {
         log.Printf("Error fetching payment methods: %v", err)
         return nil, fmt.Errorf("failed to get payment methods: %w", err)
@@ -272,13 +481,347 @@ func (s *PaymentService) GetUserPMethods(ctx context.Context, userId string) ([]
     var responses []model.PaymentMethodResponse
     for _, pm := range paymentMethods {
         response := model.PaymentMethodResponse{
-            ClientID:				pm.CustomerID,               // Assuming pm has ID field
-            PaymentMethodID:  		pm.PaymentMethodID,           // Assuming pm has Amount field
-            PaymentProvider:  		pm.PaymentProvider,         // Assuming pm has Currency field
-            PaymentMethodType:  	pm.PaymentMethodType,         // Assuming status field is named TxStatus
-            PaymentMethodStatus:	pm.PaymentMethodStatus,             // Using userId as ClientSecret as in your example
+            ClientID:				pm.CustomerID,
+            PaymentMethodID:  		pm.ID,
+            PaymentProvider:  		pm.Provider,
+            PaymentMethodType:  	pm.Type,
+            PaymentMethodStatus:	pm.Status,
         }
         responses = append(responses, response)
     }
     return responses, nil
-}
\ No newline at end of file
+}
+
+// Only terminal provider statuses are reconciled, anything else keeps ours
+func TxStatusFromProvider(status string) (string, bool) {
+	switch status {
+	case "succeeded":
+		return ports.PaymentSucceeded, true
+	case "canceled":
+		return ports.PaymentCancelled, true
+	}
+	return "", false
+}
+
+// Status of the transaction for a charge the provider answered with status.
+// Charges still waiting, e.g. requires_action or processing, are pending
+// until a webhook settles them.
+func chargeTxStatus(status string) string {
+	if txStatus, ok := TxStatusFromProvider(status); ok {
+		return txStatus
+	}
+	return ports.Pending
+}
+
+func (s *PaymentService) ResyncStatus(ctx context.Context, provider model.PaymentProvider, intentID string, dryRun bool) (*model.ResyncResult, error) {
+	processor, err := s.providerRegistry.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	txdata, err := s.transactions.FindByPaymentIntent(ctx, intentID)
+	if err != nil {
+		return nil, fmt.Errorf("Error while finding payment intent: %w", err)
+	}
+
+	// Add payment-processor-specific timeout
+	pctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+	defer cancel()
+
+	pi_response, err := processor.GetPaymentIntent(pctx, intentID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.ResyncResult{
+		PaymentIntentID: intentID,
+		PreviousStatus:  txdata.TxStatus,
+		ProviderStatus:  pi_response.Status,
+		NewStatus:       txdata.TxStatus,
+	}
+
+	// Refund states are ours, the provider intent stays "succeeded"
+	if txdata.TxStatus == ports.Refunded || txdata.TxStatus == ports.PartiallyRefunded {
+		return result, nil
+	}
+
+	if status, ok := TxStatusFromProvider(pi_response.Status); ok && status != txdata.TxStatus {
+		result.NewStatus = status
+		result.Changed = true
+	}
+
+	if !result.Changed || dryRun {
+		return result, nil
+	}
+
+	err = s.uow.Do(ctx, func(repos ports.Repositories) error {
+		err := repos.Transactions.UpdateStatus(ctx, result.NewStatus, map[string]interface{}{
+			"payment_intent_id": intentID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if txdata.OrderID != "" {
+			return refreshOrderStatus(ctx, repos, txdata.OrderID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *PaymentService) RefundPayment(ctx context.Context, provider model.PaymentProvider, req model.RefundRequest) (*model.RefundResponse, error) {
+	txdata, err := s.transactions.FindByID(ctx, req.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Routing and failover decide the provider per charge, so a refund can only
+	// go back through the one that took the money
+	if string(provider) != txdata.Provider {
+		return nil, model.Conflict("provider_mismatch", "transaction %s was charged through %s, not %s", txdata.ID, txdata.Provider, provider)
+	}
+
+	processor, err := s.providerRegistry.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	refunder, ok := processor.(ports.IRefunder)
+	if !ok {
+		return nil, model.Invalid("refunds_unsupported", "provider %s does not support refunds", provider)
+	}
+
+	if txdata.TxStatus != ports.PaymentSucceeded && txdata.TxStatus != ports.PartiallyRefunded {
+		return nil, model.Conflict("transaction_not_refundable", "transaction %s cannot be refunded in status %s", txdata.ID, txdata.TxStatus)
+	}
+
+	// Refundable balance is what has not been refunded yet
+	previous, err := s.refunds.FindByTransaction(ctx, txdata.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refunded := refundedAmount(previous)
+	remaining := txdata.Amount - refunded
+	amount := req.Amount
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return nil, model.Invalid("refund_amount_invalid", "refund amount %d exceeds refundable balance %d", amount, remaining)
+	}
+
+	// Add payment-processor-specific timeout
+	pctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+	defer cancel()
+
+	res, err := refunder.Refund(pctx, model.ProviderRefundRequest{
+		PaymentIntentID: txdata.PaymentIntentID,
+		Amount:          amount,
+		Reason:          req.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := ports.PartiallyRefunded
+	if refunded+res.Amount >= txdata.Amount {
+		status = ports.Refunded
+	}
+
+	// The refund row and the transaction status are written together
+	var newRefund model.Refund
+	err = s.uow.Do(ctx, func(repos ports.Repositories) error {
+		newRefund = model.Refund{
+			TransactionID:    txdata.ID,
+			Amount:           res.Amount,
+			Reason:           req.Reason,
+			ProviderRefundID: res.ID,
+			RefundStatus:     res.Status,
+		}
+		if err := repos.Refunds.Create(ctx, &newRefund); err != nil {
+			return err
+		}
+
+		err := repos.Transactions.UpdateStatus(ctx, status, map[string]interface{}{
+			"id": txdata.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if txdata.OrderID != "" {
+			return refreshOrderStatus(ctx, repos, txdata.OrderID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.RefundResponse{
+		ID:                newRefund.ID,
+		InternalReference: newRefund.InternalReference,
+		TransactionID:     txdata.ID,
+		Amount:            newRefund.Amount,
+		AmountDisplay:     model.FormatAmount(newRefund.Amount, txdata.Currency),
+		Currency:          txdata.Currency,
+		Status:            newRefund.RefundStatus,
+		ProviderRefundID:  newRefund.ProviderRefundID,
+	}, nil
+}
+
+func (s *PaymentService) DisablePaymentMethod(ctx context.Context, customerID string, paymentMethodID string) error {
+	return s.paymentMethods.UpdateStatus(ctx, customerID, paymentMethodID, ports.PaymentMethodDisabled)
+}
+
+// Finds the transaction an event is about. Until CreatePaymentIntent has stored
+// the intent ID, the row is matched on the internal reference from the metadata
+// and the intent is attached on the way.
+func matchTransaction(ctx context.Context, transactions ports.ITransactionRepository, event *model.PaymentEvent) (*model.Transaction, error) {
+	txdata, err := transactions.FindByPaymentIntent(ctx, event.PaymentIntent)
+	if !errors.Is(err, ports.ErrNotFound) || event.Reference == "" {
+		return txdata, err
+	}
+
+	txdata, err = transactions.FindByReference(ctx, event.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	err = transactions.AttachPaymentIntent(ctx, txdata.ID, event.PaymentIntent)
+	if err != nil {
+		return nil, err
+	}
+	txdata.PaymentIntentID = event.PaymentIntent
+	return txdata, nil
+}
+
+// Checks a payment can go towards req.Order and returns the order to link it to.
+// Payments still pending and charges not recorded yet hold their amount, so
+// run it in the unit that inserts the payment, concurrent ones then serialise.
+func (s *PaymentService) orderFor(ctx context.Context, repos ports.Repositories, req model.PaymentIntentRequest) (string, error) {
+	if req.Order == "" {
+		return "", nil
+	}
+
+	order, attempts, err := loadOrder(ctx, repos.Orders, repos.Transactions, repos.Refunds, req.Order)
+	if err != nil {
+		return "", err
+	}
+
+	if order.CustomerID != req.CustomerID {
+		return "", model.Invalid("order_customer_mismatch", "order %s belongs to another customer", order.ID)
+	}
+	if !strings.EqualFold(order.Currency, strings.TrimSpace(req.Currency)) {
+		return "", model.Invalid("order_currency_mismatch", "order %s is payable in %s, not %s", order.ID, order.Currency, req.Currency)
+	}
+
+	status, paid := orderStatus(order, attempts)
+	if status != ports.OrderOpen && status != ports.OrderPartiallyPaid {
+		return "", model.Conflict("order_closed", "order %s is %s and takes no more payments", order.ID, status)
+	}
+
+	held := paid
+	for _, a := range attempts {
+		if a.Status == ports.Pending {
+			held += a.Amount
+		}
+	}
+	open, err := repos.ChargeAttempts.FindByOrder(ctx, order.ID, []string{ports.AttemptStarted, ports.AttemptCharged})
+	if err != nil {
+		return "", err
+	}
+	for _, a := range open {
+		held += a.Amount
+	}
+
+	if req.Amount > order.AmountDue-held {
+		return "", model.Invalid("amount_exceeds_order_balance", "amount %d exceeds the %d left to pay on order %s", req.Amount, order.AmountDue-held, order.ID)
+	}
+	return order.ID, nil
+}
+
+// Loads the FX quote the request pays with and switches the request to the
+// quote's presentment side. Amount and currency, when given, must match it.
+func (s *PaymentService) lockedQuote(ctx context.Context, req *model.PaymentIntentRequest) (*model.FxQuote, error) {
+	if req.FxQuote == "" {
+		return nil, nil
+	}
+	if _, err := uuid.Parse(req.FxQuote); err != nil {
+		return nil, fmt.Errorf("error getting fx quote: %w", ports.ErrNotFound)
+	}
+
+	quote, err := s.quotes.FindByID(ctx, req.FxQuote)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := strings.TrimSpace(quote.PresentmentCurrency)
+	switch {
+	case quote.CustomerID != req.CustomerID:
+		return nil, model.Invalid("fx_quote_customer_mismatch", "fx quote %s belongs to another customer", quote.ID)
+	case quote.Status != ports.FxQuoteOpen || !quote.ExpiresAt.After(time.Now()):
+		return nil, model.Conflict("fx_quote_unavailable", "fx quote %s is used or expired, request a new one", quote.ID)
+	case req.Amount != 0 && req.Amount != quote.PresentmentAmount:
+		return nil, model.Invalid("fx_quote_amount_mismatch", "amount %d does not match fx quote %s amount %d", req.Amount, quote.ID, quote.PresentmentAmount)
+	case req.Currency != "" && !strings.EqualFold(strings.TrimSpace(req.Currency), currency):
+		return nil, model.Invalid("fx_quote_currency_mismatch", "currency %s does not match fx quote %s currency %s", req.Currency, quote.ID, currency)
+	}
+
+	req.Amount = quote.PresentmentAmount
+	req.Currency = currency
+	return quote, nil
+}
+
+// Failed and cancelled refunds give nothing back
+func refundedAmount(refunds []model.Refund) int64 {
+	var refunded int64
+	for _, rf := range refunds {
+		if rf.RefundStatus != "failed" && rf.RefundStatus != "canceled" {
+			refunded += rf.Amount
+		}
+	}
+	return refunded
+}
+
+// ProviderStats reports call counters and circuit state for every provider
+// wrapped with retries and a circuit breaker
+func (s *PaymentService) ProviderStats() []model.ProviderStats {
+	stats := []model.ProviderStats{}
+	for _, processor := range s.providerRegistry.All() {
+		if counter, ok := processor.(ports.IProviderStats); ok {
+			stats = append(stats, counter.Stats())
+		}
+	}
+	return stats
+}
+
+// Stores a payment method the first time a customer pays with it
+func savePaymentMethod(ctx context.Context, paymentMethods ports.IPaymentMethodRepository, provider model.PaymentProvider, customerID string, paymentMethodID string) error {
+	// Some events carry no payment method, nothing to save then
+	if paymentMethodID == "" {
+		return nil
+	}
+
+	_, err := paymentMethods.FindByID(ctx, paymentMethodID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ports.ErrNotFound) {
+		return err
+	}
+
+	newPm := model.PaymentMethod{
+		ID: paymentMethodID,
+		CustomerID: customerID,
+		Provider: string(provider),
+		Status: ports.PaymentMethodStatus,
+	}
+	return paymentMethods.Create(ctx, &newPm)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/core"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/repository/memory"
+)
+
+// A PaymentService on the memory repositories with the fake provider
+type testService struct {
+	*PaymentService
+	fake         *adapters.FakeAdapter
+	transactions *memory.TransactionRepository
+	refunds      *memory.RefundRepository
+	attempts     *memory.ChargeAttemptRepository
+	orders       *memory.OrderRepository
+}
+
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+
+	registry := core.NewProviderRegistry()
+	fake := adapters.NewFakeAdapter("whsec_test")
+	registry.Register(adapters.FakeProvider, fake)
+
+	transactions := memory.NewTransactionRepository()
+	paymentMethods := memory.NewPaymentMethodRepository()
+	refunds := memory.NewRefundRepository(transactions)
+	attempts := memory.NewChargeAttemptRepository()
+	orders := memory.NewOrderRepository()
+	quotes := memory.NewFxQuoteRepository()
+	uow := memory.NewUnitOfWork(transactions, paymentMethods, refunds, attempts, orders, quotes)
+
+	return &testService{
+		PaymentService: NewPaymentService(registry, nil, transactions, paymentMethods, refunds, attempts, orders, quotes, uow, time.Second),
+		fake:           fake,
+		transactions:   transactions,
+		refunds:        refunds,
+		attempts:       attempts,
+		orders:         orders,
+	}
+}
+
+func testCharge(amount int64, token string) model.PaymentIntentRequest {
+	remember := true
+	return model.PaymentIntentRequest{Amount: amount, Currency: "usd", CustomerID: "cus_test", Token: token, RememberMe: &remember}
+}
+
+func TestChargeWaitingOnTheCustomerIsRecordedPending(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	res, err := svc.ChargeClient(ctx, adapters.FakeProvider, testCharge(1250, adapters.FakeTokenRequiresAction))
+	if err != nil {
+		t.Fatalf("ChargeClient: %v", err)
+	}
+	if res.Status != "requires_action" {
+		t.Fatalf("expected requires_action, got %s", res.Status)
+	}
+
+	tx, err := svc.transactions.FindByPaymentIntent(ctx, res.ID)
+	if err != nil {
+		t.Fatalf("FindByPaymentIntent: %v", err)
+	}
+	if tx.TxStatus != ports.Pending {
+		t.Fatalf("expected a pending transaction, got %s", tx.TxStatus)
+	}
+	if methods, _ := svc.GetUserPMethods(ctx, "cus_test"); len(methods) != 0 {
+		t.Fatalf("expected the method to wait for the charge to succeed, got %+v", methods)
+	}
+
+	// Nothing is left for recovery to refund or record as paid
+	results, err := svc.RecoverChargeAttempts(ctx, time.Now().Add(time.Minute), 10, RecoveryRefund)
+	if err != nil {
+		t.Fatalf("RecoverChargeAttempts: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected no attempt to recover, got %+v", results)
+	}
+}
+
+func TestSucceededChargeIsRecordedSucceeded(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	res, err := svc.ChargeClient(ctx, adapters.FakeProvider, testCharge(1250, adapters.FakeTokenSuccess))
+	if err != nil {
+		t.Fatalf("ChargeClient: %v", err)
+	}
+	tx, err := svc.transactions.FindByPaymentIntent(ctx, res.ID)
+	if err != nil {
+		t.Fatalf("FindByPaymentIntent: %v", err)
+	}
+	if tx.TxStatus != ports.PaymentSucceeded {
+		t.Fatalf("expected a succeeded transaction, got %s", tx.TxStatus)
+	}
+	if methods, _ := svc.GetUserPMethods(ctx, "cus_test"); len(methods) != 1 {
+		t.Fatalf("expected the method to be saved, got %+v", methods)
+	}
+}
+
+func TestRefundGoesThroughTheChargingProvider(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	res, err := svc.ChargeClient(ctx, adapters.FakeProvider, testCharge(1250, adapters.FakeTokenSuccess))
+	if err != nil {
+		t.Fatalf("ChargeClient: %v", err)
+	}
+	tx, err := svc.transactions.FindByPaymentIntent(ctx, res.ID)
+	if err != nil {
+		t.Fatalf("FindByPaymentIntent: %v", err)
+	}
+
+	_, err = svc.RefundPayment(ctx, "stripe", model.RefundRequest{TransactionID: tx.ID})
+	var merr *model.Error
+	if !errors.As(err, &merr) || merr.Code != "provider_mismatch" {
+		t.Fatalf("expected provider_mismatch, got %v", err)
+	}
+
+	refund, err := svc.RefundPayment(ctx, adapters.FakeProvider, model.RefundRequest{TransactionID: tx.ID})
+	if err != nil {
+		t.Fatalf("RefundPayment: %v", err)
+	}
+	if refund.Amount != 1250 {
+		t.Fatalf("expected the full amount to be refunded, got %d", refund.Amount)
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+const (
+	MaxTenderLegs     = 5
+	splitUnwindReason = "split tender unwound"
+)
+
+// SplitTender charges each leg in turn against one order. When a leg fails or
+// does not succeed at once, e.g. it waits on 3-D Secure, its intent is cancelled
+// and the legs already charged are refunded, so the order ends fully paid or unwound.
+// Leg failures are reported in the response, errors mean nothing was charged.
+func (s *PaymentService) SplitTender(ctx context.Context, req model.SplitTenderRequest) (*model.SplitTenderResponse, error) {
+	if len(req.Legs) == 0 {
+		return nil, model.Invalid("legs_required", "at least one leg is required")
+	}
+	if len(req.Legs) > MaxTenderLegs {
+		return nil, model.Invalid("too_many_legs", "split tender has %d legs, at most %d are allowed", len(req.Legs), MaxTenderLegs)
+	}
+	if req.CustomerID == "" {
+		return nil, model.Invalid("customer_id_required", "customer_id is required")
+	}
+
+	currency, err := model.LookupCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for i, leg := range req.Legs {
+		if leg.Token == "" {
+			return nil, model.Invalid("token_required", "leg %d: token is required", i+1)
+		}
+		processor, err := s.providerRegistry.Get(leg.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("leg %d: %w", i+1, err)
+		}
+		if _, err := checkAmount(processor, leg.Amount, req.Currency); err != nil {
+			return nil, fmt.Errorf("leg %d: %w", i+1, err)
+		}
+		// Never charge a leg we could not give back
+		if _, ok := processor.(ports.IRefunder); !ok {
+			return nil, model.Invalid("refunds_unsupported", "leg %d: provider %s does not support refunds", i+1, leg.Provider)
+		}
+		total += leg.Amount
+	}
+
+	base := model.PaymentIntentRequest{
+		Amount:                    total,
+		Currency:                  currency.Code,
+		CustomerID:                req.CustomerID,
+		Metadata:                  req.Metadata,
+		OrderID:                   req.OrderID,
+		Description:               req.Description,
+		ReceiptEmail:              req.ReceiptEmail,
+		StatementDescriptorSuffix: req.StatementDescriptorSuffix,
+		Order:                     req.Order,
+	}
+	if _, err := paymentMetadata(base); err != nil {
+		return nil, err
+	}
+
+	// Each leg checks the order again as it is charged
+	var orderID string
+	err = s.uow.Do(ctx, func(repos ports.Repositories) (err error) {
+		orderID, err = s.orderFor(ctx, repos, base)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if orderID == "" {
+		order := model.Order{
+			CustomerID: req.CustomerID,
+			AmountDue:  total,
+			Currency:   base.Currency,
+			Metadata:   req.Metadata,
+		}
+		if err := s.orders.Create(ctx, &order); err != nil {
+			return nil, err
+		}
+		orderID = order.ID
+	}
+
+	res := &model.SplitTenderResponse{
+		Order:    orderID,
+		Status:   ports.SplitSucceeded,
+		Amount:   total,
+		Currency: base.Currency,
+		Legs:     make([]model.TenderLegResult, len(req.Legs)),
+	}
+	for i, leg := range req.Legs {
+		res.Legs[i] = model.TenderLegResult{Provider: leg.Provider, Amount: leg.Amount, Status: ports.LegSkipped}
+	}
+
+	for i, leg := range req.Legs {
+		legReq := base
+		legReq.Amount = leg.Amount
+		legReq.Token = leg.Token
+		legReq.Order = orderID
+
+		charge, err := s.ChargeClient(ctx, leg.Provider, legReq)
+		if err == nil && charge.Status != "succeeded" {
+			// A split cannot wait on the customer, the intent is voided below
+			res.Legs[i].PaymentIntentID = charge.ID
+			res.Legs[i].Provider = model.PaymentProvider(charge.Provider)
+			err = fmt.Errorf("charge %s is %s", charge.ID, charge.Status)
+		}
+		if err != nil {
+			res.Legs[i].Status = ports.LegFailed
+			var unsettled *unsettledChargeError
+			if errors.As(err, &unsettled) {
+				res.Legs[i].Status = ports.LegUnknown
+			}
+			res.Legs[i].Error = err.Error()
+			res.Error = fmt.Sprintf("leg %d failed: %v", i+1, err)
+
+			s.unwindLegs(ctx, res, i)
+			return res, nil
+		}
+
+		res.Legs[i].Status = ports.LegCharged
+		res.Legs[i].PaymentIntentID = charge.ID
+		// The leg may have failed over, refunds go where it was charged
+		res.Legs[i].Provider = model.PaymentProvider(charge.Provider)
+	}
+
+	return res, nil
+}
+
+// Cancels the failed leg's intent if it has one, then refunds the legs charged
+// before it, newest first. Runs detached from ctx so a client that gives up
+// does not leave the order half paid.
+func (s *PaymentService) unwindLegs(ctx context.Context, res *model.SplitTenderResponse, failed int) {
+	ctx = context.WithoutCancel(ctx)
+
+	res.Status = ports.SplitUnwound
+	if res.Legs[failed].Status == ports.LegUnknown {
+		res.Status = ports.SplitUnwindFailed
+	}
+
+	if leg := &res.Legs[failed]; leg.PaymentIntentID != "" {
+		if err := s.voidLeg(ctx, leg); err != nil {
+			log.Printf("Error cancelling split tender leg %s on order %s: %v", leg.PaymentIntentID, res.Order, err)
+			leg.Status = ports.LegUnknown
+			leg.Error = fmt.Sprintf("%s, cancel failed: %v", leg.Error, err)
+			res.Status = ports.SplitUnwindFailed
+		}
+	}
+
+	for i := failed - 1; i >= 0; i-- {
+		leg := &res.Legs[i]
+		if err := s.voidLeg(ctx, leg); err != nil {
+			log.Printf("Error unwinding split tender leg %s on order %s: %v", leg.PaymentIntentID, res.Order, err)
+			leg.Error = fmt.Sprintf("refund failed: %v", err)
+			res.Status = ports.SplitUnwindFailed
+			continue
+		}
+		leg.Status = ports.LegRefunded
+	}
+}
+
+// Gives back a leg's money: captured charges are refunded, intents that took
+// nothing are cancelled, since refunds fail on them
+func (s *PaymentService) voidLeg(ctx context.Context, leg *model.TenderLegResult) error {
+	txdata, err := s.transactions.FindByPaymentIntent(ctx, leg.PaymentIntentID)
+	if err != nil {
+		return err
+	}
+	if txdata.TxStatus == ports.PaymentSucceeded {
+		_, err = s.RefundPayment(ctx, leg.Provider, model.RefundRequest{
+			TransactionID: txdata.ID,
+			Reason:        splitUnwindReason,
+		})
+		return err
+	}
+	return s.cancelCharge(ctx, leg.Provider, txdata)
+}
+
+// Cancels an intent that took no money and marks its transaction cancelled
+func (s *PaymentService) cancelCharge(ctx context.Context, provider model.PaymentProvider, txdata *model.Transaction) error {
+	processor, err := s.providerRegistry.Get(provider)
+	if err != nil {
+		return err
+	}
+	refunder, ok := processor.(ports.IRefunder)
+	if !ok {
+		return model.Invalid("refunds_unsupported", "provider %s does not support refunds", provider)
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, s.providerTimeout)
+	defer cancel()
+
+	if _, err := refunder.Cancel(pctx, txdata.PaymentIntentID); err != nil {
+		return err
+	}
+
+	return s.uow.Do(ctx, func(repos ports.Repositories) error {
+		err := repos.Transactions.UpdateStatus(ctx, ports.PaymentCancelled, map[string]interface{}{
+			"id": txdata.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if txdata.OrderID != "" {
+			return refreshOrderStatus(ctx, repos, txdata.OrderID)
+		}
+		return nil
+	})
+}
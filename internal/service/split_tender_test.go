@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/adapters"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+func TestSplitTenderCancelsLegsWaitingOnTheCustomer(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	res, err := svc.SplitTender(ctx, model.SplitTenderRequest{
+		CustomerID: "cus_test",
+		Currency:   "usd",
+		Legs: []model.TenderLeg{
+			{Provider: adapters.FakeProvider, Token: adapters.FakeTokenSuccess, Amount: 1000},
+			{Provider: adapters.FakeProvider, Token: adapters.FakeTokenRequiresAction, Amount: 500},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SplitTender: %v", err)
+	}
+	if res.Status != ports.SplitUnwound {
+		t.Fatalf("expected the split to unwind, got %s: %s", res.Status, res.Error)
+	}
+	if res.Legs[0].Status != ports.LegRefunded {
+		t.Fatalf("expected the first leg to be refunded, got %+v", res.Legs[0])
+	}
+	if res.Legs[1].Status != ports.LegFailed || res.Legs[1].PaymentIntentID == "" {
+		t.Fatalf("expected the second leg to fail with its intent, got %+v", res.Legs[1])
+	}
+
+	intent, err := svc.fake.GetPaymentIntent(ctx, res.Legs[1].PaymentIntentID)
+	if err != nil {
+		t.Fatalf("GetPaymentIntent: %v", err)
+	}
+	if intent.Status != "canceled" {
+		t.Fatalf("expected the uncaptured intent to be canceled, got %s", intent.Status)
+	}
+
+	tx, err := svc.transactions.FindByPaymentIntent(ctx, res.Legs[1].PaymentIntentID)
+	if err != nil {
+		t.Fatalf("FindByPaymentIntent: %v", err)
+	}
+	if tx.TxStatus != ports.PaymentCancelled {
+		t.Fatalf("expected the transaction to be cancelled, got %s", tx.TxStatus)
+	}
+}
+
+func TestSplitTenderChargesEveryLeg(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	res, err := svc.SplitTender(ctx, model.SplitTenderRequest{
+		CustomerID: "cus_test",
+		Currency:   "usd",
+		Legs: []model.TenderLeg{
+			{Provider: adapters.FakeProvider, Token: adapters.FakeTokenSuccess, Amount: 1000},
+			{Provider: adapters.FakeProvider, Token: adapters.FakeTokenSuccess, Amount: 500},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SplitTender: %v", err)
+	}
+	if res.Status != ports.SplitSucceeded {
+		t.Fatalf("expected the split to succeed, got %s: %s", res.Status, res.Error)
+	}
+	for i, leg := range res.Legs {
+		if leg.Status != ports.LegCharged {
+			t.Fatalf("expected leg %d to be charged, got %+v", i+1, leg)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/ports"
+)
+
+// Limits on caller-supplied details, Stripe's are the tightest we integrate with
+const (
+	MaxMetadataKeys                    = 50
+	MaxMetadataKeyLength               = 40
+	MaxMetadataValueLength             = 500
+	MaxDescriptionLength               = 1000
+	MaxStatementDescriptorSuffixLength = 22
+)
+
+var reservedMetadataKeys = map[string]bool{
+	ports.MetadataOrderID:   true,
+	ports.MetadataReference: true,
+	ports.MetadataAttemptID: true,
+}
+
+// CheckMetadata applies the key count, length and naming limits
+func CheckMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataKeys {
+		return model.Invalid("metadata_invalid", "metadata has %d keys, at most %d are allowed", len(metadata), MaxMetadataKeys)
+	}
+
+	for key, value := range metadata {
+		switch {
+		case key == "":
+			return model.Invalid("metadata_invalid", "metadata keys must not be empty")
+		case utf8.RuneCountInString(key) > MaxMetadataKeyLength:
+			return model.Invalid("metadata_invalid", "metadata key %q exceeds %d characters", key, MaxMetadataKeyLength)
+		case strings.ContainsAny(key, "[]"):
+			return model.Invalid("metadata_invalid", "metadata key %q must not contain square brackets", key)
+		case reservedMetadataKeys[key]:
+			return model.Invalid("metadata_invalid", "metadata key %q is reserved", key)
+		case utf8.RuneCountInString(value) > MaxMetadataValueLength:
+			return model.Invalid("metadata_invalid", "metadata value for %q exceeds %d characters", key, MaxMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// CheckEmail accepts a bare address only, without a display name
+func CheckEmail(field string, email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return model.Invalid(field+"_invalid", "%s %q is not a valid email address", field, email)
+	}
+	return nil
+}
+
+// Card networks only print a short latin descriptor
+func CheckDescriptorSuffix(suffix string) error {
+	if suffix == "" {
+		return nil
+	}
+	if len(suffix) > MaxStatementDescriptorSuffixLength {
+		return model.Invalid("statement_descriptor_invalid", "statement_descriptor_suffix exceeds %d characters", MaxStatementDescriptorSuffixLength)
+	}
+	if strings.ContainsAny(suffix, `<>\'"*`) {
+		return model.Invalid("statement_descriptor_invalid", "statement_descriptor_suffix must not contain any of <>\\'\"*")
+	}
+
+	letter := false
+	for _, r := range suffix {
+		if r > unicode.MaxASCII {
+			return model.Invalid("statement_descriptor_invalid", "statement_descriptor_suffix must only contain latin characters")
+		}
+		letter = letter || unicode.IsLetter(r)
+	}
+	if !letter {
+		return model.Invalid("statement_descriptor_invalid", "statement_descriptor_suffix must contain at least one letter")
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Package validation checks request structs against the rules in their
+// validate tags, e.g.
+//
+//	CustomerID string `json:"customer_id" validate:"required,maxlen=50"`
+//
+// Rules other than the required ones are skipped for empty values:
+//
+//	required            the value must not be empty
+//	required_on=a|b     required when validating for scenario a or b
+//	required_without=f  required unless the field with JSON name f is set
+//	min=n, max=n        bounds for integers
+//	maxlen=n            characters in a string, entries in a slice or map
+//	oneof=a b           one of the listed strings
+//	currency            an ISO 4217 code
+//	email               a bare email address
+//	uuid                one of our ids
+//	metadata            the provider metadata limits
+//	descriptor          a statement descriptor suffix
+//	dive                validates each element of a slice of structs
+//
+// Embedded structs are checked as if their fields were declared in place.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// Scenarios for request types shared by several endpoints
+const (
+	// Creating a payment intent
+	ScenarioIntent = "intent"
+	// Charging a saved payment method directly
+	ScenarioCharge = "charge"
+)
+
+// Code of the error returned when any field is invalid
+const CodeValidationFailed = "validation_failed"
+
+// Struct validates v, a struct or a pointer to one, for scenario. All invalid
+// fields are returned together in one validation error, nil when v is valid.
+func Struct(v any, scenario string) error {
+	c := &checker{scenario: scenario}
+	c.walk(reflect.Indirect(reflect.ValueOf(v)), "")
+	if len(c.fields) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(c.fields))
+	for i, f := range c.fields {
+		messages[i] = f.Message
+	}
+	return &model.Error{
+		Category: model.CategoryValidation,
+		Code:     CodeValidationFailed,
+		Message:  strings.Join(messages, "; "),
+		Fields:   c.fields,
+	}
+}
+
+type checker struct {
+	scenario string
+	fields   []model.FieldError
+}
+
+func (c *checker) walk(v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			// Embedded fields are flattened into the same JSON object
+			c.walk(v.Field(i), prefix)
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+		c.field(v, v.Field(i), prefix+jsonName(field), tag)
+	}
+}
+
+func (c *checker) field(parent reflect.Value, v reflect.Value, path string, tag string) {
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "required":
+			if v.IsZero() {
+				c.fail(path, "required", "%s is required", path)
+				return
+			}
+			continue
+		case "required_on":
+			if v.IsZero() && slices.Contains(strings.Split(arg, "|"), c.scenario) {
+				c.fail(path, "required", "%s is required", path)
+				return
+			}
+			continue
+		case "required_without":
+			if v.IsZero() && siblingIsZero(parent, arg) {
+				c.fail(path, "required", "%s is required unless %s is set", path, arg)
+				return
+			}
+			continue
+		}
+
+		if v.IsZero() {
+			return
+		}
+		if !c.check(v, path, name, arg) {
+			return
+		}
+	}
+}
+
+// Applies one rule to a non-empty value, false when it failed
+func (c *checker) check(v reflect.Value, path string, name string, arg string) bool {
+	switch name {
+	case "min":
+		if n := mustInt(arg); v.Int() < n {
+			return c.fail(path, "too_small", "%s must be at least %d", path, n)
+		}
+	case "max":
+		if n := mustInt(arg); v.Int() > n {
+			return c.fail(path, "too_large", "%s must be at most %d", path, n)
+		}
+	case "maxlen":
+		n := int(mustInt(arg))
+		if v.Kind() == reflect.String {
+			if utf8.RuneCountInString(v.String()) > n {
+				return c.fail(path, "too_long", "%s exceeds %d characters", path, n)
+			}
+		} else if v.Len() > n {
+			return c.fail(path, "too_many", "%s has %d entries, at most %d are allowed", path, v.Len(), n)
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		if !slices.Contains(options, v.String()) {
+			return c.fail(path, "invalid", "%s must be one of %s", path, strings.Join(options, ", "))
+		}
+	case "currency":
+		if _, err := model.LookupCurrency(v.String()); err != nil {
+			return c.fail(path, "currency_invalid", "%s %q is not an ISO 4217 currency code", path, v.String())
+		}
+	case "email":
+		if err := CheckEmail(path, v.String()); err != nil {
+			return c.fail(path, "email_invalid", "%s %q is not a valid email address", path, v.String())
+		}
+	case "uuid":
+		if _, err := uuid.Parse(v.String()); err != nil {
+			return c.fail(path, "id_invalid", "%s %q is not a valid id", path, v.String())
+		}
+	case "metadata":
+		metadata, _ := v.Interface().(map[string]string)
+		if err := CheckMetadata(metadata); err != nil {
+			return c.fail(path, "metadata_invalid", "%s", err.Error())
+		}
+	case "descriptor":
+		if err := CheckDescriptorSuffix(v.String()); err != nil {
+			return c.fail(path, "descriptor_invalid", "%s", err.Error())
+		}
+	case "dive":
+		for i := 0; i < v.Len(); i++ {
+			c.walk(reflect.Indirect(v.Index(i)), fmt.Sprintf("%s[%d].", path, i))
+		}
+	default:
+		panic(fmt.Sprintf("validation: unknown rule %q on %s", name, path))
+	}
+	return true
+}
+
+func (c *checker) fail(path string, code string, format string, args ...any) bool {
+	c.fields = append(c.fields, model.FieldError{Field: path, Code: code, Message: fmt.Sprintf(format, args...)})
+	return false
+}
+
+func siblingIsZero(parent reflect.Value, name string) bool {
+	t := parent.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if jsonName(t.Field(i)) == name {
+			return parent.Field(i).IsZero()
+		}
+	}
+	panic(fmt.Sprintf("validation: %s has no field %q", t.Name(), name))
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func mustInt(arg string) int64 {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: %q is not a number", arg))
+	}
+	return n
+}
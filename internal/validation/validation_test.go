@@ -0,0 +1,210 @@
+package validation_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+	"github.com/danielmoisemontezima/zw-payment-service/internal/validation"
+)
+
+type item struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type base struct {
+	Reference string `json:"reference" validate:"maxlen=3"`
+}
+
+type request struct {
+	base
+	ID         string            `json:"id" validate:"required"`
+	Token      string            `json:"token" validate:"required_on=charge|capture"`
+	Amount     int64             `json:"amount" validate:"required_without=quote,min=1,max=100"`
+	Quote      string            `json:"quote"`
+	Note       string            `json:"note" validate:"maxlen=3"`
+	Tags       map[string]string `json:"tags" validate:"maxlen=1"`
+	Outcome    string            `json:"outcome" validate:"oneof=succeeded failed"`
+	Currency   string            `json:"currency" validate:"currency"`
+	Email      string            `json:"email" validate:"email"`
+	Order      string            `json:"order" validate:"uuid"`
+	Metadata   map[string]string `json:"metadata" validate:"metadata"`
+	Descriptor string            `json:"descriptor" validate:"descriptor"`
+	Items      []item            `json:"items" validate:"maxlen=2,dive"`
+	notChecked string            `validate:"required"`
+	NotTagged  string
+}
+
+// A request that passes every rule, for cases to break one field of
+func validRequest() request {
+	return request{ID: "req_1", Amount: 50}
+}
+
+func TestStructRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario string
+		modify   func(r *request)
+		field    string
+		code     string
+	}{
+		{name: "valid", modify: func(r *request) {}},
+		{name: "all optional rules pass", modify: func(r *request) {
+			r.Reference = "abc"
+			r.Note = "héé"
+			r.Tags = map[string]string{"a": "b"}
+			r.Outcome = "failed"
+			r.Currency = "EUR"
+			r.Email = "jane@example.com"
+			r.Order = "0b7f3c3e-4b6e-4c55-9d2e-5d0f6f1f3a11"
+			r.Metadata = map[string]string{"cart": "42"}
+			r.Descriptor = "ORDER 42"
+			r.Items = []item{{Name: "a"}, {Name: "b"}}
+		}},
+
+		{name: "required", modify: func(r *request) { r.ID = "" }, field: "id", code: "required"},
+		{name: "required_on outside its scenarios", scenario: "intent", modify: func(r *request) {}},
+		{name: "required_on for one scenario", scenario: "charge", modify: func(r *request) {}, field: "token", code: "required"},
+		{name: "required_on for another scenario", scenario: "capture", modify: func(r *request) {}, field: "token", code: "required"},
+		{name: "required_on when set", scenario: "charge", modify: func(r *request) { r.Token = "tok" }},
+		{name: "required_without without the sibling", modify: func(r *request) { r.Amount = 0 }, field: "amount", code: "required"},
+		{name: "required_without with the sibling", modify: func(r *request) { r.Amount, r.Quote = 0, "q_1" }},
+
+		{name: "min", modify: func(r *request) { r.Amount = -1 }, field: "amount", code: "too_small"},
+		{name: "max", modify: func(r *request) { r.Amount = 101 }, field: "amount", code: "too_large"},
+		{name: "max boundary", modify: func(r *request) { r.Amount = 100 }},
+
+		{name: "maxlen counts characters", modify: func(r *request) { r.Note = "éééé" }, field: "note", code: "too_long"},
+		{name: "maxlen on a map", modify: func(r *request) { r.Tags = map[string]string{"a": "1", "b": "2"} }, field: "tags", code: "too_many"},
+		{name: "maxlen on a slice", modify: func(r *request) { r.Items = []item{{"a"}, {"b"}, {"c"}} }, field: "items", code: "too_many"},
+		{name: "maxlen on an embedded field", modify: func(r *request) { r.Reference = "abcd" }, field: "reference", code: "too_long"},
+
+		{name: "oneof", modify: func(r *request) { r.Outcome = "pending" }, field: "outcome", code: "invalid"},
+		{name: "currency", modify: func(r *request) { r.Currency = "EURO" }, field: "currency", code: "currency_invalid"},
+		{name: "email", modify: func(r *request) { r.Email = "not-an-email" }, field: "email", code: "email_invalid"},
+		{name: "email with a display name", modify: func(r *request) { r.Email = "Jane <jane@example.com>" }, field: "email", code: "email_invalid"},
+		{name: "uuid", modify: func(r *request) { r.Order = "order-1" }, field: "order", code: "id_invalid"},
+		{name: "metadata reserved key", modify: func(r *request) { r.Metadata = map[string]string{"order_id": "1"} }, field: "metadata", code: "metadata_invalid"},
+		{name: "metadata brackets", modify: func(r *request) { r.Metadata = map[string]string{"a[b]": "1"} }, field: "metadata", code: "metadata_invalid"},
+		{name: "descriptor", modify: func(r *request) { r.Descriptor = "12345" }, field: "descriptor", code: "descriptor_invalid"},
+		{name: "dive", modify: func(r *request) { r.Items = []item{{Name: "a"}, {}} }, field: "items[1].name", code: "required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := validRequest()
+			tt.modify(&r)
+			err := validation.Struct(&r, tt.scenario)
+
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			fields := fieldErrors(t, err)
+			if len(fields) != 1 || fields[0].Field != tt.field || fields[0].Code != tt.code {
+				t.Fatalf("expected %s on %s, got %+v", tt.code, tt.field, fields)
+			}
+		})
+	}
+}
+
+func TestStructReportsEveryField(t *testing.T) {
+	r := request{Amount: 500, Currency: "xyz", Items: []item{{}}}
+	err := validation.Struct(r, "charge")
+
+	fields := fieldErrors(t, err)
+	var got []string
+	for _, f := range fields {
+		got = append(got, f.Field+":"+f.Code)
+	}
+	want := []string{"id:required", "token:required", "amount:too_large", "currency:currency_invalid", "items[0].name:required"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	var merr *model.Error
+	errors.As(err, &merr)
+	if merr.Category != model.CategoryValidation || merr.Code != validation.CodeValidationFailed {
+		t.Fatalf("expected a validation_failed error, got %+v", merr)
+	}
+	if strings.Count(merr.Message, ";") != len(want)-1 {
+		t.Fatalf("expected one message per field, got %q", merr.Message)
+	}
+}
+
+// Tags are only read while a request is being validated, so a typo in one
+// would otherwise panic in production. Every request type is validated empty
+// and with every field set, which runs each rule of its tags at least once.
+func TestRequestTypeTags(t *testing.T) {
+	requests := []any{
+		model.PaymentInfoRequest{},
+		model.PaymentIntentRequest{},
+		model.CreatePaymentIntentRequest{},
+		model.CreateRefundRequest{},
+		model.RefundRequest{},
+		model.OrderRequest{},
+		model.FxQuoteRequest{},
+		model.SplitTenderRequest{},
+		model.FakeWebhookRequest{},
+	}
+	scenarios := []string{"", validation.ScenarioIntent, validation.ScenarioCharge}
+
+	for _, req := range requests {
+		t.Run(reflect.TypeOf(req).Name(), func(t *testing.T) {
+			filled := reflect.New(reflect.TypeOf(req)).Elem()
+			fill(filled)
+
+			for _, scenario := range scenarios {
+				validation.Struct(req, scenario)
+				validation.Struct(filled.Interface(), scenario)
+			}
+		})
+	}
+}
+
+// Sets every exported field of v to a non-empty value
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		key := reflect.New(v.Type().Key()).Elem()
+		value := reflect.New(v.Type().Elem()).Elem()
+		fill(key)
+		fill(value)
+		m.SetMapIndex(key, value)
+		v.Set(m)
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fill(s.Index(0))
+		v.Set(s)
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		fill(p.Elem())
+		v.Set(p)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i))
+			}
+		}
+	}
+}
+
+func fieldErrors(t *testing.T, err error) []model.FieldError {
+	t.Helper()
+	var merr *model.Error
+	if !errors.As(err, &merr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	return merr.Fields
+}
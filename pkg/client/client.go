@@ -0,0 +1,227 @@
+// Package client calls the /v2 API of the payment service:
+//
+//	c := client.New("http://payments.internal:8080")
+//	intent, err := c.Charge(ctx, client.CreatePaymentIntentRequest{Provider: "stripe", ...})
+//	if client.Code(err) == "card_declined" { ... }
+//
+// Every POST is sent with an Idempotency-Key, generated unless
+// WithIdempotencyKey sets one, so the client retries it safely. Failures come
+// back as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Header the service replays a POST's first response for
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	defaultRetries = 2
+	defaultBackoff = 200 * time.Millisecond
+	defaultTimeout = 30 * time.Second
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the default client, which times out after 30s
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries sets how many times a failed call is retried, 2 by default,
+// and the wait before the first retry, doubled for every next one
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// New returns a client of the service at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	idempotencyKey string
+}
+
+// WithIdempotencyKey sends key instead of a generated one, so retrying a
+// call across restarts of the caller does not run it twice
+func WithIdempotencyKey(key string) CallOption {
+	return func(o *callOptions) { o.idempotencyKey = key }
+}
+
+// Sends the request, retrying what is safe to retry, and decodes the data of
+// the response into out. Statuses in accept are decoded like a 2xx.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, out any, opts []CallOption, accept ...int) error {
+	var raw []byte
+	if body != nil {
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	var call callOptions
+	for _, opt := range opts {
+		opt(&call)
+	}
+	if method == http.MethodPost && call.idempotencyKey == "" {
+		call.idempotencyKey = uuid.NewString()
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		status, data, err := c.send(ctx, method, target, raw, call.idempotencyKey)
+		if attempt < c.retries && ctx.Err() == nil && retryable(method, status, data, err) {
+			if err := c.wait(ctx, attempt); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return decode(status, data, out, accept)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, target string, raw []byte, idempotencyKey string) (int, []byte, error) {
+	var body io.Reader
+	if raw != nil {
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if raw != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("reading %s %s response: %w", method, target, err)
+	}
+	return res.StatusCode, data, nil
+}
+
+// GETs are retried when the service or a gateway in front of it failed. POSTs
+// only when their outcome is known not to be recorded yet: nothing reached
+// the service or a provider, or the first try still holds the key.
+func retryable(method string, status int, data []byte, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch status {
+	case http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return method == http.MethodGet
+	case http.StatusConflict:
+		return method == http.MethodPost && decodeError(status, data).Code == "idempotency_key_in_use"
+	}
+	return false
+}
+
+// Waits about backoff * 2^attempt, jittered so callers retrying together spread out
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	d := c.backoff << attempt
+	if d > 0 {
+		d = d/2 + rand.N(d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func decode(status int, data []byte, out any, accept []int) error {
+	ok := status >= 200 && status < 300
+	for _, s := range accept {
+		ok = ok || status == s
+	}
+	if !ok {
+		return decodeError(status, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+func decodeError(status int, data []byte) *Error {
+	var envelope struct {
+		Error ErrorDetail `json:"error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Error.Message == "" {
+		// Not the service answering, e.g. a proxy in front of it
+		return &Error{StatusCode: status, ErrorDetail: ErrorDetail{Message: http.StatusText(status)}}
+	}
+	return &Error{StatusCode: status, ErrorDetail: envelope.Error}
+}
+
+func listQuery(params ListParams) url.Values {
+	query := url.Values{}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Cursor != "" {
+		query.Set("cursor", params.Cursor)
+	}
+	return query
+}
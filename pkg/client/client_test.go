@@ -0,0 +1,12 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/danielmoisemontezima/zw-payment-service/pkg/client/clienttest"
+)
+
+// Runs the client against the real router, see clienttest
+func TestClient(t *testing.T) {
+	clienttest.Client(t)
+}
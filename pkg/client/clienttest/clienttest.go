@@ -0,0 +1,314 @@
+// Package clienttest runs the client against the real router on a local
+// listener, see apitest.NewServer:
+//
+//	func TestClient(t *testing.T) {
+//		clienttest.Client(t)
+//	}
+package clienttest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/controller/apitest"
+	"github.com/danielmoisemontezima/zw-payment-service/pkg/client"
+)
+
+const customer = "cus_client"
+
+// Client checks every method, retries, error decoding and webhook signatures
+func Client(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("payment intents and refunds", func(t *testing.T) {
+		c := newClient(t)
+
+		routed, err := c.CreatePaymentIntent(ctx, intent(""))
+		if err != nil {
+			t.Fatalf("CreatePaymentIntent: %v", err)
+		}
+		if routed.ID == "" || routed.Provider != "fake" || routed.RoutingRule == "" {
+			t.Fatalf("expected a routed intent, got %+v", routed)
+		}
+
+		charged, err := c.Charge(ctx, charge(1250))
+		if err != nil {
+			t.Fatalf("Charge: %v", err)
+		}
+		got, err := c.GetPaymentIntent(ctx, charged.ID)
+		if err != nil {
+			t.Fatalf("GetPaymentIntent: %v", err)
+		}
+		if got.ID != charged.ID || got.Amount != 1250 || got.Status != "succeeded" {
+			t.Fatalf("expected the charged intent, got %+v", got)
+		}
+
+		for _, amount := range []int64{250, 300} {
+			if _, err := c.Refund(ctx, charged.ID, client.CreateRefundRequest{Amount: amount, Reason: "requested_by_customer"}); err != nil {
+				t.Fatalf("Refund %d: %v", amount, err)
+			}
+		}
+		first, err := c.ListRefunds(ctx, charged.ID, client.ListParams{Limit: 1})
+		if err != nil {
+			t.Fatalf("ListRefunds: %v", err)
+		}
+		if len(first.Data) != 1 || first.Data[0].Amount != 300 || !first.HasMore || first.NextCursor == "" {
+			t.Fatalf("expected the newest refund and a cursor, got %+v", first)
+		}
+		second, err := c.ListRefunds(ctx, charged.ID, client.ListParams{Limit: 1, Cursor: first.NextCursor})
+		if err != nil {
+			t.Fatalf("ListRefunds: %v", err)
+		}
+		if len(second.Data) != 1 || second.Data[0].Amount != 250 || second.HasMore {
+			t.Fatalf("expected the oldest refund last, got %+v", second)
+		}
+
+		methods, err := c.ListPaymentMethods(ctx, customer, client.ListParams{})
+		if err != nil {
+			t.Fatalf("ListPaymentMethods: %v", err)
+		}
+		if len(methods.Data) != 1 {
+			t.Fatalf("expected the remembered card, got %+v", methods)
+		}
+
+		providers, err := c.ListProviders(ctx)
+		if err != nil {
+			t.Fatalf("ListProviders: %v", err)
+		}
+		if len(providers.Data) != 1 || providers.Data[0].Provider != "fake" {
+			t.Fatalf("expected the fake provider, got %+v", providers)
+		}
+	})
+
+	t.Run("split tender, orders and fx quotes", func(t *testing.T) {
+		c := newClient(t)
+
+		split := client.SplitTenderRequest{
+			CustomerID: customer,
+			Currency:   "usd",
+			Legs: []client.TenderLeg{
+				{Provider: "fake", Token: "pm_fake_mastercard", Amount: 750},
+				{Provider: "fake", Token: "pm_fake_decline_insufficient_funds", Amount: 500},
+			},
+		}
+		unwound, err := c.SplitTender(ctx, split)
+		if err != nil {
+			t.Fatalf("expected a declined split to return its response, got %v", err)
+		}
+		if unwound.Status != "unwound" || unwound.Error == "" {
+			t.Fatalf("expected an unwound split, got %+v", unwound)
+		}
+
+		order, err := c.CreateOrder(ctx, client.OrderRequest{CustomerID: customer, AmountDue: 2000, Currency: "usd"})
+		if err != nil {
+			t.Fatalf("CreateOrder: %v", err)
+		}
+		if got, err := c.GetOrder(ctx, order.ID); err != nil || got.ID != order.ID {
+			t.Fatalf("GetOrder: %+v, %v", got, err)
+		}
+		cancelled, err := c.CancelOrder(ctx, order.ID)
+		if err != nil {
+			t.Fatalf("CancelOrder: %v", err)
+		}
+		if cancelled.Status != "cancelled" {
+			t.Fatalf("expected a cancelled order, got %+v", cancelled)
+		}
+
+		quote, err := c.CreateFxQuote(ctx, client.FxQuoteRequest{CustomerID: customer, Amount: 1000, PresentmentCurrency: "usd"})
+		if err != nil {
+			t.Fatalf("CreateFxQuote: %v", err)
+		}
+		if got, err := c.GetFxQuote(ctx, quote.ID); err != nil || got.Rate != "1.0842" {
+			t.Fatalf("GetFxQuote: %+v, %v", got, err)
+		}
+	})
+
+	t.Run("errors are decoded", func(t *testing.T) {
+		c := newClient(t)
+
+		_, err := c.Charge(ctx, charge(20002))
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected *client.Error, got %v", err)
+		}
+		if apiErr.StatusCode != http.StatusPaymentRequired || apiErr.Code != "card_declined" || apiErr.DeclineCode == "" {
+			t.Fatalf("expected a decline, got %+v", apiErr)
+		}
+
+		_, err = c.Charge(ctx, client.CreatePaymentIntentRequest{Provider: "fake", PaymentIntentRequest: client.PaymentIntentRequest{Amount: -1}})
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || len(apiErr.Fields) < 2 {
+			t.Fatalf("expected every invalid field, got %v", err)
+		}
+
+		if _, err := c.GetPaymentIntent(ctx, "pi_missing"); client.Code(err) != "payment_intent_not_found" {
+			t.Fatalf("expected payment_intent_not_found, got %v", err)
+		}
+	})
+
+	t.Run("posts are idempotent", func(t *testing.T) {
+		c := newClient(t)
+
+		first, err := c.Charge(ctx, charge(1300), client.WithIdempotencyKey("order-42-charge"))
+		if err != nil {
+			t.Fatalf("Charge: %v", err)
+		}
+		again, err := c.Charge(ctx, charge(1300), client.WithIdempotencyKey("order-42-charge"))
+		if err != nil {
+			t.Fatalf("Charge: %v", err)
+		}
+		if again.ID != first.ID {
+			t.Fatalf("expected the first charge to be replayed, got %s and %s", first.ID, again.ID)
+		}
+		if _, err := c.Charge(ctx, charge(1400), client.WithIdempotencyKey("order-42-charge")); client.Code(err) != "idempotency_key_reused" {
+			t.Fatalf("expected idempotency_key_reused, got %v", err)
+		}
+
+		other, err := c.Charge(ctx, charge(1300))
+		if err != nil {
+			t.Fatalf("Charge: %v", err)
+		}
+		if other.ID == first.ID {
+			t.Fatalf("expected calls without a key to get their own")
+		}
+	})
+
+	t.Run("safe calls are retried", func(t *testing.T) {
+		svc := apitest.NewService(t)
+		flaky := &flakyHandler{next: svc.Handler, failures: map[string]int{
+			"POST /v2/charges":  1,
+			"GET /v2/providers": 2,
+			"POST /v2/orders":   5,
+		}, status: map[string]int{
+			"POST /v2/charges":  http.StatusServiceUnavailable,
+			"GET /v2/providers": http.StatusBadGateway,
+			"POST /v2/orders":   http.StatusBadGateway,
+		}}
+		srv := httptest.NewServer(flaky)
+		t.Cleanup(srv.Close)
+		c := client.New(srv.URL, client.WithRetries(2, time.Millisecond))
+
+		if _, err := c.Charge(ctx, charge(1250)); err != nil {
+			t.Fatalf("expected the charge to succeed on retry, got %v", err)
+		}
+		if keys := flaky.keys["POST /v2/charges"]; len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+			t.Fatalf("expected both tries to send the same Idempotency-Key, got %q", keys)
+		}
+		if _, err := c.ListProviders(ctx); err != nil {
+			t.Fatalf("expected the GET to succeed on the last retry, got %v", err)
+		}
+
+		// A 502 on a POST may have charged, it is not retried
+		_, err := c.CreateOrder(ctx, client.OrderRequest{CustomerID: customer, AmountDue: 2000, Currency: "usd"})
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+			t.Fatalf("expected the 502, got %v", err)
+		}
+		if n := len(flaky.keys["POST /v2/orders"]); n != 1 {
+			t.Fatalf("expected one try, got %d", n)
+		}
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := c.GetOrder(cancelled, "00000000-0000-0000-0000-000000000000"); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("webhook signatures", func(t *testing.T) {
+		srv := apitest.NewServer(t)
+		created, err := client.New(srv.URL).CreatePaymentIntent(ctx, intent("fake"))
+		if err != nil {
+			t.Fatalf("CreatePaymentIntent: %v", err)
+		}
+		secret := string(srv.Service.Config.Providers.Fake.WebhookSecret)
+		raw, headers, err := srv.Fake.SignedWebhook(created.ID, "succeeded", "pm_fake_signed")
+		if err != nil {
+			t.Fatalf("SignedWebhook: %v", err)
+		}
+		header := headers["Fake-Signature"][0]
+
+		if err := client.VerifyWebhook(raw, header, secret, 0); err != nil {
+			t.Fatalf("expected the fake provider's signature to verify, got %v", err)
+		}
+		for name, check := range map[string]error{
+			"tampered body": client.VerifyWebhook(append(raw, ' '), header, secret, 0),
+			"wrong secret":  client.VerifyWebhook(raw, header, "whsec_other", 0),
+			"old signature": client.VerifyWebhook(raw, client.SignWebhook(raw, secret, time.Now().Add(-time.Hour)), secret, time.Minute),
+			"malformed":     client.VerifyWebhook(raw, "v1=abc", secret, 0),
+		} {
+			if !errors.Is(check, client.ErrInvalidSignature) {
+				t.Errorf("%s: expected ErrInvalidSignature, got %v", name, check)
+			}
+		}
+		if err := client.VerifyWebhook(raw, client.SignWebhook(raw, secret, time.Now()), secret, 0); err != nil {
+			t.Fatalf("expected SignWebhook to verify, got %v", err)
+		}
+	})
+}
+
+func newClient(t *testing.T) *client.Client {
+	t.Helper()
+	return client.New(apitest.NewServer(t).URL, client.WithRetries(2, time.Millisecond))
+}
+
+func intent(provider string) client.CreatePaymentIntentRequest {
+	return client.CreatePaymentIntentRequest{
+		Provider: provider,
+		PaymentIntentRequest: client.PaymentIntentRequest{
+			Amount:        1250,
+			Currency:      "usd",
+			CustomerID:    customer,
+			PaymentMethod: "card",
+		},
+	}
+}
+
+func charge(amount int64) client.CreatePaymentIntentRequest {
+	remember := true
+	return client.CreatePaymentIntentRequest{
+		Provider: "fake",
+		PaymentIntentRequest: client.PaymentIntentRequest{
+			Amount:     amount,
+			Currency:   "usd",
+			CustomerID: customer,
+			Token:      "pm_fake_visa",
+			RememberMe: &remember,
+		},
+	}
+}
+
+// Answers the first failures[route] requests to a route with status[route],
+// then passes them on, keeping the Idempotency-Key of every try
+type flakyHandler struct {
+	next     http.Handler
+	failures map[string]int
+	status   map[string]int
+
+	mu   sync.Mutex
+	keys map[string][]string
+}
+
+func (h *flakyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := r.Method + " " + r.URL.Path
+
+	h.mu.Lock()
+	if h.keys == nil {
+		h.keys = make(map[string][]string)
+	}
+	h.keys[route] = append(h.keys[route], r.Header.Get(client.IdempotencyKeyHeader))
+	fail := len(h.keys[route]) <= h.failures[route]
+	h.mu.Unlock()
+
+	if fail {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(h.status[route])
+		w.Write([]byte(`{"error":{"message":"upstream failed","code":"upstream_failed","category":"provider_error"}}`))
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
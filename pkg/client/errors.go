@@ -0,0 +1,28 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is an error response of the service. Code is stable, see ErrorDetail.
+type Error struct {
+	StatusCode int
+	ErrorDetail
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("payment service: status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("payment service: status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Code returns the code of err when it is an *Error, and "" otherwise
+func Code(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) CreateOrder(ctx context.Context, req OrderRequest, opts ...CallOption) (*OrderResponse, error) {
+	return resource[OrderResponse](ctx, c, http.MethodPost, "/v2/orders", req, opts)
+}
+
+// GetOrder reads an order with its payment attempts
+func (c *Client) GetOrder(ctx context.Context, id string) (*OrderResponse, error) {
+	return resource[OrderResponse](ctx, c, http.MethodGet, "/v2/orders/"+url.PathEscape(id), nil, nil)
+}
+
+// CancelOrder cancels an order nothing was paid towards
+func (c *Client) CancelOrder(ctx context.Context, id string, opts ...CallOption) (*OrderResponse, error) {
+	return resource[OrderResponse](ctx, c, http.MethodPost, "/v2/orders/"+url.PathEscape(id)+"/cancel", nil, opts)
+}
+
+// CreateFxQuote locks a rate for a settlement amount, pass its id as the
+// intent's FxQuote. Fails with not found when the service has FX disabled.
+func (c *Client) CreateFxQuote(ctx context.Context, req FxQuoteRequest, opts ...CallOption) (*FxQuoteResponse, error) {
+	return resource[FxQuoteResponse](ctx, c, http.MethodPost, "/v2/fx-quotes", req, opts)
+}
+
+func (c *Client) GetFxQuote(ctx context.Context, id string) (*FxQuoteResponse, error) {
+	return resource[FxQuoteResponse](ctx, c, http.MethodGet, "/v2/fx-quotes/"+url.PathEscape(id), nil, nil)
+}
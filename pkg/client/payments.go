@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/danielmoisemontezima/zw-payment-service/internal/model"
+)
+
+// CreatePaymentIntent creates an intent with req.Provider, or with the one
+// the routing rules pick when it is empty
+func (c *Client) CreatePaymentIntent(ctx context.Context, req CreatePaymentIntentRequest, opts ...CallOption) (*PaymentIntentResponse, error) {
+	return resource[PaymentIntentResponse](ctx, c, http.MethodPost, "/v2/payment-intents", req, opts)
+}
+
+// Charge charges a card token or saved payment method with req.Provider
+func (c *Client) Charge(ctx context.Context, req CreatePaymentIntentRequest, opts ...CallOption) (*PaymentIntentResponse, error) {
+	return resource[PaymentIntentResponse](ctx, c, http.MethodPost, "/v2/charges", req, opts)
+}
+
+func (c *Client) GetPaymentIntent(ctx context.Context, id string) (*PaymentIntentResponse, error) {
+	return resource[PaymentIntentResponse](ctx, c, http.MethodGet, "/v2/payment-intents/"+url.PathEscape(id), nil, nil)
+}
+
+// Refund refunds req.Amount of an intent, or all that is left when it is zero
+func (c *Client) Refund(ctx context.Context, intentID string, req CreateRefundRequest, opts ...CallOption) (*RefundResponse, error) {
+	return resource[RefundResponse](ctx, c, http.MethodPost, "/v2/payment-intents/"+url.PathEscape(intentID)+"/refunds", req, opts)
+}
+
+// ListRefunds lists the refunds of an intent, newest first
+func (c *Client) ListRefunds(ctx context.Context, intentID string, params ListParams) (*List[RefundResponse], error) {
+	return list[RefundResponse](ctx, c, "/v2/payment-intents/"+url.PathEscape(intentID)+"/refunds", params)
+}
+
+// ListPaymentMethods lists a customer's saved payment methods, newest first
+func (c *Client) ListPaymentMethods(ctx context.Context, customerID string, params ListParams) (*List[PaymentMethodResponse], error) {
+	return list[PaymentMethodResponse](ctx, c, "/v2/customers/"+url.PathEscape(customerID)+"/payment-methods", params)
+}
+
+// SplitTender charges the legs in order. A declined leg is not an error: the
+// charged legs are refunded and the response has status unwound, or
+// unwind_failed, with the reason in Error.
+func (c *Client) SplitTender(ctx context.Context, req SplitTenderRequest, opts ...CallOption) (*SplitTenderResponse, error) {
+	return resource[SplitTenderResponse](ctx, c, http.MethodPost, "/v2/split-tenders", req, opts, http.StatusPaymentRequired)
+}
+
+// ListProviders reports the circuit state and call counters of each provider
+func (c *Client) ListProviders(ctx context.Context) (*List[ProviderStats], error) {
+	return list[ProviderStats](ctx, c, "/v2/providers", ListParams{})
+}
+
+func resource[T any](ctx context.Context, c *Client, method string, path string, body any, opts []CallOption, accept ...int) (*T, error) {
+	var res model.Resource[T]
+	if err := c.do(ctx, method, path, nil, body, &res, opts, accept...); err != nil {
+		return nil, err
+	}
+	return &res.Data, nil
+}
+
+func list[T any](ctx context.Context, c *Client, path string, params ListParams) (*List[T], error) {
+	var res List[T]
+	if err := c.do(ctx, http.MethodGet, path, listQuery(params), nil, &res, nil); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
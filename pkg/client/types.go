@@ -0,0 +1,35 @@
+package client
+
+import "github.com/danielmoisemontezima/zw-payment-service/internal/model"
+
+// The service's own request and response types, so callers outside this
+// module can name them
+type (
+	PaymentProvider            = model.PaymentProvider
+	PaymentIntentRequest       = model.PaymentIntentRequest
+	CreatePaymentIntentRequest = model.CreatePaymentIntentRequest
+	PaymentIntentResponse      = model.PaymentIntentResponse
+	CreateRefundRequest        = model.CreateRefundRequest
+	RefundResponse             = model.RefundResponse
+	PaymentMethodResponse      = model.PaymentMethodResponse
+	SplitTenderRequest         = model.SplitTenderRequest
+	TenderLeg                  = model.TenderLeg
+	SplitTenderResponse        = model.SplitTenderResponse
+	TenderLegResult            = model.TenderLegResult
+	ProviderStats              = model.ProviderStats
+	OrderRequest               = model.OrderRequest
+	OrderResponse              = model.OrderResponse
+	FxQuoteRequest             = model.FxQuoteRequest
+	FxQuoteResponse            = model.FxQuoteResponse
+	ErrorDetail                = model.ErrorDetail
+	FieldError                 = model.FieldError
+)
+
+// One page of a list, pass NextCursor as ListParams.Cursor for the next one
+type List[T any] = model.List[T]
+
+// Page of a list call. Zero values mean the service defaults.
+type ListParams struct {
+	Limit  int
+	Cursor string
+}
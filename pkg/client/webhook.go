@@ -0,0 +1,72 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How far a signature's timestamp may be from now by default
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is wrapped by every VerifyWebhook failure
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// SignWebhook signs payload with the scheme of the fake provider's webhooks,
+// which mirrors Stripe's: t=<unix time>,v1=<hex HMAC-SHA256 of
+// "<unix time>.<payload>" keyed by secret>
+func SignWebhook(payload []byte, secret string, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + webhookMAC(payload, secret, ts)
+}
+
+// VerifyWebhook checks the signature header of a webhook against its raw
+// body. tolerance bounds the age of the signature against replays, zero
+// means DefaultWebhookTolerance.
+func VerifyWebhook(payload []byte, header string, secret string, tolerance time.Duration) error {
+	if tolerance == 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			// Several while the secret is being rotated
+			sigs = append(sigs, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if time.Since(time.Unix(unix, 0)).Abs() > tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	want := webhookMAC(payload, secret, ts)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(want)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+}
+
+func webhookMAC(payload []byte, secret string, ts string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}